@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"transport/internal/api"
+	"transport/internal/errs"
+	"transport/internal/geo"
+	"transport/internal/resrobot"
+)
+
+// boardWindow is how far ahead a nationwide board looks. Outside the cities
+// an hour often holds no more than a train or two.
+const boardWindow = 2 * time.Hour
+
+// maxBoardStopMeters is how far the nearest stop to a coordinate may be for
+// its departures to be of any use
+const maxBoardStopMeters = 5000
+
+// resRobotBoard fetches nationwide departure or arrival boards from ResRobot
+type resRobotBoard struct {
+	client   *resrobot.Client
+	stop     *resrobot.StopLocationData
+	opts     resrobot.BoardOptions
+	filter   api.DepartureFilter // lines and platforms; mode and towards go to the API
+	arrivals bool
+	count    int
+}
+
+// newResRobotBoard resolves the stop of a board, and the stop in towards
+// (where departures go, or where arrivals come from) if one is given.
+// products narrows the board to those product classes; 0 keeps them all.
+func newResRobotBoard(ctx context.Context, client *resrobot.Client, query api.StopQuery, filter api.DepartureFilter, products resrobot.Products, arrivals bool, count int) (*resRobotBoard, error) {
+	role, towardsRole := "origin", "destination"
+	if arrivals {
+		role, towardsRole = towardsRole, role
+	}
+	sel := &locationSelection{}
+
+	stop, err := resolveBoardStop(ctx, client, query.Location, role, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := filter.Mode
+	if query.Mode != "" {
+		mode = query.Mode
+	}
+	products, err = combineProducts(resrobot.ProductsForModes(nonEmpty(mode), nil), products)
+	if err != nil {
+		return nil, errs.Invalidf("%v", err)
+	}
+	opts := resrobot.BoardOptions{
+		Duration: boardWindow,
+		Products: products,
+	}
+	if filter.Towards != "" {
+		towards, err := resolveBoardStop(ctx, client, filter.Towards, towardsRole, sel)
+		if err != nil {
+			return nil, err
+		}
+		opts.Direction = towards.ID
+	}
+
+	filter.Mode, filter.Towards = "", ""
+	return &resRobotBoard{
+		client:   client,
+		stop:     stop,
+		opts:     opts,
+		filter:   filter,
+		arrivals: arrivals,
+		count:    count,
+	}, nil
+}
+
+// resolveBoardStop resolves a stop by name. Boards are for stops, so a
+// coordinate means the stop closest to it.
+func resolveBoardStop(ctx context.Context, client *resrobot.Client, query, role string, sel *locationSelection) (*resrobot.StopLocationData, error) {
+	if p, ok := geo.ParseLatLon(query); ok {
+		return client.NearestStop(ctx, p.Lat, p.Lon, maxBoardStopMeters)
+	}
+	return resolveResRobotEndpoint(ctx, client, query, role, sel)
+}
+
+// fetch gets the board from now on
+func (b *resRobotBoard) fetch(ctx context.Context) (*api.DepartureBoard, error) {
+	var entries []resrobot.BoardEntry
+	var err error
+	if b.arrivals {
+		entries, err = b.client.GetArrivals(ctx, b.stop.ID, b.opts)
+	} else {
+		entries, err = b.client.GetDepartures(ctx, b.stop.ID, b.opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	site := api.Site{Name: b.stop.DisplayName(), Lat: b.stop.Lat, Lon: b.stop.Lon}
+	site.ID, _ = strconv.Atoi(b.stop.ExtID)
+
+	board := &api.DepartureBoard{Site: site, Arrivals: b.arrivals}
+	for _, entry := range entries {
+		dep := boardDeparture(site, entry)
+		if !b.filter.Matches(&dep) {
+			continue
+		}
+		board.Departures = append(board.Departures, dep)
+		if len(board.Departures) == b.count {
+			break
+		}
+	}
+	return board, nil
+}
+
+// boardDeparture converts a ResRobot board entry to the departure type
+// shared with SL, so that both use the same formatter and JSON schema
+func boardDeparture(site api.Site, entry resrobot.BoardEntry) api.Departure {
+	const layout = "2006-01-02T15:04:05"
+
+	dep := api.Departure{
+		Destination: entry.Direction,
+		Scheduled:   entry.Time.Format(layout),
+		Expected:    entry.ExpectedTime().Format(layout),
+		StopArea:    api.StopAreaInfo{ID: site.ID, Name: site.Name},
+		StopPoint:   api.StopPointInfo{Designation: entry.Track},
+		Line: api.LineInfo{
+			Designation:   entry.Line,
+			TransportMode: strings.ToUpper(resrobot.ModeForCategory(entry.Category)),
+		},
+	}
+	if entry.RtTime == nil {
+		// ResRobot only has live data for some operators
+		dep.Journey.PredictionState = "UNKNOWN"
+	}
+	if entry.Cancelled {
+		dep.State = api.DepartureStateCancelled
+	}
+	return dep
+}
+
+// nonEmpty returns s as a list, or nil if it is empty
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+// requireResRobotKey exits with instructions if no ResRobot API key is set
+func requireResRobotKey(client *resrobot.Client) {
+	if client.HasAPIKey() {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Error: RESROBOT_API_KEY not set")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Get a free API key at:")
+	fmt.Fprintln(os.Stderr, "  https://www.trafiklab.se/api/trafiklab-apis/resrobot-v21/")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Then set it:")
+	fmt.Fprintln(os.Stderr, "  export RESROBOT_API_KEY=\"your-key-here\"")
+	os.Exit(exitMissingAPIKey)
+}
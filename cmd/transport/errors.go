@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"transport/internal/errs"
+	"transport/internal/mcp"
+	"transport/internal/output"
+)
+
+// Exit codes, one per class of error, so scripts can tell what went wrong
+// without reading the message
+const (
+	exitError         = 1   // anything not covered below
+	exitUsage         = 2   // invalid flags or arguments, as the flag package uses
+	exitAmbiguous     = 3   // a stop name matches several stops; rerun with --select
+	exitNotFound      = 4   // no such stop, place or trip
+	exitUpstream      = 5   // a backend API failed
+	exitRateLimited   = 6   // a backend API asked us to slow down
+	exitTimeout       = 7   // a backend API didn't answer in time
+	exitMissingAPIKey = 8   // an API key needs to be set
+	exitInterrupted   = 130 // cancelled with Ctrl-C, as shells report SIGINT
+)
+
+// exitCode returns the exit code for the class of err
+func exitCode(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return exitInterrupted
+	}
+	switch errs.Classify(err) {
+	case errs.CodeInvalidInput:
+		return exitUsage
+	case errs.CodeAmbiguous:
+		return exitAmbiguous
+	case errs.CodeNotFound:
+		return exitNotFound
+	case errs.CodeUpstream:
+		return exitUpstream
+	case errs.CodeRateLimited:
+		return exitRateLimited
+	case errs.CodeTimeout:
+		return exitTimeout
+	case errs.CodeMissingAPIKey:
+		return exitMissingAPIKey
+	}
+	return exitError
+}
+
+// fail prints err and exits with the code for its class
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(exitCode(err))
+}
+
+// failUsage prints an error about the command line and exits
+func failUsage(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(exitUsage)
+}
+
+// toolError is the MCP result for a failed tool call. The text is a JSON
+// error with a machine-readable code, see output.FormatErrorJSON.
+func toolError(err error) (mcp.ToolCallResult, error) {
+	return mcp.ToolCallResult{
+		Content: []mcp.ContentBlock{mcp.NewTextContent(output.FormatErrorJSON(err))},
+		IsError: true,
+	}, nil
+}
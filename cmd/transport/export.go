@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"transport/internal/errs"
+	"transport/internal/geo"
+)
+
+// Export formats understood by --export
+const (
+	exportGeoJSON = "geojson"
+	exportGPX     = "gpx"
+)
+
+// exportOptions holds the flags for writing one trip's geometry instead
+// of listing the trips
+type exportOptions struct {
+	format string // geojson or gpx, "" to list trips as usual
+	trip   int    // trip to export, counting from 1
+}
+
+// register adds the export flags to fs
+func (e *exportOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&e.format, "export", "", "Write a trip's route as geojson or gpx instead of listing trips")
+	fs.IntVar(&e.trip, "trip", 1, "Trip to write with --export (1 for the first)")
+}
+
+// validate checks the export format and trip number
+func (e *exportOptions) validate() error {
+	switch e.format {
+	case "", exportGeoJSON, exportGPX:
+	default:
+		return fmt.Errorf("invalid export format '%s' (use %s or %s)", e.format, exportGeoJSON, exportGPX)
+	}
+	if e.trip < 1 {
+		return fmt.Errorf("--trip must be 1 or more")
+	}
+	return nil
+}
+
+// enabled reports whether a trip should be exported
+func (e *exportOptions) enabled() bool {
+	return e.format != ""
+}
+
+// index returns the index of the trip to export among count trips
+func (e *exportOptions) index(count int) (int, error) {
+	if e.trip > count {
+		return 0, errs.Invalidf("trip %d not found, only %d trips", e.trip, count)
+	}
+	return e.trip - 1, nil
+}
+
+// write prints the route in the export format
+func (e *exportOptions) write(route geo.Route) error {
+	var data []byte
+	var err error
+	if e.format == exportGPX {
+		data, err = route.GPX()
+	} else {
+		data, err = route.GeoJSON()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to export trip: %w", err)
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(data))
+	return err
+}
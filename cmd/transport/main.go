@@ -3,20 +3,27 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"transport/internal/api"
 	"transport/internal/bus"
 	"transport/internal/car"
 	"transport/internal/display"
+	"transport/internal/errs"
 	"transport/internal/flight"
+	"transport/internal/geo"
+	"transport/internal/httpx"
 	"transport/internal/mcp"
 	"transport/internal/output"
+	"transport/internal/planner"
 	"transport/internal/resrobot"
 	"transport/internal/taxi"
 	"transport/internal/tz"
@@ -27,16 +34,36 @@ var (
 )
 
 func main() {
+	httpx.UserAgent = "transport-cli/" + version
+	os.Args = stripDebugFlag(os.Args)
+	if err := setClock(); err != nil {
+		failUsage(err)
+	}
+
+	// Ctrl-C cancels in-flight requests instead of killing the process
+	// outright; a second Ctrl-C kills it as usual
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	if len(os.Args) > 1 {
 		cmd := strings.ToLower(os.Args[1])
 
 		if cmd == "--mcp" {
-			runMCP()
+			runMCP(ctx)
 			return
 		}
 
 		if isNextCommand(cmd) {
-			runNextCommand(os.Args[2:])
+			runNextCommand(ctx, os.Args[2:])
+			return
+		}
+
+		if isNearCommand(cmd) {
+			runNearCommand(ctx, os.Args[2:])
 			return
 		}
 
@@ -46,7 +73,7 @@ func main() {
 		}
 
 		if isFlightCommand(cmd) {
-			runFlightCommand(os.Args[2:])
+			runFlightCommand(ctx, os.Args[2:])
 			return
 		}
 
@@ -56,7 +83,7 @@ func main() {
 		}
 
 		if isTaxiCommand(cmd) {
-			runTaxiCommand(os.Args[2:])
+			runTaxiCommand(ctx, os.Args[2:])
 			return
 		}
 
@@ -66,7 +93,26 @@ func main() {
 		}
 	}
 
-	runTripCommand()
+	runTripCommand(ctx)
+}
+
+// stripDebugFlag removes --debug from args, wherever it is, and turns on
+// tracing of upstream HTTP calls to stderr if it was there or if
+// TRANSPORT_DEBUG is set. Every command accepts it this way.
+func stripDebugFlag(args []string) []string {
+	debug := os.Getenv("TRANSPORT_DEBUG") != ""
+	kept := make([]string, 0, len(args))
+	for i, arg := range args {
+		if i > 0 && (arg == "--debug" || arg == "-debug") {
+			debug = true
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	if debug {
+		httpx.Default.SetDebug(os.Stderr)
+	}
+	return kept
 }
 
 // isNextCommand checks if the argument is a "next" command (English or Swedish)
@@ -188,7 +234,7 @@ func runCarCommand(args []string) {
 
 	if len(posArgs) < 2 {
 		fs.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	from := posArgs[0]
@@ -248,7 +294,7 @@ func runFlyCommand(args []string) {
 
 	if len(posArgs) < 2 {
 		fs.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	// Parse origin and destination from args
@@ -258,22 +304,28 @@ func runFlyCommand(args []string) {
 	if origin == "" || dest == "" {
 		fmt.Fprintf(os.Stderr, "Error: Could not parse origin and destination\n")
 		fmt.Fprintf(os.Stderr, "Usage: transport fly from <origin> to <destination>\n")
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	// Look up IATA codes
 	originCode, originFound := flight.LookupAirportCode(origin)
 	if !originFound {
 		fmt.Fprintf(os.Stderr, "Error: Unknown airport/city '%s'\n", origin)
+		if names := flight.SuggestCities(origin); len(names) > 0 {
+			fmt.Fprintf(os.Stderr, "Did you mean: %s?\n", titleList(names))
+		}
 		fmt.Fprintf(os.Stderr, "Use a city name (e.g., Stockholm) or IATA code (e.g., ARN)\n")
-		os.Exit(1)
+		os.Exit(exitNotFound)
 	}
 
 	destCode, destFound := flight.LookupAirportCode(dest)
 	if !destFound {
 		fmt.Fprintf(os.Stderr, "Error: Unknown airport/city '%s'\n", dest)
+		if names := flight.SuggestCities(dest); len(names) > 0 {
+			fmt.Fprintf(os.Stderr, "Did you mean: %s?\n", titleList(names))
+		}
 		fmt.Fprintf(os.Stderr, "Use a city name (e.g., Vilnius) or IATA code (e.g., VNO)\n")
-		os.Exit(1)
+		os.Exit(exitNotFound)
 	}
 
 	// Build search
@@ -291,7 +343,7 @@ func runFlyCommand(args []string) {
 		parsed, err := tz.ParseStockholm("2006-01-02", dateFlag)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Invalid date format '%s' (use YYYY-MM-DD)\n", dateFlag)
-			os.Exit(1)
+			os.Exit(exitUsage)
 		}
 		search.DepartureDate = parsed
 	}
@@ -300,7 +352,7 @@ func runFlyCommand(args []string) {
 		parsed, err := time.Parse("2006-01-02", returnFlag)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Invalid return date format '%s' (use YYYY-MM-DD)\n", returnFlag)
-			os.Exit(1)
+			os.Exit(exitUsage)
 		}
 		search.ReturnDate = parsed
 	}
@@ -314,7 +366,7 @@ func runFlyCommand(args []string) {
 	}
 }
 
-func runTaxiCommand(args []string) {
+func runTaxiCommand(ctx context.Context, args []string) {
 	fs := flag.NewFlagSet("taxi", flag.ExitOnError)
 
 	var jsonOutput bool
@@ -341,7 +393,7 @@ func runTaxiCommand(args []string) {
 
 	if len(posArgs) < 2 {
 		fs.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	// Parse origin and destination (reuse flight route parsing)
@@ -357,23 +409,24 @@ func runTaxiCommand(args []string) {
 	}
 
 	// Geocode locations
-	fromLoc, err := taxi.Geocode(from)
+	client := newTaxiClient()
+	fromLoc, err := client.Geocode(ctx, from)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: kunde inte hitta '%s': %v\n", from, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 
-	toLoc, err := taxi.Geocode(to)
+	toLoc, err := client.Geocode(ctx, to)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: kunde inte hitta '%s': %v\n", to, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 
 	// Calculate route
-	route, err := taxi.CalculateRoute(fromLoc, toLoc)
+	route, err := client.CalculateRoute(ctx, fromLoc, toLoc)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: kunde inte beräkna rutt: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 
 	// Get fare estimates
@@ -435,7 +488,7 @@ func runBusCommand(args []string) {
 
 	if len(posArgs) < 2 {
 		fs.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	// Parse origin and destination (reuse flight route parsing)
@@ -450,15 +503,21 @@ func runBusCommand(args []string) {
 	fromCity, fromFound := bus.LookupCity(from)
 	if !fromFound {
 		fmt.Fprintf(os.Stderr, "Error: Okänd stad '%s'\n", from)
+		if names := bus.SuggestCities(from); len(names) > 0 {
+			fmt.Fprintf(os.Stderr, "Menade du: %s?\n", titleList(names))
+		}
 		fmt.Fprintf(os.Stderr, "Kända städer: Stockholm, Göteborg, Malmö, Uppsala, Linköping, etc.\n")
-		os.Exit(1)
+		os.Exit(exitNotFound)
 	}
 
 	toCity, toFound := bus.LookupCity(to)
 	if !toFound {
 		fmt.Fprintf(os.Stderr, "Error: Okänd stad '%s'\n", to)
+		if names := bus.SuggestCities(to); len(names) > 0 {
+			fmt.Fprintf(os.Stderr, "Menade du: %s?\n", titleList(names))
+		}
 		fmt.Fprintf(os.Stderr, "Kända städer: Stockholm, Göteborg, Malmö, Uppsala, Linköping, etc.\n")
-		os.Exit(1)
+		os.Exit(exitNotFound)
 	}
 
 	// Get bus routes
@@ -543,7 +602,21 @@ func capitalizeFirst(s string) string {
 	return strings.Join(words, " ")
 }
 
-func runFlightCommand(args []string) {
+// titleList joins lower-case names as "Lund, Luleå", capitalising each word
+func titleList(names []string) string {
+	titled := make([]string, len(names))
+	for i, name := range names {
+		words := strings.Fields(name)
+		for j, word := range words {
+			r := []rune(word)
+			words[j] = strings.ToUpper(string(r[0])) + string(r[1:])
+		}
+		titled[i] = strings.Join(words, " ")
+	}
+	return strings.Join(titled, ", ")
+}
+
+func runFlightCommand(ctx context.Context, args []string) {
 	fs := flag.NewFlagSet("flight", flag.ExitOnError)
 
 	var (
@@ -584,19 +657,21 @@ func runFlightCommand(args []string) {
 	lat, lon, found := flight.GetCoordinates(location)
 	if !found {
 		fmt.Fprintf(os.Stderr, "Error: Unknown location '%s'\n", location)
+		if names := flight.SuggestLocations(location); len(names) > 0 {
+			fmt.Fprintf(os.Stderr, "Did you mean: %s?\n", titleList(names))
+		}
 		fmt.Fprintf(os.Stderr, "Known locations: Stockholm, Göteborg, Malmö, Uppsala, Linköping, Örebro, Västerås,\n")
 		fmt.Fprintf(os.Stderr, "  Norrköping, Lund, Umeå, Jönköping, Luleå, Kiruna, Sundsvall, Gävle, Karlstad,\n")
 		fmt.Fprintf(os.Stderr, "  Växjö, Halmstad, Kalmar, Visby, Åre\n")
-		os.Exit(1)
+		os.Exit(exitNotFound)
 	}
 
 	fmt.Fprintf(os.Stderr, "Hämtar flygplatsdata...\n")
 
 	// Fetch Swedish airports
-	airports, err := flight.FetchSwedishAirports()
+	airports, err := newFlightClient().FetchSwedishAirports(ctx)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		fail(err)
 	}
 
 	// Find nearby airports
@@ -607,39 +682,80 @@ func runFlightCommand(args []string) {
 	fmt.Print(out)
 }
 
-func runNextCommand(args []string) {
+func runNextCommand(ctx context.Context, args []string) {
 	fs := flag.NewFlagSet("next", flag.ExitOnError)
 
 	var (
 		count      int
 		lang       string
 		jsonOutput bool
+		watch      bool
+		interval   time.Duration
+		stops      stopList
+		lines      string
+		exclude    string
+		direction  int
+		platforms  string
+		group      string
+		nationwide bool
+		arrivals   bool
+		products   string
 	)
 
 	fs.IntVar(&count, "n", 3, "Number of departures to show")
 	fs.StringVar(&lang, "l", "sv", "Language (sv/en)")
 	fs.BoolVar(&jsonOutput, "json", false, "Output as JSON")
 	fs.BoolVar(&jsonOutput, "j", false, "Output JSON (shorthand)")
+	fs.BoolVar(&watch, "watch", false, "Live board: refresh until Ctrl-C (NDJSON with --json)")
+	fs.BoolVar(&watch, "w", false, "Live board (shorthand)")
+	fs.DurationVar(&interval, "interval", 30*time.Second, "Refresh interval for --watch")
+	fs.Var(&stops, "stop", "Stop for a merged board: name[@mode][+walk minutes] (repeatable)")
+	fs.Var(&stops, "s", "Stop for a merged board (shorthand)")
+	fs.StringVar(&lines, "line", "", "Only these lines, e.g. 117,118")
+	fs.StringVar(&exclude, "exclude-line", "", "Leave out these lines, e.g. 117,118")
+	fs.IntVar(&direction, "direction", 0, "Only this direction code (1 or 2)")
+	fs.StringVar(&platforms, "platform", "", "Only these platforms (\"Läge\"), e.g. A,B")
+	fs.StringVar(&group, "group", "", "Only this group of lines, e.g. \"gröna linjen\"")
+	fs.BoolVar(&nationwide, "se", false, "Any stop in Sweden (ResRobot)")
+	fs.BoolVar(&nationwide, "sweden", false, "Any stop in Sweden (ResRobot)")
+	fs.BoolVar(&arrivals, "arrivals", false, "Show arrivals instead of departures (ResRobot)")
+	fs.BoolVar(&arrivals, "a", false, "Show arrivals (shorthand)")
+	fs.StringVar(&products, "products", "", "Only these product classes, e.g. regionaltåg,fjärrtåg (ResRobot)")
 
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Transport - Next departures / Nästa avgång\n\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n")
-		fmt.Fprintf(os.Stderr, "  transport next|nästa <mode> <location> [towards]\n\n")
+		fmt.Fprintf(os.Stderr, "  transport next|nästa <mode> <location> [towards]\n")
+		fmt.Fprintf(os.Stderr, "  transport next|nästa <location>\n")
+		fmt.Fprintf(os.Stderr, "  transport next|nästa --stop <stop> [--stop <stop>...] [towards]\n\n")
 		fmt.Fprintf(os.Stderr, "Arguments:\n")
 		fmt.Fprintf(os.Stderr, "  mode      Transport mode:\n")
+		fmt.Fprintf(os.Stderr, "              alla, all (every mode)\n")
 		fmt.Fprintf(os.Stderr, "              bus, buss\n")
 		fmt.Fprintf(os.Stderr, "              metro, tunnelbana, t-bana\n")
 		fmt.Fprintf(os.Stderr, "              train, tåg, pendeltåg\n")
 		fmt.Fprintf(os.Stderr, "              tram, spårvagn\n")
 		fmt.Fprintf(os.Stderr, "              ship, båt, färja\n")
 		fmt.Fprintf(os.Stderr, "  location  Stop/station name to depart from\n")
-		fmt.Fprintf(os.Stderr, "  towards   (Optional) Destination to filter by\n\n")
+		fmt.Fprintf(os.Stderr, "  towards   (Optional) Destination, or a stop the line passes, to filter by\n")
+		fmt.Fprintf(os.Stderr, "            (with --arrivals: where the vehicles come from)\n")
+		fmt.Fprintf(os.Stderr, "  stop      name[@mode][+walk], e.g. Odenplan@buss+3. Boards for several\n")
+		fmt.Fprintf(os.Stderr, "            stops are merged; departures you can't walk to in time are hidden\n\n")
 		fmt.Fprintf(os.Stderr, "Examples:\n")
 		fmt.Fprintf(os.Stderr, "  transport nästa buss Odenplan\n")
 		fmt.Fprintf(os.Stderr, "  transport nästa buss \"Spånga station\" Brommaplan\n")
 		fmt.Fprintf(os.Stderr, "  transport nästa tunnelbana Slussen\n")
 		fmt.Fprintf(os.Stderr, "  transport nästa tåg \"Stockholm Central\"\n")
-		fmt.Fprintf(os.Stderr, "  transport next -n 5 bus Odenplan\n\n")
+		fmt.Fprintf(os.Stderr, "  transport next -n 5 bus Odenplan\n")
+		fmt.Fprintf(os.Stderr, "  transport nästa --watch -n 8 tunnelbana Odenplan   # Live board\n")
+		fmt.Fprintf(os.Stderr, "  transport nästa --watch --json buss Odenplan       # NDJSON stream\n")
+		fmt.Fprintf(os.Stderr, "  transport nästa Odenplan                           # Every mode\n")
+		fmt.Fprintf(os.Stderr, "  transport nästa --line 117,118 --platform A buss Odenplan\n")
+		fmt.Fprintf(os.Stderr, "  transport nästa --exclude-line 4 buss Odenplan\n")
+		fmt.Fprintf(os.Stderr, "  transport nästa -s Odenplan@buss+3 -s \"Karlbergsvägen@buss+4\" -s Odenplan@t-bana+5\n")
+		fmt.Fprintf(os.Stderr, "  transport nästa -se tåg Sundsvall                   # Anywhere in Sweden\n")
+		fmt.Fprintf(os.Stderr, "  transport nästa -se --arrivals tåg Sundsvall Stockholm  # Arrivals from Stockholm\n")
+		fmt.Fprintf(os.Stderr, "  transport nästa --products fjärrtåg Sundsvall       # Long-distance trains only\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		fs.PrintDefaults()
 	}
@@ -647,55 +763,158 @@ func runNextCommand(args []string) {
 	fs.Parse(args)
 	posArgs := fs.Args()
 
-	if len(posArgs) < 2 {
-		fs.Usage()
-		os.Exit(1)
+	// Stops come from --stop flags, or from "<mode> <location>" / "<location>"
+	towards := ""
+	if len(stops) > 0 {
+		if len(posArgs) > 1 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		if len(posArgs) == 1 {
+			towards = posArgs[0]
+		}
+	} else {
+		stop, rest, err := parseNextArgs(posArgs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Valid modes: bus/buss, metro/tunnelbana/t-bana, train/tåg, tram/spårvagn, ship/båt/färja, alla\n")
+			os.Exit(exitUsage)
+		}
+		if stop == nil {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		stops = stopList{*stop}
+		if len(rest) > 0 {
+			towards = rest[0]
+		}
 	}
 
-	mode := posArgs[0]
-	location := posArgs[1]
-	towards := ""
-	if len(posArgs) >= 3 {
-		towards = posArgs[2]
+	if direction < 0 || direction > 2 {
+		fmt.Fprintf(os.Stderr, "Error: --direction must be 1 or 2\n")
+		os.Exit(exitUsage)
 	}
 
-	// Normalize and validate mode (supports Swedish and English)
-	modeLower := normalizeMode(strings.ToLower(mode))
-	if modeLower == "" {
-		fmt.Fprintf(os.Stderr, "Error: invalid transport mode '%s'\n", mode)
-		fmt.Fprintf(os.Stderr, "Valid modes: bus/buss, metro/tunnelbana/t-bana, train/tåg, tram/spårvagn, ship/båt/färja\n")
-		os.Exit(1)
+	filter := api.DepartureFilter{
+		Towards:      towards,
+		Lines:        splitList(lines),
+		ExcludeLines: splitList(exclude),
+		Direction:    direction,
+		Platforms:    splitList(platforms),
+		GroupOfLines: group,
+	}
+
+	if watch && interval < minWatchInterval {
+		fmt.Fprintf(os.Stderr, "Error: --interval must be at least %s\n", minWatchInterval)
+		os.Exit(exitUsage)
+	}
+
+	productSet, err := resrobot.ParseProducts(products)
+	if err != nil {
+		failUsage(err)
+	}
+
+	// Arrivals and product classes only come from ResRobot
+	if arrivals || productSet != 0 {
+		nationwide = true
+	}
+	if nationwide && (len(stops) > 1 || direction != 0 || group != "") {
+		failUsage(fmt.Errorf("-se shows one stop; merged boards, --direction and --group are for SL only"))
 	}
 
 	// Create API client
-	client := api.NewClient()
+	mode := boardMode(stops)
+	var fetch func() (*api.DepartureBoard, error)
+	if nationwide {
+		client := newResRobotClient()
+		requireResRobotKey(client)
+		board, err := newResRobotBoard(ctx, client, stops[0], filter, productSet, arrivals, count)
+		if err != nil {
+			reportAmbiguity(err)
+			fail(err)
+		}
+		stops[0].Location = board.stop.DisplayName()
+		fetch = func() (*api.DepartureBoard, error) {
+			return board.fetch(ctx)
+		}
+	} else {
+		client := newSLClient()
+		fetch = func() (*api.DepartureBoard, error) {
+			return client.GetMergedDepartures(ctx, stops, filter, count)
+		}
+	}
+
+	if watch {
+		runDepartureWatch(ctx, fetch, mode, towards, interval, lang, jsonOutput)
+		return
+	}
 
 	if !jsonOutput {
-		if towards != "" {
-			fmt.Fprintf(os.Stderr, "Söker %s från %s mot %s...\n", modeLower, location, towards)
+		what := mode
+		if what == "" {
+			what = "avgångar"
+		}
+		if arrivals {
+			what = "ankomster"
+		}
+		if arrivals && towards != "" {
+			fmt.Fprintf(os.Stderr, "Söker %s till %s från %s...\n", what, stops.String(), towards)
+		} else if arrivals {
+			fmt.Fprintf(os.Stderr, "Söker %s till %s...\n", what, stops.String())
+		} else if towards != "" {
+			fmt.Fprintf(os.Stderr, "Söker %s från %s mot %s...\n", what, stops.String(), towards)
 		} else {
-			fmt.Fprintf(os.Stderr, "Söker %s från %s...\n", modeLower, location)
+			fmt.Fprintf(os.Stderr, "Söker %s från %s...\n", what, stops.String())
 		}
 	}
 
-	departures, site, err := client.GetNextDepartures(location, modeLower, towards, count)
+	board, err := fetch()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		fail(err)
 	}
 
 	if jsonOutput {
-		jsonStr := output.FormatDeparturesJSON(site.Name, departures)
+		jsonStr := output.FormatDeparturesJSON(board)
 		fmt.Print(jsonStr)
 	} else {
 		// Format and display
 		formatter := display.NewFormatter(lang)
-		out := formatter.FormatDepartures(site, modeLower, towards, departures)
+		out := formatter.FormatDepartures(board, mode, towards)
 		fmt.Print(out)
 	}
 }
 
-func runTripCommand() {
+// parseNextArgs parses "<mode> <location> [towards]" or "<location>".
+// Returns a nil stop if there aren't enough arguments.
+func parseNextArgs(args []string) (*api.StopQuery, []string, error) {
+	if len(args) == 0 {
+		return nil, nil, nil
+	}
+
+	mode := strings.ToLower(args[0])
+	if isAllModes(mode) {
+		if len(args) < 2 {
+			return nil, nil, nil
+		}
+		return &api.StopQuery{Location: args[1]}, args[2:], nil
+	}
+
+	// Normalize and validate mode (supports Swedish and English)
+	if normalized := normalizeMode(mode); normalized != "" {
+		if len(args) < 2 {
+			return nil, nil, nil
+		}
+		return &api.StopQuery{Location: args[1], Mode: normalized}, args[2:], nil
+	}
+
+	// A lone location shows every mode
+	if len(args) == 1 {
+		return &api.StopQuery{Location: args[0]}, nil, nil
+	}
+	return nil, nil, fmt.Errorf("invalid transport mode '%s'", args[0])
+}
+
+func runTripCommand(ctx context.Context) {
 	fs := flag.NewFlagSet("trip", flag.ExitOnError)
 
 	var (
@@ -708,6 +927,12 @@ func runTripCommand() {
 		showVersion bool
 		jsonOutput  bool
 		nationwide  bool
+		selectFlag  string
+		showStops   bool
+		prefs       tripPreferences
+		via         viaStops
+		pages       pageOptions
+		export      exportOptions
 	)
 
 	fs.StringVar(&timeFlag, "time", "", "Departure time (HH:MM)")
@@ -726,8 +951,14 @@ func runTripCommand() {
 	fs.BoolVar(&showVersion, "v", false, "Show version (shorthand)")
 	fs.BoolVar(&jsonOutput, "json", false, "Output as JSON")
 	fs.BoolVar(&jsonOutput, "j", false, "Output JSON (shorthand)")
-	fs.BoolVar(&nationwide, "se", false, "Search all of Sweden (ResRobot)")
-	fs.BoolVar(&nationwide, "sweden", false, "Search all of Sweden (ResRobot)")
+	fs.BoolVar(&nationwide, "se", false, "Always search with ResRobot (default: SL in Stockholm County)")
+	fs.BoolVar(&nationwide, "sweden", false, "Always search with ResRobot (default: SL in Stockholm County)")
+	fs.StringVar(&selectFlag, "select", "", "Pick match N for an ambiguous stop (N, or N,M for origin,destination)")
+	fs.BoolVar(&showStops, "stops", false, "List the stops each leg passes")
+	prefs.register(fs)
+	via.register(fs)
+	pages.register(fs)
+	export.register(fs)
 
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Transport - Sweden public transport planner\n\n")
@@ -735,6 +966,7 @@ func runTripCommand() {
 		fmt.Fprintf(os.Stderr, "  transport [options] <destination>\n")
 		fmt.Fprintf(os.Stderr, "  transport [options] <origin> <destination>\n")
 		fmt.Fprintf(os.Stderr, "  transport next|nästa <mode> <location> [towards]\n")
+		fmt.Fprintf(os.Stderr, "  transport near|nära [lat,lon|address]\n")
 		fmt.Fprintf(os.Stderr, "  transport car|bil <from> <to>\n")
 		fmt.Fprintf(os.Stderr, "  transport fly|flyga from <origin> to <destination>\n")
 		fmt.Fprintf(os.Stderr, "  transport flight|flyg [location]\n")
@@ -744,20 +976,36 @@ func runTripCommand() {
 		fmt.Fprintf(os.Stderr, "Commands:\n")
 		fmt.Fprintf(os.Stderr, "  (default)    Plan a trip between two locations (public transport)\n")
 		fmt.Fprintf(os.Stderr, "  next, nästa  Show next departures (see: transport next --help)\n")
+		fmt.Fprintf(os.Stderr, "  near, nära   Stops within walking distance, anywhere in Sweden\n")
 		fmt.Fprintf(os.Stderr, "  car, bil     Car directions with fuel calculation\n")
 		fmt.Fprintf(os.Stderr, "  fly, flyga   Search for flights (booking links)\n")
 		fmt.Fprintf(os.Stderr, "  flight, flyg Find nearby airports\n")
 		fmt.Fprintf(os.Stderr, "  taxi         Taxi fare estimation & booking\n")
 		fmt.Fprintf(os.Stderr, "  buss         Long-distance buses (FlixBus, Vy, Flygbussarna)\n")
-		fmt.Fprintf(os.Stderr, "  --mcp        Run as MCP server (stdio JSON-RPC)\n\n")
+		fmt.Fprintf(os.Stderr, "  --mcp        Run as MCP server (stdio JSON-RPC)\n")
+		fmt.Fprintf(os.Stderr, "  --debug      Trace every upstream HTTP call to stderr (any command)\n\n")
 		fmt.Fprintf(os.Stderr, "Examples:\n")
 		fmt.Fprintf(os.Stderr, "  transport Odenplan                           # Stockholm (SL)\n")
 		fmt.Fprintf(os.Stderr, "  transport Slussen Odenplan                   # Stockholm (SL)\n")
 		fmt.Fprintf(os.Stderr, "  transport -j Slussen Odenplan                # JSON output\n")
-		fmt.Fprintf(os.Stderr, "  transport -se Sundsvall Ånge                 # Nationwide (ResRobot)\n")
-		fmt.Fprintf(os.Stderr, "  transport -se Göteborg \"Stockholm Central\"   # Nationwide (ResRobot)\n")
+		fmt.Fprintf(os.Stderr, "  transport Uppsala Slussen                    # Outside Stockholm County (ResRobot)\n")
+		fmt.Fprintf(os.Stderr, "  transport Sundsvall Ånge                     # Nationwide (ResRobot)\n")
+		fmt.Fprintf(os.Stderr, "  transport 62.3908,17.3069 Ånge               # Nationwide, from coordinates\n")
+		fmt.Fprintf(os.Stderr, "  transport -se Slussen Odenplan               # Always ResRobot\n")
+		fmt.Fprintf(os.Stderr, "  transport --products regionaltåg Umeå Luleå   # Regional trains only\n")
 		fmt.Fprintf(os.Stderr, "  transport -t 08:30 Slussen T-Centralen\n")
+		fmt.Fprintf(os.Stderr, "  transport \"Kungsgatan 1\" Odenplan             # From an address\n")
+		fmt.Fprintf(os.Stderr, "  transport 59.3293,18.0686 Odenplan           # From coordinates\n")
+		fmt.Fprintf(os.Stderr, "  transport --select 2 Centralen Odenplan      # Pick match 2 for an ambiguous name\n")
+		fmt.Fprintf(os.Stderr, "  transport --modes rail Slussen Kista         # Rail only\n")
+		fmt.Fprintf(os.Stderr, "  transport --wheelchair --min-transfer 8 Slussen Kista\n")
+		fmt.Fprintf(os.Stderr, "  transport --via \"Stockholm City\" --via-dwell 10 Solna Södertälje\n")
+		fmt.Fprintf(os.Stderr, "  transport --span 2h Slussen Kista             # All trips in the next 2 hours\n")
+		fmt.Fprintf(os.Stderr, "  transport --sort most-reliable Solna Kista\n")
+		fmt.Fprintf(os.Stderr, "  transport --stops Odenplan Alvik\n")
+		fmt.Fprintf(os.Stderr, "  transport --export geojson --trip 2 Slussen Kista > resa.geojson\n")
 		fmt.Fprintf(os.Stderr, "  transport nästa buss Odenplan\n")
+		fmt.Fprintf(os.Stderr, "  transport nära 62.3908,17.3069\n")
 		fmt.Fprintf(os.Stderr, "  transport bil \"Stockholm\" \"Åre\"\n")
 		fmt.Fprintf(os.Stderr, "  transport fly from stockholm to vilnius\n")
 		fmt.Fprintf(os.Stderr, "  transport flyg Göteborg\n")
@@ -765,8 +1013,10 @@ func runTripCommand() {
 		fmt.Fprintf(os.Stderr, "  transport buss Stockholm Göteborg\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		fs.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nNationwide search (-se) requires RESROBOT_API_KEY.\n")
+		fmt.Fprintf(os.Stderr, "\nTrips outside Stockholm County, and -se, require RESROBOT_API_KEY.\n")
 		fmt.Fprintf(os.Stderr, "Get a free key at: https://www.trafiklab.se/api/trafiklab-apis/resrobot-v21/\n")
+		fmt.Fprintf(os.Stderr, "\nAmbiguous stop names open a picker on a terminal. Otherwise the command\n")
+		fmt.Fprintf(os.Stderr, "prints the candidates as JSON and exits with code %d; rerun with --select.\n", exitAmbiguous)
 	}
 
 	fs.Parse(os.Args[1:])
@@ -779,7 +1029,21 @@ func runTripCommand() {
 	args := fs.Args()
 	if len(args) < 1 {
 		fs.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+
+	selection, err := parseSelection(selectFlag)
+	if err != nil {
+		failUsage(err)
+	}
+	if err := via.validate(); err != nil {
+		failUsage(err)
+	}
+	if err := pages.validate(); err != nil {
+		failUsage(err)
+	}
+	if err := export.validate(); err != nil {
+		failUsage(err)
 	}
 
 	var origin, dest string
@@ -798,7 +1062,7 @@ func runTripCommand() {
 		parsed, err := tz.ParseStockholm("2006-01-02", dateFlag)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: invalid date format '%s' (use YYYY-MM-DD)\n", dateFlag)
-			os.Exit(1)
+			os.Exit(exitUsage)
 		}
 		searchTime = time.Date(parsed.Year(), parsed.Month(), parsed.Day(),
 			searchTime.Hour(), searchTime.Minute(), 0, 0, searchTime.Location())
@@ -808,98 +1072,66 @@ func runTripCommand() {
 		parsed, err := time.Parse("15:04", timeFlag)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: invalid time format '%s' (use HH:MM)\n", timeFlag)
-			os.Exit(1)
+			os.Exit(exitUsage)
 		}
 		searchTime = time.Date(searchTime.Year(), searchTime.Month(), searchTime.Day(),
 			parsed.Hour(), parsed.Minute(), 0, 0, searchTime.Location())
 	}
 
-	// Use ResRobot for nationwide search
+	// SL plans trips in Stockholm County and ResRobot everywhere else
+	sl := newSLPlanner(&prefs, &pages, &selection)
+	sl.lang = lang
+	sl.maxChanges = maxChanges
+	sl.quiet = jsonOutput
+	rr := newResRobotPlanner(&prefs, &pages, &selection)
+	rr.quiet = jsonOutput
 	if nationwide {
-		runResRobotSearch(origin, dest, searchTime, arriveBy, numResults, jsonOutput)
-		return
-	}
-
-	// Use SL for Stockholm region
-	opts := api.DefaultTripOptions()
-	opts.NumResults = numResults
-	opts.MaxChanges = maxChanges
-	opts.Language = lang
-	opts.ArriveBy = arriveBy
-	opts.Time = searchTime
-
-	// Create API client
-	client := api.NewClient()
-
-	// Search for stops
-	if !jsonOutput {
-		fmt.Fprintf(os.Stderr, "Söker resor från %s till %s...\n", origin, dest)
-	}
-
-	journeys, err := client.PlanTripByName(origin, dest, opts)
-	if err != nil {
-		handleError(err, origin, dest, client)
-		os.Exit(1)
-	}
-
-	if len(journeys) == 0 {
-		fmt.Fprintln(os.Stderr, "Inga resor hittades.")
-		os.Exit(1)
-	}
-
-	if jsonOutput {
-		jsonStr := output.FormatJourneysJSON(origin, dest, journeys, display.GenerateJourneyMapsURL)
-		fmt.Print(jsonStr)
-	} else {
-		// Format and display results
-		formatter := display.NewFormatter(lang)
-		out := formatter.FormatJourneys(origin, dest, journeys)
-		fmt.Print(out)
+		requireResRobotKey(rr.client)
 	}
-}
-
-// runResRobotSearch performs a nationwide search using ResRobot
-func runResRobotSearch(origin, dest string, searchTime time.Time, arriveBy bool, numResults int, jsonOutput bool) {
-	client := resrobot.NewClient()
-
-	if !client.HasAPIKey() {
-		fmt.Fprintln(os.Stderr, "Error: RESROBOT_API_KEY not set")
-		fmt.Fprintln(os.Stderr, "")
-		fmt.Fprintln(os.Stderr, "Get a free API key at:")
-		fmt.Fprintln(os.Stderr, "  https://www.trafiklab.se/api/trafiklab-apis/resrobot-v21/")
-		fmt.Fprintln(os.Stderr, "")
-		fmt.Fprintln(os.Stderr, "Then set it:")
-		fmt.Fprintln(os.Stderr, "  export RESROBOT_API_KEY=\"your-key-here\"")
-		os.Exit(1)
-	}
-
-	opts := resrobot.TripOptions{
-		Time:       searchTime,
-		ArriveBy:   arriveBy,
-		NumResults: numResults,
+	router := newTripRouter(sl, rr, nationwide)
+	router.OnFallback = func(failed, next planner.Planner, err error) {
+		fmt.Fprintf(os.Stderr, "Varning: %s kunde inte planera resan (%v); försöker med %s.\n", failed.Name(), err, next.Name())
 	}
 
-	if !jsonOutput {
-		fmt.Fprintf(os.Stderr, "Söker resor från %s till %s (hela Sverige)...\n", origin, dest)
-	}
+	page, err := router.Plan(ctx, planner.Request{
+		Origin:      origin,
+		Destination: dest,
+		Via:         via.names,
+		ViaDwell:    via.dwell,
+		Time:        searchTime,
+		ArriveBy:    arriveBy,
+		NumResults:  numResults,
 
-	trips, err := client.PlanTripByName(origin, dest, opts)
+		IntermediateStops: showStops || export.enabled(),
+	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		reportAmbiguity(err)
+		handleError(ctx, err, sl.client)
 	}
 
-	if len(trips) == 0 {
-		fmt.Fprintln(os.Stderr, "Inga resor hittades.")
-		os.Exit(1)
+	if len(page.Trips) == 0 {
+		if page.Dropped > 0 {
+			fmt.Fprintf(os.Stderr, "Inga resor hittades med minst %d minuter att byta på (%d resor har kortare byten).\n", prefs.minTransfer, page.Dropped)
+		} else {
+			fmt.Fprintln(os.Stderr, "Inga resor hittades.")
+		}
+		os.Exit(exitNotFound)
 	}
 
-	if jsonOutput {
-		jsonStr := output.FormatResRobotJSON(origin, dest, trips)
-		fmt.Print(jsonStr)
+	if export.enabled() {
+		i, err := export.index(len(page.Trips))
+		if err == nil {
+			err = export.write(page.Trips[i].Route(page.Origin.Name + " → " + page.Destination.Name))
+		}
+		if err != nil {
+			fail(err)
+		}
+	} else if jsonOutput {
+		fmt.Print(output.FormatTripsJSON(page))
 	} else {
-		out := resrobot.FormatTrips(origin, dest, trips)
-		fmt.Print(out)
+		formatter := display.NewFormatter(lang)
+		fmt.Print(formatter.FormatTrips(page))
+		printPageHints(page.Earlier, page.Later)
 	}
 }
 
@@ -916,26 +1148,19 @@ func getDefaultLocation() string {
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "Set a default location with:")
 	fmt.Fprintln(os.Stderr, "  export TRANSPORT_DEFAULT_LOCATION=\"Slussen\"")
-	os.Exit(1)
+	os.Exit(exitUsage)
 	return ""
 }
 
-// handleError provides helpful error messages
-func handleError(err error, origin, dest string, client *api.Client) {
-	errMsg := err.Error()
-
-	if strings.Contains(errMsg, "no stops found") {
-		location := origin
-		if strings.Contains(errMsg, "destination") {
-			location = dest
-		} else if strings.Contains(errMsg, "origin") {
-			location = origin
-		}
-
-		fmt.Fprintf(os.Stderr, "Error: Could not find '%s'\n\n", location)
+// handleError prints a helpful message for an SL error, suggesting stops
+// when a name wasn't found, and exits with the code for its class
+func handleError(ctx context.Context, err error, client *api.Client) {
+	var notFound *errs.NotFoundError
+	if errors.As(err, &notFound) && notFound.Query != "" {
+		fmt.Fprintf(os.Stderr, "Error: Could not find '%s'\n\n", notFound.Query)
 
 		// Try to suggest alternatives
-		stops, searchErr := client.SearchStops(location)
+		stops, searchErr := client.SearchStops(ctx, notFound.Query)
 		if searchErr == nil && len(stops) > 0 {
 			fmt.Fprintln(os.Stderr, "Did you mean:")
 			for i, stop := range stops {
@@ -945,22 +1170,22 @@ func handleError(err error, origin, dest string, client *api.Client) {
 				fmt.Fprintf(os.Stderr, "  %d. %s\n", i+1, stop.Name)
 			}
 		}
-		return
+		os.Exit(exitNotFound)
 	}
 
-	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	fail(err)
 }
 
 // runMCP starts the transport MCP server on stdio.
-func runMCP() {
+func runMCP(ctx context.Context) {
 	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
 	registry := mcp.NewToolRegistry()
 	registerTools(registry)
 	server := mcp.NewServer("transport", version, registry, logger)
 	logger.Info("transport MCP server starting")
-	if err := server.Serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil {
 		logger.Error("MCP server error", "error", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
 
@@ -968,16 +1193,30 @@ func registerTools(registry *mcp.ToolRegistry) {
 	// transport/plan-trip
 	registry.Register(mcp.Tool{
 		Name:        "transport/plan-trip",
-		Description: "Plan a public transport trip in Sweden. Uses SL for Stockholm region, ResRobot for nationwide. Returns trips with departure/arrival times, lines, platforms, and changes.",
+		Description: "Plan a public transport trip anywhere in Sweden. Uses SL when every stop is in Stockholm County and ResRobot otherwise, trying the other if one fails; \"planner\" in the result tells which answered. Returns trips with departure/arrival times, real-time delays and cancellations, lines, platforms, and changes, with \"earlier\"/\"later\" page tokens for paging.",
 		InputSchema: json.RawMessage(`{
 			"type": "object",
 			"properties": {
-				"origin":     {"type": "string", "description": "Origin stop or address"},
-				"destination":{"type": "string", "description": "Destination stop or address"},
+				"origin":     {"type": "string", "description": "Origin stop, address, POI or \"lat,lon\" coordinates"},
+				"destination":{"type": "string", "description": "Destination stop, address, POI or \"lat,lon\" coordinates"},
 				"time":       {"type": "string", "description": "Departure/arrival time HH:MM (default: now)"},
 				"date":       {"type": "string", "description": "Date YYYY-MM-DD (default: today)"},
 				"arriveBy":   {"type": "boolean", "description": "If true, time is arrival time"},
-				"nationwide": {"type": "boolean", "description": "Search all of Sweden via ResRobot (default: Stockholm/SL)"}
+				"nationwide": {"type": "boolean", "description": "Always search with ResRobot (default: SL in Stockholm County, ResRobot elsewhere)"},
+				"modes":              {"type": "string", "description": "Only use these modes, comma-separated: bus, metro, train, tram, ship, or rail"},
+				"excludeModes":       {"type": "string", "description": "Never use these modes, comma-separated (e.g. \"bus\")"},
+				"products":           {"type": "string", "description": "Only these product classes, via ResRobot, comma-separated: long_distance_train, regional_train, local_train, metro, tram, express_bus, bus, ferry, taxi"},
+				"maxWalkMeters":      {"type": "integer", "description": "Longest walk in meters"},
+				"walkSpeed":          {"type": "string", "description": "Walking speed: slow, normal or fast"},
+				"minTransferMinutes": {"type": "integer", "description": "Shortest acceptable time to change, in minutes"},
+				"wheelchair":         {"type": "boolean", "description": "Wheelchair accessible routes only (SL only)"},
+				"stepFree":           {"type": "boolean", "description": "Avoid stairs and escalators (SL only)"},
+				"via":                {"type": "array", "items": {"type": "string"}, "description": "Stops to travel via, in order (one for SL, up to 5 nationwide)"},
+				"viaDwellMinutes":    {"type": "integer", "description": "Minutes to stay at each via stop"},
+				"page":               {"type": "string", "description": "Continue from the \"earlier\" or \"later\" page token of a previous result, with the same stops and options"},
+				"span":               {"type": "string", "description": "Return all trips departing within this long, e.g. \"2h\" (arriving before time with arriveBy)"},
+				"sort":               {"type": "string", "description": "Order trips by fastest, earliest-arrival, fewest-changes, least-walking or most-reliable (default: planner order)"},
+				"stops":              {"type": "boolean", "description": "Include the stops each leg passes, with planned and real-time times and coordinates"}
 			},
 			"required": ["origin", "destination"]
 		}`),
@@ -986,16 +1225,24 @@ func registerTools(registry *mcp.ToolRegistry) {
 	// transport/next-departures
 	registry.Register(mcp.Tool{
 		Name:        "transport/next-departures",
-		Description: "Get real-time next departures from a stop in Stockholm (SL). Returns line, destination, scheduled/expected times, delay status. Provide either location (stop name) or latitude+longitude (finds nearest stop).",
+		Description: "Get real-time next departures from a stop in Sweden. Uses SL in Stockholm County and switches to ResRobot for stops SL doesn't know. Returns line, destination, scheduled/expected times, delay status and service deviations. Provide either location (stop name) or latitude+longitude (finds nearest stop).",
 		InputSchema: json.RawMessage(`{
 			"type": "object",
 			"properties": {
-				"location":  {"type": "string", "description": "Stop or station name"},
-				"latitude":  {"type": "number", "description": "Latitude (WGS84) — used to find nearest stop if location is not provided"},
-				"longitude": {"type": "number", "description": "Longitude (WGS84) — used to find nearest stop if location is not provided"},
-				"mode":      {"type": "string", "description": "Transport mode: bus, metro, train, tram, ship"},
-				"towards":   {"type": "string", "description": "Filter by destination direction"},
-				"count":     {"type": "integer", "description": "Number of departures (default: 3)"}
+				"location":     {"type": "string", "description": "Stop or station name"},
+				"latitude":     {"type": "number", "description": "Latitude (WGS84) — used to find nearest stop if location is not provided"},
+				"longitude":    {"type": "number", "description": "Longitude (WGS84) — used to find nearest stop if location is not provided"},
+				"mode":         {"type": "string", "description": "Transport mode: bus, metro, train, tram, ship"},
+				"towards":      {"type": "string", "description": "Filter by destination or direction, or a stop the line passes on the way"},
+				"lines":        {"type": "string", "description": "Only these line numbers, comma-separated (e.g. \"117,118\")"},
+				"excludeLines": {"type": "string", "description": "Leave out these line numbers, comma-separated"},
+				"direction":    {"type": "integer", "description": "Only this direction code (1 or 2)"},
+				"platform":     {"type": "string", "description": "Only these platforms/\"Läge\", comma-separated (e.g. \"A,B\")"},
+				"group":        {"type": "string", "description": "Only this group of lines (e.g. \"gröna linjen\")"},
+				"count":        {"type": "integer", "description": "Number of departures (default: 3)"},
+				"nationwide":   {"type": "boolean", "description": "Look the stop up in all of Sweden via ResRobot (default: SL first)"},
+				"arrivals":     {"type": "boolean", "description": "Arrivals instead of departures, via ResRobot; towards is then where they come from"},
+				"products":     {"type": "string", "description": "Only these product classes, via ResRobot, comma-separated: long_distance_train, regional_train, local_train, metro, tram, express_bus, bus, ferry, taxi"}
 			}
 		}`),
 	}, handleNextDepartures)
@@ -1031,7 +1278,7 @@ func registerTools(registry *mcp.ToolRegistry) {
 	}, handleCarDirections)
 }
 
-func handlePlanTrip(_ context.Context, raw json.RawMessage) (mcp.ToolCallResult, error) {
+func handlePlanTrip(ctx context.Context, raw json.RawMessage) (mcp.ToolCallResult, error) {
 	var args struct {
 		Origin      string `json:"origin"`
 		Destination string `json:"destination"`
@@ -1039,22 +1286,34 @@ func handlePlanTrip(_ context.Context, raw json.RawMessage) (mcp.ToolCallResult,
 		Date        string `json:"date"`
 		ArriveBy    bool   `json:"arriveBy"`
 		Nationwide  bool   `json:"nationwide"`
+
+		Modes              string `json:"modes"`
+		ExcludeModes       string `json:"excludeModes"`
+		Products           string `json:"products"`
+		MaxWalkMeters      int    `json:"maxWalkMeters"`
+		WalkSpeed          string `json:"walkSpeed"`
+		MinTransferMinutes int    `json:"minTransferMinutes"`
+		Wheelchair         bool   `json:"wheelchair"`
+		StepFree           bool   `json:"stepFree"`
+
+		Via             []string `json:"via"`
+		ViaDwellMinutes int      `json:"viaDwellMinutes"`
+
+		Page string `json:"page"`
+		Span string `json:"span"`
+		Sort string `json:"sort"`
+
+		Stops bool `json:"stops"`
 	}
 	if err := json.Unmarshal(raw, &args); err != nil {
-		return mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{mcp.NewTextContent("invalid arguments: " + err.Error())},
-			IsError: true,
-		}, nil
+		return toolError(errs.Invalidf("invalid arguments: %v", err))
 	}
 
 	searchTime := tz.Now()
 	if args.Date != "" {
 		parsed, err := tz.ParseStockholm("2006-01-02", args.Date)
 		if err != nil {
-			return mcp.ToolCallResult{
-				Content: []mcp.ContentBlock{mcp.NewTextContent("invalid date format: " + args.Date + " (use YYYY-MM-DD)")},
-				IsError: true,
-			}, nil
+			return toolError(errs.Invalidf("invalid date format: %s (use YYYY-MM-DD)", args.Date))
 		}
 		searchTime = time.Date(parsed.Year(), parsed.Month(), parsed.Day(),
 			searchTime.Hour(), searchTime.Minute(), 0, 0, searchTime.Location())
@@ -1062,75 +1321,87 @@ func handlePlanTrip(_ context.Context, raw json.RawMessage) (mcp.ToolCallResult,
 	if args.Time != "" {
 		parsed, err := time.Parse("15:04", args.Time)
 		if err != nil {
-			return mcp.ToolCallResult{
-				Content: []mcp.ContentBlock{mcp.NewTextContent("invalid time format: " + args.Time + " (use HH:MM)")},
-				IsError: true,
-			}, nil
+			return toolError(errs.Invalidf("invalid time format: %s (use HH:MM)", args.Time))
 		}
 		searchTime = time.Date(searchTime.Year(), searchTime.Month(), searchTime.Day(),
 			parsed.Hour(), parsed.Minute(), 0, 0, searchTime.Location())
 	}
 
-	if args.Nationwide {
-		client := resrobot.NewClient()
-		if !client.HasAPIKey() {
-			return mcp.ToolCallResult{
-				Content: []mcp.ContentBlock{mcp.NewTextContent("RESROBOT_API_KEY not set — nationwide search unavailable")},
-				IsError: true,
-			}, nil
-		}
-		opts := resrobot.TripOptions{
-			Time:       searchTime,
-			ArriveBy:   args.ArriveBy,
-			NumResults: 3,
-		}
-		trips, err := client.PlanTripByName(args.Origin, args.Destination, opts)
+	prefs := tripPreferences{
+		modes:        args.Modes,
+		excludeModes: args.ExcludeModes,
+		products:     args.Products,
+		maxWalk:      args.MaxWalkMeters,
+		walkSpeed:    args.WalkSpeed,
+		minTransfer:  args.MinTransferMinutes,
+		wheelchair:   args.Wheelchair,
+		stepFree:     args.StepFree,
+	}
+	via := viaStops{names: args.Via, dwell: args.ViaDwellMinutes}
+	if err := via.validate(); err != nil {
+		return toolError(errs.Invalidf("invalid via: %v", err))
+	}
+
+	pages := pageOptions{token: args.Page, sort: args.Sort}
+	if args.Span != "" {
+		span, err := time.ParseDuration(args.Span)
 		if err != nil {
-			return mcp.ToolCallResult{
-				Content: []mcp.ContentBlock{mcp.NewTextContent("trip planning failed: " + err.Error())},
-				IsError: true,
-			}, nil
+			return toolError(errs.Invalidf("invalid span: %s (use e.g. 2h or 90m)", args.Span))
 		}
-		result := output.FormatResRobotJSON(args.Origin, args.Destination, trips)
-		return mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{mcp.NewTextContent(result)},
-		}, nil
+		pages.span = span
+	}
+	if err := pages.validate(); err != nil {
+		return toolError(errs.Invalidf("invalid paging: %v", err))
+	}
+
+	selection := locationSelection{}
+	sl := newSLPlanner(&prefs, &pages, &selection)
+	sl.quiet = true
+	rr := newResRobotPlanner(&prefs, &pages, &selection)
+	rr.quiet = true
+	rr.strict = true
+	if args.Nationwide && !rr.client.HasAPIKey() {
+		return toolError(fmt.Errorf("nationwide search unavailable: %w", resrobot.ErrNoAPIKey))
 	}
 
-	// SL (Stockholm region)
-	opts := api.DefaultTripOptions()
-	opts.NumResults = 3
-	opts.ArriveBy = args.ArriveBy
-	opts.Time = searchTime
+	page, err := newTripRouter(sl, rr, args.Nationwide).Plan(ctx, planner.Request{
+		Origin:      args.Origin,
+		Destination: args.Destination,
+		Via:         via.names,
+		ViaDwell:    via.dwell,
+		Time:        searchTime,
+		ArriveBy:    args.ArriveBy,
+		NumResults:  3,
 
-	client := api.NewClient()
-	journeys, err := client.PlanTripByName(args.Origin, args.Destination, opts)
+		IntermediateStops: args.Stops,
+	})
 	if err != nil {
-		return mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{mcp.NewTextContent("trip planning failed: " + err.Error())},
-			IsError: true,
-		}, nil
+		return toolError(fmt.Errorf("trip planning failed: %w", err))
 	}
-	result := output.FormatJourneysJSON(args.Origin, args.Destination, journeys, display.GenerateJourneyMapsURL)
 	return mcp.ToolCallResult{
-		Content: []mcp.ContentBlock{mcp.NewTextContent(result)},
+		Content: []mcp.ContentBlock{mcp.NewTextContent(output.FormatTripsJSON(page))},
 	}, nil
 }
 
-func handleNextDepartures(_ context.Context, raw json.RawMessage) (mcp.ToolCallResult, error) {
+func handleNextDepartures(ctx context.Context, raw json.RawMessage) (mcp.ToolCallResult, error) {
 	var args struct {
-		Location  string   `json:"location"`
-		Latitude  *float64 `json:"latitude"`
-		Longitude *float64 `json:"longitude"`
-		Mode      string   `json:"mode"`
-		Towards   string   `json:"towards"`
-		Count     int      `json:"count"`
+		Location     string   `json:"location"`
+		Latitude     *float64 `json:"latitude"`
+		Longitude    *float64 `json:"longitude"`
+		Mode         string   `json:"mode"`
+		Towards      string   `json:"towards"`
+		Lines        string   `json:"lines"`
+		ExcludeLines string   `json:"excludeLines"`
+		Direction    int      `json:"direction"`
+		Platform     string   `json:"platform"`
+		Group        string   `json:"group"`
+		Count        int      `json:"count"`
+		Nationwide   bool     `json:"nationwide"`
+		Arrivals     bool     `json:"arrivals"`
+		Products     string   `json:"products"`
 	}
 	if err := json.Unmarshal(raw, &args); err != nil {
-		return mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{mcp.NewTextContent("invalid arguments: " + err.Error())},
-			IsError: true,
-		}, nil
+		return toolError(errs.Invalidf("invalid arguments: %v", err))
 	}
 
 	if args.Count <= 0 {
@@ -1141,89 +1412,134 @@ func handleNextDepartures(_ context.Context, raw json.RawMessage) (mcp.ToolCallR
 	if args.Mode != "" {
 		mode = normalizeMode(strings.ToLower(args.Mode))
 		if mode == "" {
-			return mcp.ToolCallResult{
-				Content: []mcp.ContentBlock{mcp.NewTextContent("invalid mode: " + args.Mode + " (use bus, metro, train, tram, ship)")},
-				IsError: true,
-			}, nil
+			return toolError(errs.Invalidf("invalid mode: %s (use bus, metro, train, tram, ship)", args.Mode))
 		}
 	}
 
-	client := api.NewClient()
+	products, err := resrobot.ParseProducts(args.Products)
+	if err != nil {
+		return toolError(errs.Invalidf("%v", err))
+	}
+
+	client := newSLClient()
 
 	// Resolve location: use stop name if provided, otherwise find nearest stop by coordinates.
+	// Outside Stockholm County the nearest ResRobot stop is used instead.
+	nationwide := args.Nationwide || args.Arrivals || products != 0
+	hasResRobot := newResRobotClient().HasAPIKey()
 	location := args.Location
 	if location == "" && args.Latitude != nil && args.Longitude != nil {
-		site, distMeters, err := client.NearestSite(*args.Latitude, *args.Longitude)
-		if err != nil {
-			return mcp.ToolCallResult{
-				Content: []mcp.ContentBlock{mcp.NewTextContent("couldn't find a nearby stop: " + err.Error())},
-				IsError: true,
-			}, nil
+		lat, lon := *args.Latitude, *args.Longitude
+		if !nationwide && hasResRobot && !api.InServiceArea(geo.Point{Lat: lat, Lon: lon}) {
+			nationwide = true
 		}
-		if distMeters > 5000 {
-			return mcp.ToolCallResult{
-				Content: []mcp.ContentBlock{mcp.NewTextContent(fmt.Sprintf("nearest stop is %s (%.0f m away) — too far for useful departures", site.Name, distMeters))},
-				IsError: true,
-			}, nil
+		if !nationwide {
+			site, distMeters, err := client.NearestSite(ctx, lat, lon)
+			switch {
+			case err != nil && ctx.Err() != nil:
+				return toolError(ctx.Err())
+			case err == nil && distMeters <= maxBoardStopMeters:
+				location = site.Name
+			case !hasResRobot:
+				if err != nil {
+					return toolError(fmt.Errorf("couldn't find a nearby stop: %w", err))
+				}
+				return toolError(fmt.Errorf("%w: nearest stop is %s (%.0f m away), too far for useful departures", errs.ErrNotFound, site.Name, distMeters))
+			default:
+				nationwide = true
+			}
+		}
+		if nationwide {
+			location = fmt.Sprintf("%.6f,%.6f", lat, lon)
 		}
-		location = site.Name
 	}
 
 	if location == "" {
-		return mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{mcp.NewTextContent("either location or latitude+longitude is required")},
-			IsError: true,
-		}, nil
+		return toolError(errs.Invalidf("either location or latitude+longitude is required"))
+	}
+
+	filter := api.DepartureFilter{
+		Mode:         mode,
+		Towards:      args.Towards,
+		Lines:        splitList(args.Lines),
+		ExcludeLines: splitList(args.ExcludeLines),
+		Direction:    args.Direction,
+		Platforms:    splitList(args.Platform),
+		GroupOfLines: args.Group,
+	}
+	stop := api.StopQuery{Location: location}
+
+	// SL has the full timetable only in Stockholm County, the same rule
+	// the trip router uses; stops elsewhere, and those SL doesn't know,
+	// are looked up nationwide instead
+	if !nationwide && args.Location != "" && hasResRobot {
+		site, err := client.FindSite(ctx, location)
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			nationwide = true
+		case err != nil:
+			return toolError(fmt.Errorf("departures lookup failed: %w", err))
+		default:
+			nationwide = !site.InServiceArea()
+		}
 	}
 
-	departures, site, err := client.GetNextDepartures(location, mode, args.Towards, args.Count)
+	var board *api.DepartureBoard
+	if nationwide {
+		board, err = nationwideBoard(ctx, stop, filter, products, args.Arrivals, args.Count)
+	} else {
+		board, err = client.GetMergedDepartures(ctx, []api.StopQuery{stop}, filter, args.Count)
+	}
 	if err != nil {
-		return mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{mcp.NewTextContent("departures lookup failed: " + err.Error())},
-			IsError: true,
-		}, nil
+		return toolError(fmt.Errorf("departures lookup failed: %w", err))
 	}
 
-	result := output.FormatDeparturesJSON(site.Name, departures)
+	result := output.FormatDeparturesJSON(board)
 	return mcp.ToolCallResult{
 		Content: []mcp.ContentBlock{mcp.NewTextContent(result)},
 	}, nil
 }
 
-func handleTaxiEstimate(_ context.Context, raw json.RawMessage) (mcp.ToolCallResult, error) {
+// nationwideBoard fetches a departure or arrival board from ResRobot for
+// the next-departures tool
+func nationwideBoard(ctx context.Context, stop api.StopQuery, filter api.DepartureFilter, products resrobot.Products, arrivals bool, count int) (*api.DepartureBoard, error) {
+	client := newResRobotClient()
+	if !client.HasAPIKey() {
+		return nil, fmt.Errorf("nationwide departures unavailable: %w", resrobot.ErrNoAPIKey)
+	}
+	if filter.Direction != 0 || filter.GroupOfLines != "" {
+		return nil, errs.Invalidf("direction and group filters are only available for SL stops")
+	}
+	board, err := newResRobotBoard(ctx, client, stop, filter, products, arrivals, count)
+	if err != nil {
+		return nil, err
+	}
+	return board.fetch(ctx)
+}
+
+func handleTaxiEstimate(ctx context.Context, raw json.RawMessage) (mcp.ToolCallResult, error) {
 	var args struct {
 		From string `json:"from"`
 		To   string `json:"to"`
 	}
 	if err := json.Unmarshal(raw, &args); err != nil {
-		return mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{mcp.NewTextContent("invalid arguments: " + err.Error())},
-			IsError: true,
-		}, nil
+		return toolError(errs.Invalidf("invalid arguments: %v", err))
 	}
 
-	fromLoc, err := taxi.Geocode(args.From)
+	client := newTaxiClient()
+	fromLoc, err := client.Geocode(ctx, args.From)
 	if err != nil {
-		return mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{mcp.NewTextContent("could not find location: " + args.From + " — " + err.Error())},
-			IsError: true,
-		}, nil
+		return toolError(fmt.Errorf("could not find location %s: %w", args.From, err))
 	}
 
-	toLoc, err := taxi.Geocode(args.To)
+	toLoc, err := client.Geocode(ctx, args.To)
 	if err != nil {
-		return mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{mcp.NewTextContent("could not find location: " + args.To + " — " + err.Error())},
-			IsError: true,
-		}, nil
+		return toolError(fmt.Errorf("could not find location %s: %w", args.To, err))
 	}
 
-	route, err := taxi.CalculateRoute(fromLoc, toLoc)
+	route, err := client.CalculateRoute(ctx, fromLoc, toLoc)
 	if err != nil {
-		return mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{mcp.NewTextContent("route calculation failed: " + err.Error())},
-			IsError: true,
-		}, nil
+		return toolError(fmt.Errorf("route calculation failed: %w", err))
 	}
 
 	estimates := taxi.GetFareEstimates(route)
@@ -1240,7 +1556,7 @@ func handleTaxiEstimate(_ context.Context, raw json.RawMessage) (mcp.ToolCallRes
 	}, nil
 }
 
-func handleCarDirections(_ context.Context, raw json.RawMessage) (mcp.ToolCallResult, error) {
+func handleCarDirections(ctx context.Context, raw json.RawMessage) (mcp.ToolCallResult, error) {
 	var args struct {
 		From        string  `json:"from"`
 		To          string  `json:"to"`
@@ -1248,10 +1564,7 @@ func handleCarDirections(_ context.Context, raw json.RawMessage) (mcp.ToolCallRe
 		FuelPercent float64 `json:"fuelPercent"`
 	}
 	if err := json.Unmarshal(raw, &args); err != nil {
-		return mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{mcp.NewTextContent("invalid arguments: " + err.Error())},
-			IsError: true,
-		}, nil
+		return toolError(errs.Invalidf("invalid arguments: %v", err))
 	}
 
 	if args.FuelPercent <= 0 {
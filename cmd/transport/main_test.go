@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"transport/internal/output"
+	"transport/internal/transporttest"
+)
+
+// envRunMain makes the test binary run main instead of the tests, so that
+// the tests can run the command as a subprocess
+const envRunMain = "TRANSPORT_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(envRunMain) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// result is the outcome of running the command
+type result struct {
+	stdout, stderr string
+	code           int
+}
+
+// run runs the transport command with args against srv
+func run(t *testing.T, srv *transporttest.Server, args ...string) result {
+	t.Helper()
+	return runEnv(t, srv, nil, args...)
+}
+
+// runEnv runs the transport command with args against srv, with env
+// added to its environment
+func runEnv(t *testing.T, srv *transporttest.Server, env []string, args ...string) result {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), srv.Env()...)
+	cmd.Env = append(cmd.Env, envRunMain+"=1", "HOME="+t.TempDir())
+	cmd.Env = append(cmd.Env, env...)
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	var r result
+	if err := cmd.Run(); err != nil {
+		var exit *exec.ExitError
+		if !errors.As(err, &exit) {
+			t.Fatalf("transport %s: %v", strings.Join(args, " "), err)
+		}
+		r.code = exit.ExitCode()
+	}
+	r.stdout, r.stderr = stdout.String(), stderr.String()
+	return r
+}
+
+// tripResult decodes the JSON output of a trip search
+func tripResult(t *testing.T, r result) output.TripResult {
+	t.Helper()
+	if r.code != 0 {
+		t.Fatalf("exit %d\n%s", r.code, r.stderr)
+	}
+	var out struct {
+		Data output.TripResult `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(r.stdout), &out); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, r.stdout)
+	}
+	return out.Data
+}
+
+// hit reports whether the server was asked for anything under path
+func hit(srv *transporttest.Server, path string) bool {
+	for _, req := range srv.Requests() {
+		if strings.HasPrefix(req, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTripPlanner(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		slFails   bool
+		want      string // planner in the JSON output
+		resrobot  bool   // ResRobot was asked for trips
+		slJourney bool   // SL was asked for trips
+	}{
+		{"inside Stockholm County", []string{"-j", "Slussen", "Odenplan"}, false, "SL", false, true},
+		{"from outside", []string{"-j", "Uppsala", "Slussen"}, false, "ResRobot", true, false},
+		{"forced nationwide", []string{"-j", "-se", "Slussen", "Odenplan"}, false, "ResRobot", true, false},
+		{"SL failing", []string{"-j", "Slussen", "Odenplan"}, true, "ResRobot", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := transporttest.Start(t)
+			if tt.slFails {
+				srv.Handle("/sl/journey/trips", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					http.Error(w, "maintenance", http.StatusServiceUnavailable)
+				}))
+			}
+
+			out := tripResult(t, run(t, srv, tt.args...))
+			if out.Planner != tt.want || len(out.Trips) == 0 {
+				t.Errorf("planner %q with %d trips, want trips from %q", out.Planner, len(out.Trips), tt.want)
+			}
+			if got := hit(srv, "/resrobot/trip"); got != tt.resrobot {
+				t.Errorf("asked ResRobot for trips: %v, want %v", got, tt.resrobot)
+			}
+			if got := hit(srv, "/sl/journey/trips"); got != tt.slJourney {
+				t.Errorf("asked SL for trips: %v, want %v", got, tt.slJourney)
+			}
+		})
+	}
+}
+
+func TestNextDeparturesExactStop(t *testing.T) {
+	srv := transporttest.Start(t)
+
+	r := run(t, srv, "nästa", "buss", "Kisa")
+	if r.code != exitNotFound {
+		t.Errorf("exit %d, want %d (not found)\n%s%s", r.code, exitNotFound, r.stdout, r.stderr)
+	}
+	if !strings.Contains(r.stderr, "Did you mean: Kista") {
+		t.Errorf("Kista not suggested for Kisa:\n%s", r.stderr)
+	}
+	if hit(srv, "/sl/transport/sites/9302/departures") {
+		t.Errorf("asked SL for departures from Kista")
+	}
+}
+
+func TestMinTransferDropsTrips(t *testing.T) {
+	srv := transporttest.Start(t)
+
+	out := tripResult(t, run(t, srv, "-j", "--min-transfer", "10", "Slussen", "Odenplan"))
+	if out.Dropped == 0 {
+		t.Errorf("no trips dropped for a 10 minute change, %d trips shown", len(out.Trips))
+	}
+}
+
+func TestPageTokenBelongsToItsSearch(t *testing.T) {
+	tests := []struct {
+		name         string
+		search, next []string
+	}{
+		{"SL", []string{"Slussen", "Odenplan"}, []string{"Slussen", "T-Centralen"}},
+		{"ResRobot", []string{"Göteborg", "Stockholm"}, []string{"Göteborg", "Uppsala"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := transporttest.Start(t)
+
+			out := tripResult(t, run(t, srv, append([]string{"-j"}, tt.search...)...))
+			if out.Later == "" {
+				t.Fatal("no page token in the output")
+			}
+
+			if r := run(t, srv, append([]string{"-j", "--page", out.Later}, tt.search...)...); r.code != 0 {
+				t.Errorf("token rejected for its own search: exit %d\n%s", r.code, r.stderr)
+			}
+			r := run(t, srv, append([]string{"-j", "--page", out.Later}, tt.next...)...)
+			if r.code != exitUsage || !strings.Contains(r.stderr, "another search") {
+				t.Errorf("token for another search: exit %d, want %d\n%s", r.code, exitUsage, r.stderr)
+			}
+		})
+	}
+}
+
+func TestNextDeparturesTowardsStopOnTheWay(t *testing.T) {
+	srv := transporttest.Start(t)
+
+	// Line 19 passes Odenplan on its way to Hässelby strand; line 14 to
+	// Mörby centrum doesn't go there without a change
+	r := run(t, srv, "nästa", "-j", "tunnelbana", "Slussen", "Odenplan")
+	if r.code != 0 {
+		t.Fatalf("exit %d\n%s", r.code, r.stderr)
+	}
+	var out struct {
+		Data struct {
+			Departures []struct {
+				Line        string `json:"line"`
+				Destination string `json:"destination"`
+			} `json:"departures"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(r.stdout), &out); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, r.stdout)
+	}
+	var lines []string
+	for _, dep := range out.Data.Departures {
+		lines = append(lines, dep.Line+" "+dep.Destination)
+	}
+	if strings.Join(lines, ",") != "19 Hässelby strand" {
+		t.Errorf("departures %q, want line 19 to Hässelby strand only", lines)
+	}
+}
+
+func TestTripWithoutResRobotKey(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"inside Stockholm County", []string{"-j", "Slussen", "Odenplan"}, 0},
+		{"unknown to SL", []string{"-j", "Slussen", "Kista"}, exitNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := transporttest.Start(t)
+			r := runEnv(t, srv, []string{"RESROBOT_API_KEY=", "TRAFIKLAB_RESROBOT_KEY="}, tt.args...)
+			if r.code != tt.code {
+				t.Errorf("exit %d, want %d\n%s", r.code, tt.code, r.stderr)
+			}
+			if strings.Contains(r.stderr, "ResRobot") {
+				t.Errorf("ResRobot mentioned without a key:\n%s", r.stderr)
+			}
+			if hit(srv, "/resrobot/") {
+				t.Errorf("asked ResRobot without a key")
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"transport/internal/geo"
+	"transport/internal/output"
+	"transport/internal/resrobot"
+)
+
+// isNearCommand checks if the argument is a "near" command (English or Swedish)
+func isNearCommand(arg string) bool {
+	switch strings.ToLower(arg) {
+	case "near", "nearby", "nära", "nara":
+		return true
+	}
+	return false
+}
+
+func runNearCommand(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("near", flag.ExitOnError)
+
+	var (
+		radius     int
+		count      int
+		jsonOutput bool
+	)
+
+	fs.IntVar(&radius, "r", resrobot.DefaultNearbyRadius, "Search radius in meters")
+	fs.IntVar(&radius, "radius", resrobot.DefaultNearbyRadius, "Search radius in meters")
+	fs.IntVar(&count, "n", 10, "Number of stops to show")
+	fs.BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	fs.BoolVar(&jsonOutput, "j", false, "Output JSON (shorthand)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Transport - Nearby stops / Hållplatser i närheten\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  transport near|nära [options] [lat,lon|address]\n\n")
+		fmt.Fprintf(os.Stderr, "Arguments:\n")
+		fmt.Fprintf(os.Stderr, "  lat,lon   Coordinates, e.g. 62.3908,17.3069\n")
+		fmt.Fprintf(os.Stderr, "  address   Street address or place anywhere in Sweden\n")
+		fmt.Fprintf(os.Stderr, "            (default: TRANSPORT_DEFAULT_LOCATION)\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  transport nära 62.3908,17.3069\n")
+		fmt.Fprintf(os.Stderr, "  transport nära \"Storgatan 10, Sundsvall\"\n")
+		fmt.Fprintf(os.Stderr, "  transport nära -r 500 -n 5 Slussen\n")
+	}
+
+	fs.Parse(args)
+	posArgs := fs.Args()
+	if len(posArgs) > 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	var place string
+	if len(posArgs) == 1 {
+		place = posArgs[0]
+	} else {
+		place = getDefaultLocation()
+	}
+
+	if radius <= 0 {
+		failUsage(fmt.Errorf("--radius must be positive"))
+	}
+
+	client := newResRobotClient()
+	requireResRobotKey(client)
+
+	p, ok := geo.ParseLatLon(place)
+	if !ok {
+		loc, err := newTaxiClient().GeocodeIn(ctx, place, "")
+		if err != nil {
+			fail(err)
+		}
+		p = geo.Point{Lat: loc.Lat, Lon: loc.Lon}
+	}
+
+	stops, err := client.NearbyStops(ctx, p.Lat, p.Lon, radius, count)
+	if err != nil {
+		fail(err)
+	}
+
+	if jsonOutput {
+		fmt.Print(output.FormatNearbyStopsJSON(place, p.Lat, p.Lon, radius, stops))
+	} else {
+		fmt.Print(resrobot.FormatNearbyStops(place, radius, stops))
+	}
+	if len(stops) == 0 {
+		os.Exit(exitNotFound)
+	}
+}
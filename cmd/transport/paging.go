@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"transport/internal/api"
+	"transport/internal/rank"
+	"transport/internal/resrobot"
+)
+
+// pageOptions holds the flags for paging through and ordering trip
+// results, shared by the CLI and the MCP plan-trip tool
+type pageOptions struct {
+	later   bool
+	earlier bool
+	token   string        // continue from a page token in earlier JSON output
+	span    time.Duration // collect every trip departing within this long
+	sort    string        // rank strategy name, "" keeps the planner's order
+
+	strategy rank.Strategy // parsed sort, set by validate
+}
+
+// register adds the paging and sort flags to fs
+func (p *pageOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&p.later, "later", false, "Show the trips after these")
+	fs.BoolVar(&p.earlier, "earlier", false, "Show the trips before these")
+	fs.StringVar(&p.token, "page", "", "Continue from a page token (\"earlier\"/\"later\" in JSON output)")
+	fs.DurationVar(&p.span, "span", 0, "Show all trips departing within this long, e.g. 2h")
+	fs.StringVar(&p.sort, "sort", "", "Sort trips: fastest, earliest-arrival, fewest-changes, least-walking or most-reliable")
+}
+
+// validate checks that the paging flags can be combined
+func (p *pageOptions) validate() error {
+	if p.later && p.earlier {
+		return fmt.Errorf("--later and --earlier can't be combined")
+	}
+	if p.span < 0 {
+		return fmt.Errorf("--span can't be negative")
+	}
+	if p.span > 0 && (p.later || p.earlier || p.token != "") {
+		return fmt.Errorf("--span can't be combined with --later, --earlier or --page")
+	}
+	if p.token != "" && !api.IsPageToken(p.token) && !resrobot.IsPageToken(p.token) {
+		return fmt.Errorf("invalid page token '%s'", p.token)
+	}
+	if p.sort != "" {
+		strategy, err := rank.ParseStrategy(p.sort)
+		if err != nil {
+			return err
+		}
+		p.strategy = strategy
+	}
+	return nil
+}
+
+// step returns the token for the page --later or --earlier asks for
+func (p *pageOptions) step(earlier, later string) string {
+	if p.earlier {
+		return earlier
+	}
+	return later
+}
+
+// planSL finds the page of SL journeys the flags ask for, sorted by --sort
+func (p *pageOptions) planSL(ctx context.Context, client *api.Client, origin, dest *api.Location, opts api.TripOptions) (*api.TripPage, error) {
+	page, err := p.fetchSL(ctx, client, origin, dest, opts)
+	if err == nil && p.strategy != "" {
+		api.SortJourneys(page.Journeys, p.strategy)
+	}
+	return page, err
+}
+
+// fetchSL requests the page of SL journeys the paging flags ask for
+func (p *pageOptions) fetchSL(ctx context.Context, client *api.Client, origin, dest *api.Location, opts api.TripOptions) (*api.TripPage, error) {
+	if p.span > 0 {
+		return client.PlanTripSpan(ctx, origin, dest, opts, p.span)
+	}
+
+	page, err := client.PlanTripPage(ctx, origin, dest, opts, p.token)
+	if err != nil || !(p.later || p.earlier) {
+		return page, err
+	}
+	if token := p.step(page.Earlier, page.Later); token != "" {
+		return client.PlanTripPage(ctx, origin, dest, opts, token)
+	}
+	return &api.TripPage{}, nil
+}
+
+// planResRobot finds the page of ResRobot trips the flags ask for, sorted
+// by --sort
+func (p *pageOptions) planResRobot(ctx context.Context, client *resrobot.Client, originID, destID string, opts resrobot.TripOptions) (*resrobot.TripPage, error) {
+	page, err := p.fetchResRobot(ctx, client, originID, destID, opts)
+	if err == nil && p.strategy != "" {
+		resrobot.SortTrips(page.Trips, p.strategy)
+	}
+	return page, err
+}
+
+// fetchResRobot requests the page of ResRobot trips the paging flags ask for
+func (p *pageOptions) fetchResRobot(ctx context.Context, client *resrobot.Client, originID, destID string, opts resrobot.TripOptions) (*resrobot.TripPage, error) {
+	if p.span > 0 {
+		return client.PlanTripSpan(ctx, originID, destID, opts, p.span)
+	}
+
+	page, err := client.PlanTripPage(ctx, originID, destID, opts, p.token)
+	if err != nil || !(p.later || p.earlier) {
+		return page, err
+	}
+	if token := p.step(page.Earlier, page.Later); token != "" {
+		return client.PlanTripPage(ctx, originID, destID, opts, token)
+	}
+	return &resrobot.TripPage{}, nil
+}
+
+// printPageHints tells how to continue paging from the text output
+func printPageHints(earlier, later string) {
+	if earlier != "" {
+		fmt.Fprintf(os.Stderr, "Tidigare resor: --page %s\n", earlier)
+	}
+	if later != "" {
+		fmt.Fprintf(os.Stderr, "Senare resor:   --page %s\n", later)
+	}
+}
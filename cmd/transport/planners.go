@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"transport/internal/api"
+	"transport/internal/display"
+	"transport/internal/errs"
+	"transport/internal/planner"
+	"transport/internal/resrobot"
+)
+
+// slPlanner plans trips in Stockholm County with SL, honouring the trip
+// flags shared by the CLI and the MCP plan-trip tool
+type slPlanner struct {
+	client     *api.Client
+	prefs      *tripPreferences
+	pages      *pageOptions
+	selection  *locationSelection // the --select choices; each search starts from them
+	lang       string
+	maxChanges int
+	quiet      bool // don't report progress on stderr, e.g. for JSON output
+
+	candidates map[string][]api.Location // matches per query, from Covers
+}
+
+// newSLPlanner creates an SL planner for the flags
+func newSLPlanner(prefs *tripPreferences, pages *pageOptions, selection *locationSelection) *slPlanner {
+	return &slPlanner{
+		client:     newSLClient(),
+		prefs:      prefs,
+		pages:      pages,
+		selection:  selection,
+		lang:       "sv",
+		maxChanges: -1,
+		candidates: make(map[string][]api.Location),
+	}
+}
+
+// Name returns "SL"
+func (p *slPlanner) Name() string {
+	return api.PlannerName
+}
+
+// MaxVias returns the number of via stops the SL journey planner takes
+func (p *slPlanner) MaxVias() int {
+	return api.MaxVias
+}
+
+// Covers reports whether the query matches a place in Stockholm County.
+// When it matches several, any one in the county will do, so that the
+// user is asked which one they meant.
+func (p *slPlanner) Covers(ctx context.Context, query string) (bool, error) {
+	candidates, err := p.lookup(ctx, query)
+	if err != nil || len(candidates) == 0 {
+		return false, err
+	}
+
+	loc, err := api.ChooseLocation(query, candidates)
+	if err == nil {
+		return loc.InServiceArea(), nil
+	}
+	for i := range candidates {
+		if candidates[i].InServiceArea() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// lookup returns the places the query matches, asking SL only once per
+// query
+func (p *slPlanner) lookup(ctx context.Context, query string) ([]api.Location, error) {
+	if candidates, ok := p.candidates[query]; ok {
+		return candidates, nil
+	}
+	candidates, err := p.client.LocationCandidates(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	p.candidates[query] = candidates
+	return candidates, nil
+}
+
+// resolve resolves one end or the via stop of the trip with the choices
+// left in sel, see resolveSLEndpoint
+func (p *slPlanner) resolve(ctx context.Context, query, role string, sel *locationSelection) (*api.Location, error) {
+	candidates, err := p.lookup(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s '%s': %w", role, query, err)
+	}
+	return chooseSLEndpoint(ctx, query, role, candidates, sel, display.NewFormatter(p.lang))
+}
+
+// Plan finds SL journeys for the request
+func (p *slPlanner) Plan(ctx context.Context, req planner.Request) (*planner.Page, error) {
+	opts := p.client.DefaultTripOptions()
+	opts.NumResults = req.NumResults
+	opts.MaxChanges = p.maxChanges
+	opts.Language = p.lang
+	opts.ArriveBy = req.ArriveBy
+	opts.Time = req.Time
+	opts.IntermediateStops = req.IntermediateStops
+	if err := p.prefs.applySL(&opts); err != nil {
+		return nil, errs.Invalidf("invalid preferences: %v", err)
+	}
+
+	// A search that falls back from ResRobot picks with the same choices
+	sel := *p.selection
+	originLoc, err := p.resolve(ctx, req.Origin, "origin", &sel)
+	if err != nil {
+		return nil, err
+	}
+	destLoc, err := p.resolve(ctx, req.Destination, "destination", &sel)
+	if err != nil {
+		return nil, err
+	}
+	via := viaStops{names: req.Via, dwell: req.ViaDwell}
+	viaLoc, err := via.resolveSL(ctx, p, &sel)
+	if err != nil {
+		return nil, err
+	}
+	opts.Via = viaLoc
+	opts.ViaDwellMinutes = via.dwell
+
+	if !p.quiet {
+		formatter := display.NewFormatter(p.lang)
+		fmt.Fprintf(os.Stderr, "Söker resor från %s (%s) till %s (%s)",
+			originLoc.DisplayName(), formatter.LocationKindName(originLoc.Kind()),
+			destLoc.DisplayName(), formatter.LocationKindName(destLoc.Kind()))
+		if viaLoc != nil {
+			fmt.Fprintf(os.Stderr, " via %s", viaLoc.DisplayName())
+		}
+		fmt.Fprintln(os.Stderr, "...")
+	}
+
+	page, err := p.pages.planSL(ctx, p.client, originLoc, destLoc, opts)
+	if err != nil {
+		return nil, err
+	}
+	return page.PlannerPage(originLoc, destLoc, viaLoc), nil
+}
+
+// resRobotPlanner plans trips in all of Sweden with ResRobot, honouring
+// the trip flags shared by the CLI and the MCP plan-trip tool
+type resRobotPlanner struct {
+	client    *resrobot.Client
+	prefs     *tripPreferences
+	pages     *pageOptions
+	selection *locationSelection // the --select choices; each search starts from them
+	quiet     bool               // don't report progress on stderr, e.g. for JSON output
+
+	// strict fails searches for accessible routes, which ResRobot can't
+	// plan, instead of warning about them
+	strict bool
+}
+
+// newResRobotPlanner creates a ResRobot planner for the flags
+func newResRobotPlanner(prefs *tripPreferences, pages *pageOptions, selection *locationSelection) *resRobotPlanner {
+	return &resRobotPlanner{
+		client:    newResRobotClient(),
+		prefs:     prefs,
+		pages:     pages,
+		selection: selection,
+	}
+}
+
+// Name returns "ResRobot"
+func (p *resRobotPlanner) Name() string {
+	return resrobot.PlannerName
+}
+
+// MaxVias returns the number of via stops ResRobot takes
+func (p *resRobotPlanner) MaxVias() int {
+	return resrobot.MaxVias
+}
+
+// Covers reports true: ResRobot has the timetables of all of Sweden
+func (p *resRobotPlanner) Covers(ctx context.Context, query string) (bool, error) {
+	return true, nil
+}
+
+// Plan finds ResRobot trips for the request
+func (p *resRobotPlanner) Plan(ctx context.Context, req planner.Request) (*planner.Page, error) {
+	if !p.client.HasAPIKey() {
+		return nil, fmt.Errorf("nationwide search unavailable: %w", resrobot.ErrNoAPIKey)
+	}
+
+	opts := resrobot.TripOptions{
+		Time:       req.Time,
+		ArriveBy:   req.ArriveBy,
+		NumResults: req.NumResults,
+
+		IntermediateStops: req.IntermediateStops,
+	}
+	unsupported, err := p.prefs.applyResRobot(&opts)
+	if err != nil {
+		return nil, errs.Invalidf("invalid preferences: %v", err)
+	}
+	if unsupported && p.strict {
+		return nil, errs.Invalidf("wheelchair and step-free routing are not supported for nationwide search")
+	}
+
+	// A search that falls back from SL picks with the same choices
+	sel := *p.selection
+	originStop, err := resolveResRobotEndpoint(ctx, p.client, req.Origin, "origin", &sel)
+	if err != nil {
+		return nil, err
+	}
+	destStop, err := resolveResRobotEndpoint(ctx, p.client, req.Destination, "destination", &sel)
+	if err != nil {
+		return nil, err
+	}
+	via := viaStops{names: req.Via, dwell: req.ViaDwell}
+	viaList, vias, err := via.resolveResRobot(ctx, p.client, &sel)
+	if err != nil {
+		return nil, err
+	}
+	opts.Via = vias
+
+	if !p.quiet {
+		if unsupported {
+			fmt.Fprintln(os.Stderr, "Varning: ResRobot kan inte söka tillgänglighetsanpassade resor; kontrollera resan själv.")
+		}
+		route := originStop.DisplayName() + " till " + destStop.DisplayName()
+		if len(viaList) > 0 {
+			names := make([]string, len(viaList))
+			for i := range viaList {
+				names[i] = viaList[i].DisplayName()
+			}
+			route += " via " + strings.Join(names, ", ")
+		}
+		fmt.Fprintf(os.Stderr, "Söker resor från %s (hela Sverige)...\n", route)
+	}
+
+	page, err := p.pages.planResRobot(ctx, p.client, originStop.ID, destStop.ID, opts)
+	if err != nil {
+		return nil, err
+	}
+	return page.PlannerPage(originStop, destStop, viaList), nil
+}
+
+// newTripRouter returns a router between the two planners: SL in
+// Stockholm County and ResRobot elsewhere. A page token or product
+// classes tie the search to the planner they belong to, and nationwide
+// forces ResRobot. Without a ResRobot API key every search goes to SL.
+func newTripRouter(sl *slPlanner, rr *resRobotPlanner, nationwide bool) *planner.Router {
+	switch {
+	case nationwide || resrobot.IsPageToken(sl.pages.token) || sl.prefs.products != "":
+		return &planner.Router{Local: rr}
+	case api.IsPageToken(sl.pages.token) || !rr.client.HasAPIKey():
+		return &planner.Router{Local: sl}
+	}
+	return &planner.Router{Local: sl, Nationwide: rr}
+}
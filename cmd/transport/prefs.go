@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"transport/internal/api"
+	"transport/internal/resrobot"
+)
+
+// resrobotWalkSpeeds converts walking speeds to ResRobot's percent of normal
+var resrobotWalkSpeeds = map[string]int{
+	api.WalkSpeedSlow:   70,
+	api.WalkSpeedNormal: 100,
+	api.WalkSpeedFast:   130,
+}
+
+// tripPreferences holds the mode, walking and accessibility preferences
+// shared by the CLI flags and the MCP plan-trip tool
+type tripPreferences struct {
+	modes        string
+	excludeModes string
+	products     string // ResRobot product classes, e.g. regionaltåg,buss
+	maxWalk      int
+	walkSpeed    string
+	minTransfer  int
+	wheelchair   bool
+	stepFree     bool
+}
+
+// register adds the preference flags to fs
+func (p *tripPreferences) register(fs *flag.FlagSet) {
+	fs.StringVar(&p.modes, "modes", "", "Only use these modes, e.g. train,metro or rail")
+	fs.StringVar(&p.excludeModes, "exclude", "", "Never use these modes, e.g. bus")
+	fs.StringVar(&p.products, "products", "", "Only these product classes, e.g. regionaltåg,buss (ResRobot)")
+	fs.IntVar(&p.maxWalk, "max-walk", 0, "Longest walk in meters")
+	fs.StringVar(&p.walkSpeed, "walk-speed", "", "Walking speed: slow, normal or fast")
+	fs.IntVar(&p.minTransfer, "min-transfer", 0, "Shortest time to change, in minutes")
+	fs.BoolVar(&p.wheelchair, "wheelchair", false, "Wheelchair accessible routes only")
+	fs.BoolVar(&p.stepFree, "step-free", false, "Avoid stairs and escalators")
+}
+
+// applySL validates the preferences and sets them on SL trip options
+func (p *tripPreferences) applySL(opts *api.TripOptions) error {
+	modes, err := parseModeList(p.modes)
+	if err != nil {
+		return err
+	}
+	exclude, err := parseModeList(p.excludeModes)
+	if err != nil {
+		return err
+	}
+	if p.products != "" {
+		return fmt.Errorf("product classes are for nationwide trips; use modes for SL")
+	}
+	if p.walkSpeed != "" && resrobotWalkSpeeds[p.walkSpeed] == 0 {
+		return fmt.Errorf("invalid walking speed '%s' (use slow, normal or fast)", p.walkSpeed)
+	}
+	if p.maxWalk < 0 || p.minTransfer < 0 {
+		return fmt.Errorf("walking distance and transfer time can't be negative")
+	}
+
+	opts.Modes = modes
+	opts.ExcludeModes = exclude
+	opts.MaxWalkMeters = p.maxWalk
+	opts.WalkSpeed = p.walkSpeed
+	opts.MinTransferMinutes = p.minTransfer
+	opts.Wheelchair = p.wheelchair
+	opts.StepFree = p.stepFree
+	return nil
+}
+
+// applyResRobot sets the preferences ResRobot supports on its trip options.
+// Accessibility can't be requested from ResRobot, so unsupported reports
+// whether the caller should warn about it.
+func (p *tripPreferences) applyResRobot(opts *resrobot.TripOptions) (unsupported bool, err error) {
+	slPrefs := *p
+	slPrefs.products = ""
+	var sl api.TripOptions
+	if err := slPrefs.applySL(&sl); err != nil {
+		return false, err
+	}
+	products, err := resrobot.ParseProducts(p.products)
+	if err != nil {
+		return false, err
+	}
+
+	opts.Products, err = combineProducts(resrobot.ProductsForModes(sl.Modes, sl.ExcludeModes), products)
+	if err != nil {
+		return false, err
+	}
+	opts.MaxWalkMeters = sl.MaxWalkMeters
+	opts.WalkSpeed = resrobotWalkSpeeds[sl.WalkSpeed]
+	opts.MinChangeMinutes = sl.MinTransferMinutes
+	return sl.Wheelchair || sl.StepFree, nil
+}
+
+// parseModeList parses a comma-separated list of modes in Swedish or
+// English. "rail" is short for train, metro and tram.
+func parseModeList(value string) ([]string, error) {
+	var modes []string
+	for _, item := range splitList(value) {
+		item = strings.ToLower(item)
+		switch item {
+		case "rail", "spår", "spar", "räls", "rals":
+			modes = append(modes, "train", "metro", "tram")
+			continue
+		}
+
+		mode := normalizeMode(item)
+		if mode == "" {
+			return nil, fmt.Errorf("invalid transport mode '%s'", item)
+		}
+		modes = append(modes, mode)
+	}
+	return modes, nil
+}
+
+// combineProducts narrows the products allowed by mode to the product
+// classes asked for, if any
+func combineProducts(byMode, classes resrobot.Products) (resrobot.Products, error) {
+	switch {
+	case classes == 0:
+		return byMode, nil
+	case byMode == 0:
+		return classes, nil
+	case byMode&classes == 0:
+		return 0, fmt.Errorf("none of the product classes '%s' match the modes", classes)
+	default:
+		return byMode & classes, nil
+	}
+}
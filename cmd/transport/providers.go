@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"transport/internal/api"
+	"transport/internal/flight"
+	"transport/internal/resrobot"
+	"transport/internal/taxi"
+	"transport/internal/tz"
+)
+
+// Environment variables that point the providers at other servers, such as
+// the fakes in internal/transporttest, and pin the clock for reproducible
+// output
+const (
+	envSLJourneyURL   = "TRANSPORT_SL_JOURNEY_URL"
+	envSLTransportURL = "TRANSPORT_SL_TRANSPORT_URL"
+	envResRobotURL    = "TRANSPORT_RESROBOT_URL"
+	envNominatimURL   = "TRANSPORT_NOMINATIM_URL"
+	envOSRMURL        = "TRANSPORT_OSRM_URL"
+	envAirportsURL    = "TRANSPORT_AIRPORTS_URL"
+	envNow            = "TRANSPORT_NOW"
+)
+
+// setClock pins the current time to TRANSPORT_NOW, an RFC 3339 timestamp
+func setClock() error {
+	value := os.Getenv(envNow)
+	if value == "" {
+		return nil
+	}
+	now, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: want RFC 3339, e.g. 2026-03-02T08:00:00+01:00", envNow, value)
+	}
+	tz.SetClock(func() time.Time { return now })
+	return nil
+}
+
+// newSLClient creates an SL client, honouring the URL overrides
+func newSLClient() *api.Client {
+	var opts []api.Option
+	if url := os.Getenv(envSLJourneyURL); url != "" {
+		opts = append(opts, api.WithBaseURL(url))
+	}
+	if url := os.Getenv(envSLTransportURL); url != "" {
+		opts = append(opts, api.WithTransportURL(url))
+	}
+	return api.NewClient(opts...)
+}
+
+// newResRobotClient creates a ResRobot client, honouring the URL override
+func newResRobotClient() *resrobot.Client {
+	var opts []resrobot.Option
+	if url := os.Getenv(envResRobotURL); url != "" {
+		opts = append(opts, resrobot.WithBaseURL(url))
+	}
+	return resrobot.NewClient(opts...)
+}
+
+// newTaxiClient creates a geocoding and routing client, honouring the URL
+// overrides
+func newTaxiClient() *taxi.Client {
+	var opts []taxi.Option
+	if url := os.Getenv(envNominatimURL); url != "" {
+		opts = append(opts, taxi.WithNominatimURL(url))
+	}
+	if url := os.Getenv(envOSRMURL); url != "" {
+		opts = append(opts, taxi.WithOSRMURL(url))
+	}
+	return taxi.NewClient(opts...)
+}
+
+// newFlightClient creates an airports client, honouring the URL override
+func newFlightClient() *flight.Client {
+	var opts []flight.Option
+	if url := os.Getenv(envAirportsURL); url != "" {
+		opts = append(opts, flight.WithAirportsURL(url))
+	}
+	return flight.NewClient(opts...)
+}
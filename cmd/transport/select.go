@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"transport/internal/api"
+	"transport/internal/display"
+	"transport/internal/errs"
+	"transport/internal/geo"
+	"transport/internal/output"
+	"transport/internal/resrobot"
+)
+
+// stdinReader is shared by all prompts so buffered input isn't lost
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// locationSelection holds the --select choices. Choices are 1-based and
+// 0 means "pick automatically".
+type locationSelection struct {
+	origin int
+	dest   int
+	next   int // applies to the first ambiguous endpoint
+}
+
+// parseSelection parses a --select value: "N" picks match N for whichever
+// endpoint is ambiguous, "N,M" picks for origin and destination.
+func parseSelection(value string) (locationSelection, error) {
+	var sel locationSelection
+	if value == "" {
+		return sel, nil
+	}
+
+	parts := strings.Split(value, ",")
+	choices := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 {
+			return sel, fmt.Errorf("invalid --select value '%s' (use N or N,M)", value)
+		}
+		choices[i] = n
+	}
+
+	switch len(choices) {
+	case 1:
+		sel.next = choices[0]
+	case 2:
+		sel.origin, sel.dest = choices[0], choices[1]
+	default:
+		return sel, fmt.Errorf("invalid --select value '%s' (use N or N,M)", value)
+	}
+	return sel, nil
+}
+
+// choiceFor returns the choice for an endpoint or via stop. An ambiguous
+// stop without an explicit choice consumes the "first ambiguous" choice.
+func (s *locationSelection) choiceFor(role string, ambiguous bool) int {
+	var explicit int
+	switch role {
+	case "origin":
+		explicit = s.origin
+	case "destination":
+		explicit = s.dest
+	}
+	if explicit > 0 {
+		return explicit
+	}
+	if ambiguous && s.next > 0 {
+		choice := s.next
+		s.next = 0
+		return choice
+	}
+	return 0
+}
+
+// isInteractive reports whether we can prompt the user for a choice
+func isInteractive() bool {
+	for _, f := range []*os.File{os.Stdin, os.Stdout} {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// promptChoice lists the options on stderr and reads a choice from stdin.
+// Returns the 0-based index of the chosen option.
+func promptChoice(ctx context.Context, title string, options []string) (int, error) {
+	fmt.Fprintln(os.Stderr, title)
+	for i, option := range options {
+		fmt.Fprintf(os.Stderr, "  %d. %s\n", i+1, option)
+	}
+
+	for {
+		fmt.Fprintf(os.Stderr, "Välj 1-%d: ", len(options))
+		line, err := readLine(ctx)
+		if n, convErr := strconv.Atoi(strings.TrimSpace(line)); convErr == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		if err != nil {
+			return 0, fmt.Errorf("no choice made")
+		}
+	}
+}
+
+// readLine reads a line from stdin, giving up when ctx is done
+func readLine(ctx context.Context) (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		line, err := stdinReader.ReadString('\n')
+		done <- result{line, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.line, r.err
+	case <-ctx.Done():
+		fmt.Fprintln(os.Stderr)
+		return "", ctx.Err()
+	}
+}
+
+// roleLabel returns the Swedish label for an endpoint role
+func roleLabel(role string) string {
+	switch role {
+	case "destination":
+		return "till"
+	case "via":
+		return "via"
+	}
+	return "från"
+}
+
+// resolveSLEndpoint resolves one end of an SL trip, honouring --select and
+// prompting on a terminal when the name is ambiguous
+func resolveSLEndpoint(ctx context.Context, client *api.Client, query, role string, sel *locationSelection, formatter *display.Formatter) (*api.Location, error) {
+	candidates, err := client.LocationCandidates(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s '%s': %w", role, query, err)
+	}
+	return chooseSLEndpoint(ctx, query, role, candidates, sel, formatter)
+}
+
+// chooseSLEndpoint picks one end of an SL trip among the locations the
+// query matched, as resolveSLEndpoint does
+func chooseSLEndpoint(ctx context.Context, query, role string, candidates []api.Location, sel *locationSelection, formatter *display.Formatter) (*api.Location, error) {
+	if len(candidates) == 0 {
+		return nil, &errs.NotFoundError{What: "stops", Query: query, Role: role}
+	}
+
+	loc, err := api.ChooseLocation(query, candidates)
+	var ambiguous *api.AmbiguousLocationError
+	if errors.As(err, &ambiguous) {
+		ambiguous.Role = role
+		candidates = ambiguous.Candidates
+	} else if err != nil {
+		return nil, err
+	}
+
+	if choice := sel.choiceFor(role, ambiguous != nil); choice > 0 {
+		if choice > len(candidates) {
+			return nil, fmt.Errorf("--select %d is out of range: '%s' has %d matches", choice, query, len(candidates))
+		}
+		return &candidates[choice-1], nil
+	}
+
+	if ambiguous == nil {
+		return loc, nil
+	}
+	if !isInteractive() {
+		return nil, ambiguous
+	}
+
+	options := make([]string, len(candidates))
+	for i := range candidates {
+		options[i] = describeSLCandidate(&candidates[i], formatter)
+	}
+	choice, err := promptChoice(ctx, fmt.Sprintf("'%s' (%s) matchar flera platser:", query, roleLabel(role)), options)
+	if err != nil {
+		return nil, err
+	}
+	return &candidates[choice], nil
+}
+
+// describeSLCandidate formats a candidate as "T-Centralen — hållplats, Stockholm (Tunnelbana, Buss)"
+func describeSLCandidate(loc *api.Location, formatter *display.Formatter) string {
+	desc := fmt.Sprintf("%s — %s", loc.DisplayName(), formatter.LocationKindName(loc.Kind()))
+	if municipality := loc.Municipality(); municipality != "" {
+		desc += ", " + municipality
+	}
+
+	var products []string
+	for _, class := range loc.ProductClasses {
+		if name := display.ProductClassName(class); name != "" {
+			products = append(products, name)
+		}
+	}
+	if len(products) > 0 {
+		desc += " (" + strings.Join(products, ", ") + ")"
+	}
+	return desc
+}
+
+// resolveResRobotEndpoint resolves one end of a ResRobot trip, honouring
+// --select and prompting on a terminal when the name is ambiguous
+func resolveResRobotEndpoint(ctx context.Context, client *resrobot.Client, query, role string, sel *locationSelection) (*resrobot.StopLocationData, error) {
+	if _, ok := geo.ParseLatLon(query); ok {
+		return client.ResolveTripStop(ctx, query, role)
+	}
+
+	stops, err := client.SearchStops(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s '%s': %w", role, query, err)
+	}
+	if len(stops) == 0 {
+		return nil, &errs.NotFoundError{What: "stops", Query: query, Role: role}
+	}
+
+	stop, err := resrobot.ChooseStop(query, stops)
+	var ambiguous *resrobot.AmbiguousStopError
+	if errors.As(err, &ambiguous) {
+		ambiguous.Role = role
+		stops = ambiguous.Candidates
+	} else if err != nil {
+		return nil, err
+	}
+
+	if choice := sel.choiceFor(role, ambiguous != nil); choice > 0 {
+		if choice > len(stops) {
+			return nil, fmt.Errorf("--select %d is out of range: '%s' has %d matches", choice, query, len(stops))
+		}
+		return &stops[choice-1], nil
+	}
+
+	if ambiguous == nil {
+		return stop, nil
+	}
+	if !isInteractive() {
+		return nil, ambiguous
+	}
+
+	options := make([]string, len(stops))
+	for i := range stops {
+		options[i] = describeResRobotCandidate(&stops[i])
+	}
+	choice, err := promptChoice(ctx, fmt.Sprintf("'%s' (%s) matchar flera hållplatser:", query, roleLabel(role)), options)
+	if err != nil {
+		return nil, err
+	}
+	return &stops[choice], nil
+}
+
+// describeResRobotCandidate formats a candidate as
+// "Sundsvall Centralstation — hållplats, Sundsvall (Fjärrtåg, Regionaltåg, Buss)"
+func describeResRobotCandidate(stop *resrobot.StopLocationData) string {
+	desc := fmt.Sprintf("%s — hållplats", stop.DisplayName())
+	if municipality := stop.Municipality(); municipality != "" {
+		desc += ", " + municipality
+	}
+	if stop.Products != 0 {
+		desc += " (" + stop.Products.String() + ")"
+	}
+	return desc
+}
+
+// reportAmbiguity prints an ambiguity error, as JSON on stdout for scripts
+// and as a readable list on stderr, then exits
+func reportAmbiguity(err error) {
+	var slErr *api.AmbiguousLocationError
+	var rrErr *resrobot.AmbiguousStopError
+
+	var names []string
+	switch {
+	case errors.As(err, &slErr):
+		fmt.Println(output.FormatAmbiguityJSON(slErr))
+		for _, loc := range slErr.Candidates {
+			names = append(names, loc.Name)
+		}
+		fmt.Fprintf(os.Stderr, "Error: '%s' is ambiguous\n\n", slErr.Query)
+	case errors.As(err, &rrErr):
+		fmt.Println(output.FormatResRobotAmbiguityJSON(rrErr))
+		for _, stop := range rrErr.Candidates {
+			names = append(names, stop.DisplayName())
+		}
+		fmt.Fprintf(os.Stderr, "Error: '%s' is ambiguous\n\n", rrErr.Query)
+	default:
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "Did you mean:")
+	for i, name := range names {
+		fmt.Fprintf(os.Stderr, "  %d. %s\n", i+1, name)
+	}
+	fmt.Fprintln(os.Stderr, "\nUse: transport --select N <origin> <destination>")
+	os.Exit(exitAmbiguous)
+}
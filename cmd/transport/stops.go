@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"transport/internal/api"
+)
+
+// stopList collects repeated --stop flags for a merged departure board
+type stopList []api.StopQuery
+
+func (s *stopList) String() string {
+	var specs []string
+	for _, stop := range *s {
+		specs = append(specs, stop.Location)
+	}
+	return strings.Join(specs, ", ")
+}
+
+func (s *stopList) Set(value string) error {
+	stop, err := parseStopSpec(value)
+	if err != nil {
+		return err
+	}
+	*s = append(*s, stop)
+	return nil
+}
+
+// parseStopSpec parses "<location>[@<mode>][+<walk minutes>]", e.g.
+// "Odenplan@buss+3", "Odenplan@tunnelbana" or "Odenplan+5"
+func parseStopSpec(spec string) (api.StopQuery, error) {
+	var stop api.StopQuery
+	rest := strings.TrimSpace(spec)
+
+	if i := strings.LastIndex(rest, "+"); i >= 0 {
+		walk, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(rest[i+1:]), "min"))
+		if err != nil || walk < 0 {
+			return stop, fmt.Errorf("invalid walking time in '%s' (use e.g. Odenplan+3)", spec)
+		}
+		stop.WalkMinutes = walk
+		rest = strings.TrimSpace(rest[:i])
+	}
+
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		mode := strings.ToLower(strings.TrimSpace(rest[i+1:]))
+		if !isAllModes(mode) {
+			stop.Mode = normalizeMode(mode)
+			if stop.Mode == "" {
+				return stop, fmt.Errorf("invalid transport mode '%s' in '%s'", mode, spec)
+			}
+		}
+		rest = strings.TrimSpace(rest[:i])
+	}
+
+	if rest == "" {
+		return stop, fmt.Errorf("missing stop name in '%s'", spec)
+	}
+	stop.Location = rest
+	return stop, nil
+}
+
+// isAllModes reports whether mode asks for departures of every mode
+func isAllModes(mode string) bool {
+	switch mode {
+	case "alla", "allt", "all", "any":
+		return true
+	}
+	return false
+}
+
+// boardMode returns the mode shared by all stops, or "" if they differ
+func boardMode(stops []api.StopQuery) string {
+	mode := stops[0].Mode
+	for _, stop := range stops[1:] {
+		if stop.Mode != mode {
+			return ""
+		}
+	}
+	return mode
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
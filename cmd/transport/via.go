@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"transport/internal/api"
+	"transport/internal/errs"
+	"transport/internal/resrobot"
+)
+
+// viaStops holds the --via and --via-dwell flags, shared by the CLI and
+// the MCP plan-trip tool
+type viaStops struct {
+	names []string
+	dwell int // minutes to spend at each via stop
+}
+
+// viaNames collects repeated --via flags
+type viaNames []string
+
+func (v *viaNames) String() string {
+	return strings.Join(*v, ", ")
+}
+
+func (v *viaNames) Set(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return errs.Invalidf("missing via stop")
+	}
+	*v = append(*v, value)
+	return nil
+}
+
+// register adds the via flags to fs
+func (v *viaStops) register(fs *flag.FlagSet) {
+	fs.Var((*viaNames)(&v.names), "via", "Travel via this stop (repeatable for nationwide trips, up to 5)")
+	fs.IntVar(&v.dwell, "via-dwell", 0, "Minutes to stay at each via stop")
+}
+
+// validate checks the dwell time
+func (v *viaStops) validate() error {
+	if v.dwell < 0 {
+		return errs.Invalidf("via dwell time can't be negative")
+	}
+	if v.dwell > 0 && len(v.names) == 0 {
+		return errs.Invalidf("--via-dwell needs a --via stop")
+	}
+	return nil
+}
+
+// resolveSL resolves the via stop for an SL trip with the planner's
+// matches and the choices left in sel. The SL journey planner only takes
+// one via stop. Returns nil if no via stop was given.
+func (v *viaStops) resolveSL(ctx context.Context, p *slPlanner, sel *locationSelection) (*api.Location, error) {
+	if err := v.validate(); err != nil {
+		return nil, err
+	}
+	switch len(v.names) {
+	case 0:
+		return nil, nil
+	case 1:
+		return p.resolve(ctx, v.names[0], "via", sel)
+	default:
+		return nil, errs.Invalidf("SL trips can only go via one stop (nationwide trips up to %d)", resrobot.MaxVias)
+	}
+}
+
+// resolveResRobot resolves the via stops for a ResRobot trip and returns
+// them both as stops, for display, and as trip options
+func (v *viaStops) resolveResRobot(ctx context.Context, client *resrobot.Client, sel *locationSelection) ([]resrobot.StopLocationData, []resrobot.ViaStop, error) {
+	if err := v.validate(); err != nil {
+		return nil, nil, err
+	}
+	if len(v.names) > resrobot.MaxVias {
+		return nil, nil, errs.Invalidf("too many via stops: %d (at most %d)", len(v.names), resrobot.MaxVias)
+	}
+
+	var stops []resrobot.StopLocationData
+	var vias []resrobot.ViaStop
+	for _, name := range v.names {
+		stop, err := resolveResRobotEndpoint(ctx, client, name, "via", sel)
+		if err != nil {
+			return nil, nil, err
+		}
+		stops = append(stops, *stop)
+		vias = append(vias, resrobot.ViaStop{ID: stop.ID, WaitMinutes: v.dwell})
+	}
+	return stops, vias, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"transport/internal/api"
+	"transport/internal/display"
+	"transport/internal/output"
+	"transport/internal/tz"
+)
+
+// minWatchInterval keeps the live board from hammering the Transport API
+const minWatchInterval = 10 * time.Second
+
+// Terminal control sequences for redrawing the board in place
+const (
+	clearScreen = "\033[H\033[2J"
+	hideCursor  = "\033[?25l"
+	showCursor  = "\033[?25h"
+)
+
+// runDepartureWatch shows a live departure board until ctx is done. The
+// board is refetched every interval and redrawn every second; with
+// jsonOutput each refresh is written as one line of NDJSON instead.
+func runDepartureWatch(ctx context.Context, fetch func() (*api.DepartureBoard, error), mode, towards string, interval time.Duration, lang string, jsonOutput bool) {
+	board, err := fetch()
+	if err != nil {
+		fail(err)
+	}
+
+	formatter := display.NewFormatter(lang)
+	frame := display.WatchFrame{
+		Board:     board,
+		Mode:      mode,
+		Towards:   towards,
+		UpdatedAt: tz.Now(),
+		NextFetch: time.Now().Add(interval),
+	}
+
+	render := func() {
+		if jsonOutput {
+			fmt.Println(output.FormatDeparturesFrameJSON(frame.Board, frame.Changes))
+			return
+		}
+		fmt.Print(clearScreen + formatter.FormatDepartureWatch(frame))
+	}
+
+	if !jsonOutput {
+		fmt.Print(hideCursor)
+		defer fmt.Print(showCursor)
+	}
+	render()
+
+	refresh := time.NewTicker(interval)
+	defer refresh.Stop()
+
+	// Only the terminal board needs redrawing between refreshes
+	var redraw <-chan time.Time
+	if !jsonOutput {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		redraw = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if !jsonOutput {
+				fmt.Println()
+			}
+			return
+
+		case <-redraw:
+			render()
+
+		case <-refresh.C:
+			board, err := fetch()
+			frame.NextFetch = time.Now().Add(interval)
+			if err != nil && ctx.Err() != nil {
+				continue
+			}
+			if err != nil {
+				// Keep showing the last good board; a wall display shouldn't die
+				// because of one failed request
+				frame.Err = err
+				if jsonOutput {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				}
+				continue
+			}
+
+			frame.Changes = api.DiffBoards(frame.Board, board)
+			frame.Board = board
+			frame.UpdatedAt = tz.Now()
+			frame.Err = nil
+			if jsonOutput {
+				render()
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import "transport/internal/geo"
+
+// serviceArea is a rough outline of Stockholm County, where SL runs. It
+// errs on the side of the county, so places just across the border, such
+// as Knivsta, Bålsta and Gnesta, fall outside.
+var serviceArea = []geo.Point{
+	{Lat: 58.95, Lon: 17.62},
+	{Lat: 59.00, Lon: 17.45},
+	{Lat: 59.10, Lon: 17.38},
+	{Lat: 59.25, Lon: 17.35},
+	{Lat: 59.40, Lon: 17.40},
+	{Lat: 59.52, Lon: 17.58},
+	{Lat: 59.60, Lon: 17.62},
+	{Lat: 59.67, Lon: 17.75},
+	{Lat: 59.68, Lon: 18.00},
+	{Lat: 59.78, Lon: 18.15},
+	{Lat: 59.95, Lon: 18.35},
+	{Lat: 60.10, Lon: 18.45},
+	{Lat: 60.20, Lon: 18.70},
+	{Lat: 60.20, Lon: 19.10},
+	{Lat: 59.60, Lon: 19.50},
+	{Lat: 59.20, Lon: 19.40},
+	{Lat: 58.75, Lon: 18.40},
+	{Lat: 58.70, Lon: 17.85},
+}
+
+// InServiceArea reports whether p is in Stockholm County, where the SL
+// journey planner has the full timetable
+func InServiceArea(p geo.Point) bool {
+	return geo.InPolygon(p, serviceArea)
+}
+
+// InServiceArea reports whether the location is in Stockholm County.
+// Locations without coordinates are taken to be: SL found them, and
+// nothing says they are elsewhere.
+func (l *Location) InServiceArea() bool {
+	p, ok := geo.FromLatLon(l.Coord)
+	return !ok || InServiceArea(p)
+}
+
+// InServiceArea reports whether the site is in Stockholm County. SL
+// lists a few stops beyond it, such as Bålsta and Gnesta on the
+// commuter trains; those, and sites without coordinates, are not.
+func (s *Site) InServiceArea() bool {
+	if s.Lat == 0 && s.Lon == 0 {
+		return false
+	}
+	return InServiceArea(geo.Point{Lat: s.Lat, Lon: s.Lon})
+}
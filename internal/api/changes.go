@@ -0,0 +1,96 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DepartureChange is a set of flags describing how a departure changed
+// between two refreshes of a departure board
+type DepartureChange uint8
+
+const (
+	ChangeNew DepartureChange = 1 << iota
+	ChangeDelayed
+	ChangeCancelled
+	ChangePlatform
+)
+
+// Has reports whether all flags in flag are set
+func (c DepartureChange) Has(flag DepartureChange) bool {
+	return c&flag == flag
+}
+
+// Names returns the change flags as lowercase names, e.g. ["delayed", "platform"]
+func (c DepartureChange) Names() []string {
+	var names []string
+	for _, f := range []struct {
+		flag DepartureChange
+		name string
+	}{
+		{ChangeNew, "new"},
+		{ChangeDelayed, "delayed"},
+		{ChangeCancelled, "cancelled"},
+		{ChangePlatform, "platform"},
+	} {
+		if c.Has(f.flag) {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// String implements fmt.Stringer
+func (c DepartureChange) String() string {
+	return strings.Join(c.Names(), ",")
+}
+
+// Key identifies a departure from a stop across refreshes of the same board
+func (d *Departure) Key() string {
+	if d.Journey.ID != 0 {
+		return fmt.Sprintf("%d@%d", d.Journey.ID, d.StopArea.ID)
+	}
+	return fmt.Sprintf("%s|%s|%s@%d", d.Line.Designation, d.Destination, d.Scheduled, d.StopArea.ID)
+}
+
+// DiffBoards compares two snapshots of a departure board and returns the
+// changes per departure key. Departures that didn't change are left out,
+// and nothing is reported when there is no previous snapshot.
+func DiffBoards(prev, curr *DepartureBoard) map[string]DepartureChange {
+	changes := make(map[string]DepartureChange)
+	if prev == nil || curr == nil {
+		return changes
+	}
+
+	before := make(map[string]*Departure, len(prev.Departures))
+	for i := range prev.Departures {
+		before[prev.Departures[i].Key()] = &prev.Departures[i]
+	}
+
+	for i := range curr.Departures {
+		dep := &curr.Departures[i]
+		key := dep.Key()
+
+		old, ok := before[key]
+		if !ok {
+			changes[key] = ChangeNew
+			continue
+		}
+
+		var change DepartureChange
+		if dep.IsCancelled() && !old.IsCancelled() {
+			change |= ChangeCancelled
+		}
+		if dep.DelayMinutes() > old.DelayMinutes() {
+			change |= ChangeDelayed
+		}
+		if dep.StopPoint.Designation != old.StopPoint.Designation {
+			change |= ChangePlatform
+		}
+		if change != 0 {
+			changes[key] = change
+		}
+	}
+
+	return changes
+}
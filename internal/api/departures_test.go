@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"transport/internal/errs"
+	"transport/internal/tz"
+)
+
+// departuresServer serves one site per name and departures for each, or a
+// 503 for the sites in failing
+func departuresServer(t *testing.T, failing ...string) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/sites":
+			w.Write([]byte(`[{"id":9117,"name":"Odenplan","lat":59.343,"lon":18.049},{"id":9192,"name":"Slussen","lat":59.319,"lon":18.072}]`))
+		case strings.HasSuffix(r.URL.Path, "/departures"):
+			for _, id := range failing {
+				if strings.Contains(r.URL.Path, "/"+id+"/") {
+					http.Error(w, "maintenance", http.StatusServiceUnavailable)
+					return
+				}
+			}
+			w.Write([]byte(`{"departures":[{"destination":"Hjulsta","scheduled":"2026-03-02T08:10:00","expected":"2026-03-02T08:10:00","journey":{"id":` +
+				strings.Split(r.URL.Path, "/")[2] + `},"line":{"designation":"10","transport_mode":"METRO"},"stop_area":{"name":"Stop"}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	now := time.Date(2026, 3, 2, 8, 0, 0, 0, tz.Stockholm)
+	return NewClient(WithTransportURL(srv.URL), WithCacheDir(""), WithClock(func() time.Time { return now }),
+		WithHTTPClient(&http.Client{}))
+}
+
+func TestGetMergedDeparturesWarnings(t *testing.T) {
+	stops := []StopQuery{{Location: "Odenplan"}, {Location: "Slussen"}}
+	tests := []struct {
+		name       string
+		failing    []string
+		departures int
+		warnings   []string
+		wantErr    bool
+	}{
+		{"both stops", nil, 2, nil, false},
+		{"one stop fails", []string{"9192"}, 1, []string{"Slussen"}, false},
+		{"every stop fails", []string{"9117", "9192"}, 0, nil, true},
+	}
+	for _, tt := range tests {
+		client := departuresServer(t, tt.failing...)
+		board, err := client.GetMergedDepartures(context.Background(), stops, DepartureFilter{}, 10)
+		if tt.wantErr {
+			if !errors.Is(err, errs.ErrUpstream) {
+				t.Errorf("%s: error = %v, want an upstream error", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error = %v", tt.name, err)
+			continue
+		}
+		var warned []string
+		for _, w := range board.Warnings {
+			warned = append(warned, w.Location)
+		}
+		if len(board.Departures) != tt.departures || strings.Join(warned, ",") != strings.Join(tt.warnings, ",") {
+			t.Errorf("%s: %d departures, warnings for %q; want %d, %q", tt.name, len(board.Departures), warned, tt.departures, tt.warnings)
+		}
+	}
+}
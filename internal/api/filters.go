@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"transport/internal/errs"
+	"transport/internal/match"
+)
+
+// DepartureFilter narrows down a departure board. Zero values match everything.
+type DepartureFilter struct {
+	Mode         string   // transport mode, e.g. "bus"; StopQuery.Mode takes precedence
+	Towards      string   // final destination, or a stop the line passes on the way
+	Lines        []string // line designations, e.g. ["117", "118"]
+	ExcludeLines []string // line designations to leave out
+	Direction    int      // DirectionCode, 1 or 2
+	Platforms    []string // stop point designations ("Läge"), e.g. ["A", "B"]
+	GroupOfLines string   // e.g. "Tunnelbanans gröna linje", substring match
+}
+
+// lineTowards is a line and the final destination of a vehicle on it that
+// passes the filter's Towards stop
+type lineTowards struct {
+	line        string
+	destination string
+}
+
+// Matches reports whether dep passes the filter, matching Towards against
+// its destination and direction only. For boards that weren't fetched
+// with GetMergedDepartures.
+func (f *DepartureFilter) Matches(dep *Departure) bool {
+	return f.matches(dep, f.Mode, nil)
+}
+
+// matches reports whether dep passes the filter, with mode in place of
+// the filter's. via lists the lines that reach Towards further down the
+// line.
+func (f *DepartureFilter) matches(dep *Departure, mode string, via []lineTowards) bool {
+	if mode != "" && dep.Line.TransportMode != strings.ToUpper(mode) {
+		return false
+	}
+	if len(f.Lines) > 0 && !containsFold(f.Lines, dep.Line.Designation) {
+		return false
+	}
+	if containsFold(f.ExcludeLines, dep.Line.Designation) {
+		return false
+	}
+	if f.Direction != 0 && dep.DirectionCode != f.Direction {
+		return false
+	}
+	if len(f.Platforms) > 0 && !containsFold(f.Platforms, dep.StopPoint.Designation) {
+		return false
+	}
+	if f.GroupOfLines != "" && !match.Contains(dep.Line.GroupOfLines, f.GroupOfLines) {
+		return false
+	}
+	if f.Towards != "" && !f.matchesTowards(dep, via) {
+		return false
+	}
+	return true
+}
+
+// matchesTowards checks the departure's destination and direction, then
+// whether it is on a line that passes Towards before reaching its final
+// destination
+func (f *DepartureFilter) matchesTowards(dep *Departure, via []lineTowards) bool {
+	if match.Contains(dep.Destination, f.Towards) || match.Contains(dep.Direction, f.Towards) {
+		return true
+	}
+	for _, v := range via {
+		if !strings.EqualFold(v.line, dep.Line.Designation) {
+			continue
+		}
+		if match.Contains(dep.Destination, v.destination) || match.Contains(v.destination, dep.Destination) {
+			return true
+		}
+	}
+	return false
+}
+
+// linesTowards finds the lines from site that reach towards without a
+// change. It is asked once per board, and the answer is kept for the
+// site and destination, since a live board asks again on every refresh.
+// If the lines can't be found, only the destinations are matched.
+func (c *Client) linesTowards(ctx context.Context, site Site, towards string) []lineTowards {
+	key := fmt.Sprintf("%d|%s", site.ID, match.Normalize(towards))
+	if cached, ok := c.towardsCache.Load(key); ok {
+		return cached.([]lineTowards)
+	}
+
+	lines, err := c.findLinesTowards(ctx, site, towards)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return nil // try again on the next refresh
+	}
+	c.towardsCache.Store(key, lines)
+	return lines
+}
+
+// findLinesTowards plans direct trips from site to towards and collects
+// the line and final destination of each ride
+func (c *Client) findLinesTowards(ctx context.Context, site Site, towards string) ([]lineTowards, error) {
+	origin := &Location{Type: LocationKindCoord, Coord: []float64{site.Lat, site.Lon}}
+	if candidates, err := c.LocationCandidates(ctx, site.Name); err == nil && len(candidates) > 0 {
+		origin = &candidates[0]
+	}
+
+	dests, err := c.LocationCandidates(ctx, towards)
+	if err != nil {
+		return nil, err
+	}
+	if len(dests) == 0 {
+		return nil, &errs.NotFoundError{What: "stops", Query: towards}
+	}
+
+	opts := c.DefaultTripOptions()
+	opts.MaxChanges = 0
+	opts.NumResults = 6
+
+	journeys, err := c.PlanTripBetween(ctx, origin, &dests[0], opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []lineTowards
+	for _, journey := range journeys {
+		var rides []*Transportation
+		for _, leg := range journey.Legs {
+			if !leg.Transportation.IsWalking() {
+				rides = append(rides, leg.Transportation)
+			}
+		}
+		if len(rides) != 1 {
+			continue // not direct, the planner ignored MaxChanges
+		}
+		lines = append(lines, lineTowards{
+			line:        rides[0].GetLineName(),
+			destination: rides[0].GetDirection(),
+		})
+	}
+	return lines, nil
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
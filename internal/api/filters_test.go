@@ -0,0 +1,31 @@
+package api
+
+import "testing"
+
+func TestMatchesTowards(t *testing.T) {
+	// Line 19 from Slussen passes Odenplan on its way to Hässelby strand
+	via := []lineTowards{{line: "19", destination: "Hässelby strand"}}
+	tests := []struct {
+		name        string
+		line        string
+		destination string
+		towards     string
+		via         []lineTowards
+		want        bool
+	}{
+		{"final destination", "14", "Mörby centrum", "Mörby", nil, true},
+		{"diacritics and case", "19", "Hässelby strand", "hasselby", nil, true},
+		{"passes on the way", "19", "Hässelby strand", "Odenplan", via, true},
+		{"other line", "14", "Mörby centrum", "Odenplan", via, false},
+		{"same line, other way", "19", "Hagsätra", "Odenplan", via, false},
+		{"passes, unknown lines", "19", "Hässelby strand", "Odenplan", nil, false},
+	}
+	for _, tt := range tests {
+		dep := Departure{Destination: tt.destination, Direction: tt.destination}
+		dep.Line.Designation = tt.line
+		f := DepartureFilter{Towards: tt.towards}
+		if got := f.matches(&dep, "", tt.via); got != tt.want {
+			t.Errorf("%s: matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBaseURL points the client at another Journey Planner, e.g. a fake
+// from transporttest
+func WithBaseURL(url string) Option {
+	return func(c *Client) {
+		c.baseURL = url
+	}
+}
+
+// WithTransportURL points the client at another Transport API, which
+// serves sites and departures
+func WithTransportURL(url string) Option {
+	return func(c *Client) {
+		c.transportURL = url
+	}
+}
+
+// WithHTTPClient sends requests with hc instead of the shared client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithClock makes the client read the current time from now, which decides
+// which departures are still catchable and when the sites cache expires
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// WithCacheDir keeps the sites catalogue in dir instead of the user cache
+// directory. An empty dir keeps it in memory only.
+func WithCacheDir(dir string) Option {
+	return func(c *Client) {
+		c.cacheDir = dir
+	}
+}
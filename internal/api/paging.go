@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"transport/internal/errs"
+	"transport/internal/pagetoken"
+	"transport/internal/tz"
+)
+
+// maxPageRequests bounds the number of requests PlanTripSpan makes
+const maxPageRequests = 10
+
+// pageTokenPrefix marks page tokens made by this client
+const pageTokenPrefix = "sl."
+
+// Page directions stored in page tokens
+const (
+	pageEarlier = "earlier"
+	pageLater   = "later"
+)
+
+// TripPage is a list of journeys sorted by departure, with tokens for
+// continuing before or after them
+type TripPage struct {
+	Journeys []Journey
+	Earlier  string // token for journeys before these, "" if unknown
+	Later    string // token for journeys after these, "" if unknown
+	Dropped  int    // journeys left out for a change shorter than MinTransferMinutes
+}
+
+// IsPageToken reports whether token was made by this client, as opposed
+// to a ResRobot token
+func IsPageToken(token string) bool {
+	return strings.HasPrefix(token, pageTokenPrefix)
+}
+
+// pageSearch identifies the search a page token belongs to: the stops
+// and the options that decide which journeys are found
+func pageSearch(origin, dest *Location, opts TripOptions) string {
+	var via []float64
+	viaID, dwell := "", 0
+	if opts.Via != nil {
+		viaID, via, dwell = opts.Via.ID, opts.Via.Coord, opts.ViaDwellMinutes
+	}
+	// Coordinates tell apart places that have no stop ID of their own
+	return pagetoken.Search(origin.ID, origin.Coord, dest.ID, dest.Coord, viaID, via, dwell,
+		opts.MaxChanges, opts.Modes, opts.ExcludeModes, opts.MaxWalkMeters, opts.WalkSpeed,
+		opts.MinTransferMinutes, opts.Wheelchair, opts.StepFree)
+}
+
+// newPageToken encodes where the next page of a search starts
+func newPageToken(search, direction string, t time.Time) string {
+	return pagetoken.Encode(pageTokenPrefix, search, direction, t.Format(time.RFC3339))
+}
+
+// parsePageToken decodes a token made by newPageToken for the same search
+func parsePageToken(token, search string) (direction string, t time.Time, err error) {
+	direction, stamp, err := pagetoken.Decode(token, pageTokenPrefix, search)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	invalid := errs.Invalidf("invalid page token '%s'", token)
+	if direction != pageEarlier && direction != pageLater {
+		return "", time.Time{}, invalid
+	}
+	t, err = time.Parse(time.RFC3339, stamp)
+	if err != nil {
+		return "", time.Time{}, invalid
+	}
+	return direction, t.In(tz.Stockholm), nil
+}
+
+// PlanTripPage finds one page of journeys. With an empty token the page
+// starts at opts.Time; otherwise it continues from a token of an earlier
+// TripPage for the same trip. Journeys with a change shorter than
+// opts.MinTransferMinutes are left out, and the following pages are
+// fetched to make up for them.
+func (c *Client) PlanTripPage(ctx context.Context, origin, dest *Location, opts TripOptions, token string) (*TripPage, error) {
+	search := pageSearch(origin, dest, opts)
+	backwards := opts.ArriveBy
+	if token != "" {
+		direction, t, err := parsePageToken(token, search)
+		if err != nil {
+			return nil, err
+		}
+		opts.Time = t
+		opts.ArriveBy = direction == pageEarlier
+		backwards = opts.ArriveBy
+	}
+
+	journeys, err := c.planJourneys(ctx, origin, dest, opts)
+	if err != nil {
+		return nil, err
+	}
+	all := mergeJourneys(nil, journeys)
+	kept, dropped := keepTransfers(all, opts)
+
+	for i := 1; dropped > 0 && len(kept) < opts.NumResults && i < maxPageRequests; i++ {
+		fetched := newTripPage(all, search)
+		next := fetched.Later
+		if backwards {
+			next = fetched.Earlier
+		}
+		if next == "" {
+			break
+		}
+		direction, t, err := parsePageToken(next, search)
+		if err != nil {
+			return nil, err
+		}
+		more := opts
+		more.Time = t
+		more.ArriveBy = direction == pageEarlier
+		journeys, err := c.planJourneys(ctx, origin, dest, more)
+		if err != nil {
+			return nil, err
+		}
+		before := len(all)
+		all = mergeJourneys(all, journeys)
+		if len(all) == before {
+			break // nothing new, the planner has run out of journeys
+		}
+		kept, dropped = keepTransfers(all, opts)
+	}
+
+	// Continue after every journey fetched, dropped or not, so the next
+	// page doesn't fetch them again
+	page := newTripPage(all, search)
+	page.Journeys, page.Dropped = kept, dropped
+	return page, nil
+}
+
+// PlanTripSpan collects journeys departing within span of opts.Time, or
+// arriving within span before it with opts.ArriveBy, by requesting page
+// after page. Journeys returned on several pages are only listed once.
+func (c *Client) PlanTripSpan(ctx context.Context, origin, dest *Location, opts TripOptions, span time.Duration) (*TripPage, error) {
+	page, err := c.PlanTripPage(ctx, origin, dest, opts, "")
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := opts.Time, opts.Time.Add(span)
+	if opts.ArriveBy {
+		start, end = opts.Time.Add(-span), opts.Time
+	}
+
+	journeys, dropped := page.Journeys, page.Dropped
+	for i := 1; i < maxPageRequests && !spanCovered(journeys, start, end, opts.ArriveBy); i++ {
+		token := page.Later
+		if opts.ArriveBy {
+			token = page.Earlier
+		}
+		if token == "" {
+			break
+		}
+
+		page, err = c.PlanTripPage(ctx, origin, dest, opts, token)
+		if err != nil {
+			return nil, err
+		}
+		dropped += page.Dropped
+		before := len(journeys)
+		journeys = mergeJourneys(journeys, page.Journeys)
+		if len(journeys) == before && page.Dropped == 0 {
+			break // nothing new, the planner has run out of journeys
+		}
+	}
+
+	// Keep only journeys inside the span
+	var inSpan []Journey
+	for _, journey := range journeys {
+		t, ok := journey.PlannedDeparture()
+		if opts.ArriveBy {
+			t, ok = journey.PlannedArrival()
+		}
+		if !ok || (!t.Before(start) && !t.After(end)) {
+			inSpan = append(inSpan, journey)
+		}
+	}
+	page = newTripPage(inSpan, pageSearch(origin, dest, opts))
+	page.Dropped = dropped
+	return page, nil
+}
+
+// spanCovered reports whether journeys reach the far end of the span
+func spanCovered(journeys []Journey, start, end time.Time, arriveBy bool) bool {
+	if len(journeys) == 0 {
+		return false
+	}
+	if arriveBy {
+		first, ok := journeys[0].PlannedArrival()
+		return ok && !first.After(start)
+	}
+	last, ok := journeys[len(journeys)-1].PlannedDeparture()
+	return ok && !last.Before(end)
+}
+
+// mergeJourneys adds the journeys in more that aren't already in journeys
+// and sorts the result by planned departure
+func mergeJourneys(journeys, more []Journey) []Journey {
+	seen := make(map[string]bool, len(journeys))
+	for i := range journeys {
+		seen[journeys[i].Key()] = true
+	}
+	for _, journey := range more {
+		if key := journey.Key(); !seen[key] {
+			seen[key] = true
+			journeys = append(journeys, journey)
+		}
+	}
+
+	sort.SliceStable(journeys, func(i, j int) bool {
+		a, _ := journeys[i].PlannedDeparture()
+		b, _ := journeys[j].PlannedDeparture()
+		return a.Before(b)
+	})
+	return journeys
+}
+
+// newTripPage adds page tokens for the search to journeys. The next page
+// starts a minute after the last departure, the previous one a minute
+// before the earliest arrival, so the pages don't overlap.
+func newTripPage(journeys []Journey, search string) *TripPage {
+	page := &TripPage{Journeys: journeys}
+	if len(journeys) == 0 {
+		return page
+	}
+
+	if last, ok := journeys[len(journeys)-1].PlannedDeparture(); ok {
+		page.Later = newPageToken(search, pageLater, last.Add(time.Minute))
+	}
+
+	var earliest time.Time
+	for i := range journeys {
+		if arrival, ok := journeys[i].PlannedArrival(); ok && (earliest.IsZero() || arrival.Before(earliest)) {
+			earliest = arrival
+		}
+	}
+	if !earliest.IsZero() {
+		page.Earlier = newPageToken(search, pageEarlier, earliest.Add(-time.Minute))
+	}
+	return page
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"transport/internal/errs"
+	"transport/internal/tz"
+)
+
+func TestPageTokenBelongsToItsSearch(t *testing.T) {
+	slussen := &Location{ID: "9091001000009192", Coord: []float64{59.3195, 18.0722}}
+	odenplan := &Location{ID: "9091001000009117", Coord: []float64{59.3429, 18.0497}}
+	kista := &Location{ID: "9091001000009302", Coord: []float64{59.4030, 17.9423}}
+	opts := TripOptions{MaxChanges: -1, NumResults: 3}
+	at := time.Date(2026, 3, 2, 8, 12, 0, 0, tz.Stockholm)
+
+	token := newPageToken(pageSearch(slussen, odenplan, opts), pageLater, at)
+
+	moreResults := opts
+	moreResults.NumResults = 6
+	direction, got, err := parsePageToken(token, pageSearch(slussen, odenplan, moreResults))
+	if err != nil || direction != pageLater || !got.Equal(at) {
+		t.Fatalf("parsePageToken() = %q, %v, %v; want %q, %v", direction, got, err, pageLater, at)
+	}
+
+	minTransfer := opts
+	minTransfer.MinTransferMinutes = 5
+	via := opts
+	via.Via = kista
+	others := []struct {
+		name   string
+		origin *Location
+		dest   *Location
+		opts   TripOptions
+	}{
+		{"other destination", slussen, kista, opts},
+		{"reversed", odenplan, slussen, opts},
+		{"other options", slussen, odenplan, minTransfer},
+		{"via", slussen, odenplan, via},
+	}
+	for _, tt := range others {
+		_, _, err := parsePageToken(token, pageSearch(tt.origin, tt.dest, tt.opts))
+		if !errors.Is(err, errs.ErrInvalidInput) {
+			t.Errorf("%s: parsePageToken() error = %v, want invalid input", tt.name, err)
+		}
+	}
+}
+
+func TestNewTripPage(t *testing.T) {
+	journeys := []Journey{
+		testJourney("2026-03-02T07:05:00Z", "2026-03-02T07:14:00Z"),
+		testJourney("2026-03-02T07:10:00Z", "2026-03-02T07:25:00Z"),
+		testJourney("2026-03-02T07:20:00Z", "2026-03-02T07:29:00Z"),
+	}
+	search := pageSearch(&Location{ID: "a"}, &Location{ID: "b"}, TripOptions{})
+	page := newTripPage(journeys, search)
+
+	tests := []struct {
+		token     string
+		direction string
+		want      string
+	}{
+		{page.Later, pageLater, "2026-03-02T08:21:00+01:00"},     // a minute after the last departure
+		{page.Earlier, pageEarlier, "2026-03-02T08:13:00+01:00"}, // a minute before the first arrival
+	}
+	for _, tt := range tests {
+		direction, got, err := parsePageToken(tt.token, search)
+		if err != nil || direction != tt.direction || got.Format(time.RFC3339) != tt.want {
+			t.Errorf("token %q = %q, %v, %v; want %q, %s", tt.token, direction, got.Format(time.RFC3339), err, tt.direction, tt.want)
+		}
+	}
+
+	if empty := newTripPage(nil, search); empty.Earlier != "" || empty.Later != "" {
+		t.Errorf("newTripPage(nil) has tokens %q and %q", empty.Earlier, empty.Later)
+	}
+}
+
+// testJourney returns a one-leg journey with planned UTC times
+func testJourney(departure, arrival string) Journey {
+	var leg Leg
+	leg.Origin.DepartureTimePlanned = departure
+	leg.Destination.ArrivalTimePlanned = arrival
+	return Journey{Legs: []Leg{leg}}
+}
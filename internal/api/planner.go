@@ -0,0 +1,155 @@
+package api
+
+import (
+	"strings"
+	"time"
+
+	"transport/internal/geo"
+	"transport/internal/planner"
+)
+
+// PlannerName is the name of the SL journey planner in planner.Page
+const PlannerName = "SL"
+
+// Mode returns the mode of the transportation: metro, bus, train, tram,
+// ship or walk, by product class or else by name, or "unknown"
+func (t *Transportation) Mode() string {
+	if t.IsWalking() {
+		return "walk"
+	}
+	if t.Product != nil {
+		for mode, class := range modeProductClasses {
+			if class == t.Product.Class {
+				return mode
+			}
+		}
+	}
+
+	name := strings.ToLower(t.Name)
+	if t.Product != nil {
+		name = strings.ToLower(t.Product.Name) + " " + name
+	}
+	switch {
+	case containsAny(name, "tunnelbana", "metro", "t-bana"):
+		return "metro"
+	case containsAny(name, "pendeltåg", "commuter", "train", "tåg"):
+		return "train"
+	case containsAny(name, "spårvagn", "tram", "tvärbanan", "lidingöbanan", "nockebybanan", "saltsjöbanan"):
+		return "tram"
+	case containsAny(name, "buss", "bus", "ersättnings"):
+		return "bus"
+	case containsAny(name, "båt", "ferry", "färja", "waxholm", "sjövägen"):
+		return "ship"
+	default:
+		return "unknown"
+	}
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// Place converts a resolved location to the planner's model
+func (l *Location) Place() planner.Place {
+	place := planner.Place{Name: l.DisplayName(), Kind: l.Kind()}
+	place.Coord, _ = geo.FromLatLon(l.Coord)
+	return place
+}
+
+// PlannerPage converts a page of journeys to the planner's model. via is
+// the requested via stop, or nil.
+func (p *TripPage) PlannerPage(origin, dest, via *Location) *planner.Page {
+	page := &planner.Page{
+		Planner:     PlannerName,
+		Origin:      origin.Place(),
+		Destination: dest.Place(),
+		Dropped:     p.Dropped,
+		Earlier:     p.Earlier,
+		Later:       p.Later,
+	}
+	if via != nil {
+		page.Via = []planner.Place{via.Place()}
+	}
+	for i := range p.Journeys {
+		page.Trips = append(page.Trips, p.Journeys[i].Trip(via))
+	}
+	return page
+}
+
+// Trip converts the journey to the planner's model, marking where it
+// passes via, which may be nil
+func (j *Journey) Trip(via *Location) planner.Trip {
+	trip := planner.Trip{
+		Duration:         time.Duration(j.TripDuration) * time.Second,
+		RealtimeDuration: time.Duration(j.RealtimeDuration()) * time.Second,
+		Changes:          j.Interchanges,
+		Cancelled:        j.IsCancelled(),
+		Metrics:          j.Metrics(),
+	}
+
+	viaLeg := j.ViaLeg(via)
+	for i := range j.Legs {
+		leg := j.Legs[i].plannerLeg()
+		leg.From.Via = i == viaLeg
+		trip.Legs = append(trip.Legs, leg)
+	}
+	return trip
+}
+
+// plannerLeg converts the leg to the planner's model
+func (l *Leg) plannerLeg() planner.Leg {
+	leg := planner.Leg{
+		Mode:      l.Transportation.Mode(),
+		From:      l.Origin.plannerStop(),
+		To:        l.Destination.plannerStop(),
+		Duration:  time.Duration(l.Duration) * time.Second,
+		Distance:  l.Distance,
+		Cancelled: l.IsCancelled(),
+		Geometry:  l.Geometry(),
+	}
+	leg.Departure, _ = ParseTime(l.Origin.DepartureTimePlanned)
+	leg.Arrival, _ = ParseTime(l.Destination.ArrivalTimePlanned)
+	leg.ExpectedDeparture, _ = ParseTime(l.Origin.DepartureTimeEstimated)
+	leg.ExpectedArrival, _ = ParseTime(l.Destination.ArrivalTimeEstimated)
+
+	if !l.Transportation.IsWalking() {
+		leg.Line = l.Transportation.GetLineName()
+		leg.Direction = l.Transportation.GetDirection()
+		if l.Transportation.Operator != nil {
+			leg.Operator = l.Transportation.Operator.Name
+		}
+	}
+
+	for _, stop := range l.IntermediateStops() {
+		st := planner.StopTime{Name: stop.GetStopName()}
+		st.Coord, _ = geo.FromLatLon(stop.Coord)
+		st.Arrival, _ = ParseTime(stop.ArrivalTimePlanned)
+		st.Departure, _ = ParseTime(stop.DepartureTimePlanned)
+		st.ExpectedArrival, _ = ParseTime(stop.ArrivalTimeEstimated)
+		st.ExpectedDeparture, _ = ParseTime(stop.DepartureTimeEstimated)
+		leg.Stops = append(leg.Stops, st)
+	}
+
+	for _, info := range l.SortedInfos() {
+		leg.Notices = append(leg.Notices, planner.Notice{
+			Importance: info.Importance(),
+			Title:      info.Title,
+			Message:    info.Text(),
+			URL:        info.URL,
+		})
+	}
+	return leg
+}
+
+// plannerStop converts the end of a leg to the planner's model
+func (s *StopPoint) plannerStop() planner.Stop {
+	stop := planner.Stop{Name: s.GetStopName(), Platform: s.GetPlatform()}
+	stop.Coord, _ = geo.FromLatLon(s.Coord)
+	return stop
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	"transport/internal/rank"
+)
+
+// footPath is one entry of a leg's footPathInfo: a walk before or after
+// the ride, e.g. between platforms when changing
+type footPath struct {
+	Position string `json:"position"` // "BEFORE", "AFTER" or "IDEST"
+	Duration int    `json:"duration"` // seconds
+	Distance int    `json:"distance,omitempty"`
+	Elements []struct {
+		Distance int `json:"distance,omitempty"`
+	} `json:"footPathElem,omitempty"`
+}
+
+// footPaths returns the walks attached to the leg at position
+func (l *Leg) footPaths(position string) (walk time.Duration, meters int) {
+	var paths []footPath
+	if len(l.FootPathInfo) == 0 || json.Unmarshal(l.FootPathInfo, &paths) != nil {
+		return 0, 0
+	}
+	for _, p := range paths {
+		if p.Position != position {
+			continue
+		}
+		walk += time.Duration(p.Duration) * time.Second
+		meters += p.Distance
+		if p.Distance == 0 {
+			for _, e := range p.Elements {
+				meters += e.Distance
+			}
+		}
+	}
+	return walk, meters
+}
+
+// Transfers returns the changes between rides, using estimated times when
+// available. The walk between the rides counts towards the time needed
+// to change: the walking legs between them, or else the footpaths
+// attached to the rides, which describe the same walk when both are sent.
+func (j *Journey) Transfers() []rank.Transfer {
+	var transfers []rank.Transfer
+	var prev *Leg
+	var walk time.Duration
+	var meters int
+
+	for i := range j.Legs {
+		leg := &j.Legs[i]
+		if leg.Transportation.IsWalking() {
+			walk += time.Duration(leg.Duration) * time.Second
+			meters += leg.Distance
+			continue
+		}
+
+		if prev != nil {
+			if walk == 0 {
+				afterWalk, afterMeters := prev.footPaths("AFTER")
+				beforeWalk, beforeMeters := leg.footPaths("BEFORE")
+				walk, meters = afterWalk+beforeWalk, afterMeters+beforeMeters
+			}
+			t := rank.Transfer{
+				Stop:       leg.Origin.GetStopName(),
+				Walk:       walk,
+				WalkMeters: meters,
+			}
+			t.Arrival, _ = prev.Destination.ArrivalTime()
+			t.Departure, _ = leg.Origin.DepartureTime()
+			transfers = append(transfers, t)
+		}
+		prev, walk, meters = leg, 0, 0
+	}
+	return transfers
+}
+
+// Metrics describes the journey for ranking
+func (j *Journey) Metrics() rank.Metrics {
+	m := rank.Metrics{
+		Duration:  time.Duration(j.RealtimeDuration()) * time.Second,
+		Changes:   j.Interchanges,
+		Transfers: j.Transfers(),
+		Cancelled: j.IsCancelled(),
+	}
+
+	if len(j.Legs) > 0 {
+		first, last := &j.Legs[0], &j.Legs[len(j.Legs)-1]
+		m.Departure, _ = first.Origin.DepartureTime()
+		m.Arrival, _ = last.Destination.ArrivalTime()
+		m.Delay = last.Destination.ArrivalDelay()
+	}
+	for _, leg := range j.Legs {
+		if leg.Transportation.IsWalking() {
+			m.Walk += time.Duration(leg.Duration) * time.Second
+		}
+	}
+	return m
+}
+
+// Score returns the journey's ranking score (see rank.Metrics.Score)
+func (j *Journey) Score() int {
+	return j.Metrics().Score()
+}
+
+// journeyMetrics returns the metrics of each journey
+func journeyMetrics(journeys []Journey) []rank.Metrics {
+	metrics := make([]rank.Metrics, len(journeys))
+	for i := range journeys {
+		metrics[i] = journeys[i].Metrics()
+	}
+	return metrics
+}
+
+// SortJourneys sorts journeys in place by strategy
+func SortJourneys(journeys []Journey, strategy rank.Strategy) {
+	sorted := make([]Journey, len(journeys))
+	for i, idx := range rank.Order(strategy, journeyMetrics(journeys)) {
+		sorted[i] = journeys[idx]
+	}
+	copy(journeys, sorted)
+}
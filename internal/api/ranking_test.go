@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// ride returns a metro leg leaving and arriving at the given UTC times,
+// with footPathInfo if footpaths isn't empty
+func ride(departure, arrival, footpaths string) Leg {
+	leg := Leg{Transportation: &Transportation{Name: "T17", Product: &Product{Class: ProductClassMetro}}}
+	leg.Origin.Name = "Odenplan"
+	leg.Origin.DepartureTimePlanned = departure
+	leg.Destination.ArrivalTimePlanned = arrival
+	if footpaths != "" {
+		leg.FootPathInfo = json.RawMessage(footpaths)
+	}
+	return leg
+}
+
+// walk returns a walking leg of the given length
+func walk(seconds, meters int) Leg {
+	return Leg{
+		Duration:       seconds,
+		Distance:       meters,
+		Transportation: &Transportation{Product: &Product{Class: ProductClassFootpath}},
+	}
+}
+
+func TestJourneyTransfers(t *testing.T) {
+	tests := []struct {
+		name   string
+		legs   []Leg
+		walk   time.Duration
+		meters int
+		slack  time.Duration
+	}{
+		{
+			name: "no walk",
+			legs: []Leg{
+				ride("2026-03-02T07:00:00Z", "2026-03-02T07:10:00Z", ""),
+				ride("2026-03-02T07:15:00Z", "2026-03-02T07:30:00Z", ""),
+			},
+			slack: 5 * time.Minute,
+		},
+		{
+			name: "footpaths on the rides",
+			legs: []Leg{
+				ride("2026-03-02T07:00:00Z", "2026-03-02T07:10:00Z", `[{"position":"AFTER","duration":120,"distance":150}]`),
+				ride("2026-03-02T07:15:00Z", "2026-03-02T07:30:00Z", `[{"position":"BEFORE","duration":60,"footPathElem":[{"distance":40},{"distance":30}]}]`),
+			},
+			walk:   3 * time.Minute,
+			meters: 220,
+			slack:  2 * time.Minute,
+		},
+		{
+			// The planner describes the same walk twice; it counts once
+			name: "walking leg and footpath",
+			legs: []Leg{
+				ride("2026-03-02T07:00:00Z", "2026-03-02T07:10:00Z", `[{"position":"AFTER","duration":240,"distance":300}]`),
+				walk(240, 300),
+				ride("2026-03-02T07:16:00Z", "2026-03-02T07:30:00Z", ""),
+			},
+			walk:   4 * time.Minute,
+			meters: 300,
+			slack:  2 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		journey := Journey{Legs: tt.legs}
+		transfers := journey.Transfers()
+		if len(transfers) != 1 {
+			t.Errorf("%s: %d transfers, want 1", tt.name, len(transfers))
+			continue
+		}
+		got := transfers[0]
+		if got.Walk != tt.walk || got.WalkMeters != tt.meters || got.Slack() != tt.slack {
+			t.Errorf("%s: walk %v, %d m, slack %v; want %v, %d m, %v",
+				tt.name, got.Walk, got.WalkMeters, got.Slack(), tt.walk, tt.meters, tt.slack)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"transport/internal/errs"
+	"transport/internal/match"
+)
+
+const (
+	// siteCacheTTL is how long the sites catalogue is trusted before it is
+	// revalidated against the Transport API.
+	siteCacheTTL = 24 * time.Hour
+
+	// siteCacheFile is the name of the cache file, with a hash of the
+	// Transport API base URL so that clients of different servers don't
+	// share a catalogue
+	siteCacheFile = "sites-%s.json"
+)
+
+// siteCache keeps the SL sites catalogue in memory and on disk so that
+// repeated lookups don't download the multi-megabyte list every time.
+type siteCache struct {
+	mu    sync.Mutex
+	path  string // empty disables the disk cache
+	ttl   time.Duration
+	index *siteIndex
+
+	etag         string
+	lastModified string
+	fetchedAt    time.Time
+}
+
+// siteIndex is the in-memory view of the catalogue shared by all site lookups
+type siteIndex struct {
+	sites []Site
+	names []string // normalized site names, same order as sites
+}
+
+// cachedSites is the on-disk representation of the catalogue
+type cachedSites struct {
+	FetchedAt    time.Time `json:"fetched_at"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Sites        []Site    `json:"sites"`
+}
+
+// defaultCacheDir is where the sites catalogue is kept: TRANSPORT_CACHE_DIR,
+// or the user cache directory. Empty if neither is available.
+func defaultCacheDir() string {
+	if dir := os.Getenv("TRANSPORT_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	if userDir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(userDir, "transport")
+	}
+	return ""
+}
+
+// newSiteCache creates a cache of the catalogue served by transportURL,
+// stored in dir. An empty dir keeps the cache in memory only.
+func newSiteCache(dir, transportURL string) *siteCache {
+	cache := &siteCache{ttl: siteCacheTTL}
+	if dir != "" {
+		sum := sha256.Sum256([]byte(transportURL))
+		name := fmt.Sprintf(siteCacheFile, hex.EncodeToString(sum[:8]))
+		cache.path = filepath.Join(dir, name)
+	}
+	return cache
+}
+
+func newSiteIndex(sites []Site) *siteIndex {
+	idx := &siteIndex{
+		sites: sites,
+		names: make([]string, len(sites)),
+	}
+	for i, site := range sites {
+		idx.names[i] = match.Normalize(site.Name)
+	}
+	return idx
+}
+
+// sites returns the sites catalogue, fetching or revalidating it when needed
+func (c *Client) sites(ctx context.Context) (*siteIndex, error) {
+	cache := c.siteCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.index == nil {
+		cache.load()
+	}
+
+	if cache.index != nil && c.now().Sub(cache.fetchedAt) < cache.ttl {
+		return cache.index, nil
+	}
+
+	if err := c.refreshSites(ctx, cache); err != nil {
+		// A stale catalogue is still far better than no catalogue, unless
+		// the caller has given up
+		if cache.index != nil && ctx.Err() == nil {
+			return cache.index, nil
+		}
+		return nil, err
+	}
+
+	return cache.index, nil
+}
+
+// refreshSites downloads the catalogue, using a conditional request when a
+// previous copy is available. Must be called with cache.mu held.
+func (c *Client) refreshSites(ctx context.Context, cache *siteCache) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/sites", c.transportURL), nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch sites: %w", errs.Unavailable("SL", err))
+	}
+	if cache.index != nil {
+		if cache.etag != "" {
+			req.Header.Set("If-None-Match", cache.etag)
+		}
+		if cache.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cache.lastModified)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch sites: %w", errs.Unavailable("SL", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cache.index != nil {
+		cache.fetchedAt = c.now()
+		cache.save()
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errs.FromResponse("SL", resp)
+	}
+
+	var allSites []Site
+	if err := json.NewDecoder(resp.Body).Decode(&allSites); err != nil {
+		return errs.Unavailable("SL", fmt.Errorf("failed to decode sites: %w", err))
+	}
+
+	cache.index = newSiteIndex(allSites)
+	cache.etag = resp.Header.Get("ETag")
+	cache.lastModified = resp.Header.Get("Last-Modified")
+	cache.fetchedAt = c.now()
+	cache.save()
+
+	return nil
+}
+
+// load reads the catalogue from disk, ignoring missing or corrupt files
+func (s *siteCache) load() {
+	if s.path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+
+	var cached cachedSites
+	if err := json.Unmarshal(data, &cached); err != nil || len(cached.Sites) == 0 {
+		return
+	}
+
+	s.index = newSiteIndex(cached.Sites)
+	s.etag = cached.ETag
+	s.lastModified = cached.LastModified
+	s.fetchedAt = cached.FetchedAt
+}
+
+// save writes the catalogue to disk. Failures are ignored since the cache
+// is only an optimisation.
+func (s *siteCache) save() {
+	if s.path == "" || s.index == nil {
+		return
+	}
+
+	data, err := json.Marshal(cachedSites{
+		FetchedAt:    s.fetchedAt,
+		ETag:         s.etag,
+		LastModified: s.lastModified,
+		Sites:        s.index.sites,
+	})
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return
+	}
+
+	// Write to a temp file and rename so concurrent runs never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,26 @@
+package api
+
+import "testing"
+
+func TestSiteCachePath(t *testing.T) {
+	dir := t.TempDir()
+	real := newSiteCache(dir, transportBaseURL).path
+	fake := newSiteCache(dir, "http://127.0.0.1:8080/sl/transport").path
+
+	if real == "" || fake == "" || real == fake {
+		t.Errorf("cache files %q and %q, want one file per base URL", real, fake)
+	}
+	if again := newSiteCache(dir, transportBaseURL).path; again != real {
+		t.Errorf("cache file %q, then %q for the same base URL", real, again)
+	}
+	if memory := newSiteCache("", transportBaseURL).path; memory != "" {
+		t.Errorf("cache file %q without a cache directory", memory)
+	}
+
+	// The option order doesn't matter
+	a := NewClient(WithCacheDir(dir), WithTransportURL("http://example.com"))
+	b := NewClient(WithTransportURL("http://example.com"), WithCacheDir(dir))
+	if a.siteCache.path != b.siteCache.path || a.siteCache.path == real {
+		t.Errorf("cache files %q and %q for the same base URL", a.siteCache.path, b.siteCache.path)
+	}
+}
@@ -1,14 +1,21 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"math"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"transport/internal/errs"
+	"transport/internal/httpx"
+	"transport/internal/match"
 	"transport/internal/tz"
 )
 
@@ -20,50 +27,91 @@ const (
 
 // Client handles communication with the SL Journey Planner API
 type Client struct {
-	httpClient *http.Client
-	baseURL    string
+	httpClient   *http.Client
+	baseURL      string
+	transportURL string
+	cacheDir     string // where the sites catalogue is kept, "" for memory only
+	siteCache    *siteCache
+	now          func() time.Time
+	towardsCache sync.Map // site ID and destination → []lineTowards
 }
 
 // NewClient creates a new SL API client
-func NewClient() *Client {
-	return &Client{
-		httpClient: &http.Client{
-			Timeout: defaultTimeout,
-		},
-		baseURL: baseURL,
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient:   httpx.NewClient(defaultTimeout),
+		baseURL:      baseURL,
+		transportURL: transportBaseURL,
+		cacheDir:     defaultCacheDir(),
+		now:          tz.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.siteCache = newSiteCache(c.cacheDir, c.transportURL)
+	return c
+}
+
+// get sends a GET request that is abandoned when ctx is done
+func (c *Client) get(ctx context.Context, reqURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
 	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errs.Unavailable("SL", err)
+	}
+	return resp, nil
 }
 
+// Stop-finder object filters (any_obj_filter_sf bitmask)
+const (
+	objFilterStops     = 2
+	objFilterStreets   = 4
+	objFilterAddresses = 8
+	objFilterPOIs      = 32
+)
+
 // SearchStops finds stops matching the given query
-func (c *Client) SearchStops(query string) ([]Location, error) {
+func (c *Client) SearchStops(ctx context.Context, query string) ([]Location, error) {
+	return c.searchLocations(ctx, query, objFilterStops)
+}
+
+// SearchLocations finds stops, streets, addresses and POIs matching the query
+func (c *Client) SearchLocations(ctx context.Context, query string) ([]Location, error) {
+	return c.searchLocations(ctx, query, objFilterStops|objFilterStreets|objFilterAddresses|objFilterPOIs)
+}
+
+// searchLocations queries the stop-finder with the given object filter
+func (c *Client) searchLocations(ctx context.Context, query string, filter int) ([]Location, error) {
 	params := url.Values{}
 	params.Set("type_sf", "any")
 	params.Set("name_sf", query)
-	params.Set("any_obj_filter_sf", "2") // Only stops (not addresses/POI)
+	params.Set("any_obj_filter_sf", fmt.Sprintf("%d", filter))
 
 	reqURL := fmt.Sprintf("%s/stop-finder?%s", c.baseURL, params.Encode())
 
-	resp, err := c.httpClient.Get(reqURL)
+	resp, err := c.get(ctx, reqURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search stops: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return nil, errs.FromResponse("SL", resp)
 	}
 
 	var result StopFinderResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, errs.Unavailable("SL", fmt.Errorf("failed to decode response: %w", err))
 	}
 
 	// Check for API errors only if no locations found
 	if len(result.Locations) == 0 {
 		for _, msg := range result.SystemMessages {
 			if msg.Type == "error" && msg.Text != "" {
-				return nil, fmt.Errorf("API error: %s", msg.Text)
+				return nil, &errs.UpstreamError{Service: "SL", Message: msg.Text}
 			}
 		}
 	}
@@ -71,19 +119,241 @@ func (c *Client) SearchStops(query string) ([]Location, error) {
 	return result.Locations, nil
 }
 
+// maxCandidates is the number of alternatives offered for an ambiguous location
+const maxCandidates = 8
+
+// ambiguityRatio decides when a location is ambiguous: if the runner-up
+// scores at least this fraction of the best match quality, there is no
+// clear winner
+const ambiguityRatio = 0.9
+
+// AmbiguousLocationError is returned when a query matches several
+// locations and none of them is a clear winner
+type AmbiguousLocationError struct {
+	Query      string
+	Role       string // "origin" or "destination", if known
+	Candidates []Location
+}
+
+// Is makes errors.Is(err, errs.ErrAmbiguous) true
+func (e *AmbiguousLocationError) Is(target error) bool {
+	return target == errs.ErrAmbiguous
+}
+
+func (e *AmbiguousLocationError) Error() string {
+	names := make([]string, len(e.Candidates))
+	for i, loc := range e.Candidates {
+		names[i] = loc.Name
+	}
+	if e.Role != "" {
+		return fmt.Sprintf("ambiguous %s '%s'. Could be: %s", e.Role, e.Query, formatSuggestions(names))
+	}
+	return fmt.Sprintf("ambiguous location '%s'. Could be: %s", e.Query, formatSuggestions(names))
+}
+
+// ResolveLocation turns user input into a trip endpoint. Coordinate pairs
+// ("59.3293,18.0686") are used directly; anything else is looked up as a
+// stop, address or POI. Returns *AmbiguousLocationError if there is no
+// clear best match.
+func (c *Client) ResolveLocation(ctx context.Context, query string) (*Location, error) {
+	candidates, err := c.LocationCandidates(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return ChooseLocation(query, candidates)
+}
+
+// LocationCandidates returns every location the query could refer to,
+// best match first
+func (c *Client) LocationCandidates(ctx context.Context, query string) ([]Location, error) {
+	if lat, lon, ok := ParseCoordinates(query); ok {
+		return []Location{{
+			Name:  query,
+			Type:  LocationKindCoord,
+			Coord: []float64{lat, lon},
+		}}, nil
+	}
+
+	locations, err := c.SearchLocations(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// The stop-finder flags its favourite; otherwise order by match quality
+	sort.SliceStable(locations, func(i, j int) bool {
+		if locations[i].IsBest != locations[j].IsBest {
+			return locations[i].IsBest
+		}
+		return locations[i].MatchQuality > locations[j].MatchQuality
+	})
+
+	return locations, nil
+}
+
+// ChooseLocation picks the best of the candidates. A unique exact name
+// match always wins; otherwise the top candidate wins only if it is
+// clearly better than the runner-up. Returns nil if there are no
+// candidates and *AmbiguousLocationError if there is no clear winner.
+func ChooseLocation(query string, candidates []Location) (*Location, error) {
+	loc, ambiguous := match.Choose(query, candidates, locationCandidate, ambiguityRatio, maxCandidates)
+	if ambiguous != nil {
+		return nil, &AmbiguousLocationError{Query: query, Candidates: ambiguous}
+	}
+	return loc, nil
+}
+
+// locationCandidate weighs locations by the stop-finder's match quality
+var locationCandidate = match.Candidate[Location]{
+	Names:  func(l *Location) []string { return []string{l.Name, l.DisassembledName} },
+	Weight: func(l *Location) int { return l.MatchQuality },
+}
+
+// ResolveTripEndpoints resolves both ends of a trip
+func (c *Client) ResolveTripEndpoints(ctx context.Context, origin, dest string) (*Location, *Location, error) {
+	originLoc, err := c.ResolveTripLocation(ctx, origin, "origin")
+	if err != nil {
+		return nil, nil, err
+	}
+	destLoc, err := c.ResolveTripLocation(ctx, dest, "destination")
+	if err != nil {
+		return nil, nil, err
+	}
+	return originLoc, destLoc, nil
+}
+
+// ResolveTripLocation resolves one stop of a trip. role ("origin",
+// "destination" or "via") is used in errors and set on ambiguity errors.
+func (c *Client) ResolveTripLocation(ctx context.Context, query, role string) (*Location, error) {
+	loc, err := c.ResolveLocation(ctx, query)
+	if err != nil {
+		var ambiguous *AmbiguousLocationError
+		if errors.As(err, &ambiguous) {
+			ambiguous.Role = role
+			return nil, ambiguous
+		}
+		return nil, fmt.Errorf("failed to find %s '%s': %w", role, query, err)
+	}
+	if loc == nil {
+		return nil, &errs.NotFoundError{What: "stops", Query: query, Role: role}
+	}
+	return loc, nil
+}
+
+// MaxVias is the number of via stops the journey planner accepts
+const MaxVias = 1
+
 // TripOptions contains options for trip planning
 type TripOptions struct {
-	Time          time.Time // Departure or arrival time
-	ArriveBy      bool      // If true, time is arrival time
-	MaxChanges    int       // Maximum number of transfers (-1 for unlimited)
-	NumResults    int       // Number of results to return (1-6)
-	Language      string    // "sv" or "en"
+	Time       time.Time // Departure or arrival time
+	ArriveBy   bool      // If true, time is arrival time
+	MaxChanges int       // Maximum number of transfers (-1 for unlimited)
+	NumResults int       // Number of results to return (1-6)
+	Language   string    // "sv" or "en"
+
+	Modes              []string // Only use these modes (bus, metro, train, tram, ship); empty for all
+	ExcludeModes       []string // Never use these modes
+	MaxWalkMeters      int      // Longest walk to, from or between stops (0 for no limit)
+	WalkSpeed          string   // "slow", "normal" or "fast"
+	MinTransferMinutes int      // Shortest acceptable time to change (0 for no limit)
+	Wheelchair         bool     // Wheelchair accessible vehicles, stops and transfers
+	StepFree           bool     // No stairs or escalators
+
+	Via             *Location // Pass through this stop (the planner allows MaxVias)
+	ViaDwellMinutes int       // Time to spend at the via stop
+
+	IntermediateStops bool // Keep the stops each leg passes in Leg.StopSequence
+}
+
+// Walking speeds understood by TripOptions.WalkSpeed
+const (
+	WalkSpeedSlow   = "slow"
+	WalkSpeedNormal = "normal"
+	WalkSpeedFast   = "fast"
+)
+
+// walkMetersPerMinute converts walking distances to the journey planner's
+// walking time limit
+var walkMetersPerMinute = map[string]int{
+	WalkSpeedSlow:   55,
+	WalkSpeedNormal: 80,
+	WalkSpeedFast:   105,
+}
+
+// modeProductClasses maps mode names to journey planner product classes
+var modeProductClasses = map[string]int{
+	"train": ProductClassTrain,
+	"metro": ProductClassMetro,
+	"tram":  ProductClassTram,
+	"bus":   ProductClassBus,
+	"ship":  ProductClassFerry,
+}
+
+// excludedClasses returns the product classes the trip must not use
+func (o *TripOptions) excludedClasses() []int {
+	excluded := make(map[int]bool)
+	if len(o.Modes) > 0 {
+		for _, class := range modeProductClasses {
+			excluded[class] = true
+		}
+		for _, mode := range o.Modes {
+			delete(excluded, modeProductClasses[mode])
+		}
+	}
+	for _, mode := range o.ExcludeModes {
+		if class, ok := modeProductClasses[mode]; ok {
+			excluded[class] = true
+		}
+	}
+
+	classes := make([]int, 0, len(excluded))
+	for class := range excluded {
+		classes = append(classes, class)
+	}
+	sort.Ints(classes)
+	return classes
 }
 
-// DefaultTripOptions returns default options for trip planning
-func DefaultTripOptions() TripOptions {
+// setPreferenceParams adds mode, walking and accessibility preferences
+// to a journey planner request
+func (o *TripOptions) setPreferenceParams(params url.Values) {
+	if classes := o.excludedClasses(); len(classes) > 0 {
+		params.Set("excludedMeans", "checkbox")
+		for _, class := range classes {
+			params.Set(fmt.Sprintf("exclMOT_%d", class), "1")
+		}
+	}
+
+	speed := o.WalkSpeed
+	if _, ok := walkMetersPerMinute[speed]; !ok {
+		speed = WalkSpeedNormal
+	}
+	if o.WalkSpeed != "" {
+		params.Set("changeSpeed", speed)
+	}
+
+	if o.MaxWalkMeters > 0 {
+		// The planner limits walking by time, not distance
+		minutes := max(1, o.MaxWalkMeters/walkMetersPerMinute[speed])
+		params.Set("trITMOT", "100")
+		params.Set("trITMOTvalue100", fmt.Sprintf("%d", minutes))
+	}
+
+	if o.Wheelchair || o.StepFree {
+		params.Set("imparedOptionsActive", "1")
+		params.Set("noSolidStairs", "1")
+		params.Set("noEscalators", "1")
+	}
+	if o.Wheelchair {
+		params.Set("wheelchair", "1")
+		params.Set("lowPlatformVhcl", "1")
+	}
+}
+
+// DefaultTripOptions returns default options for trip planning, leaving
+// now by the client's clock
+func (c *Client) DefaultTripOptions() TripOptions {
 	return TripOptions{
-		Time:       tz.Now(),
+		Time:       c.now(),
 		ArriveBy:   false,
 		MaxChanges: -1,
 		NumResults: 3,
@@ -91,13 +361,31 @@ func DefaultTripOptions() TripOptions {
 	}
 }
 
-// PlanTrip finds journeys between origin and destination
-func (c *Client) PlanTrip(originID, destID string, opts TripOptions) ([]Journey, error) {
+// PlanTrip finds journeys between origin and destination stop IDs
+func (c *Client) PlanTrip(ctx context.Context, originID, destID string, opts TripOptions) ([]Journey, error) {
+	return c.PlanTripBetween(ctx, &Location{ID: originID}, &Location{ID: destID}, opts)
+}
+
+// PlanTripBetween finds journeys between two resolved locations
+func (c *Client) PlanTripBetween(ctx context.Context, origin, dest *Location, opts TripOptions) ([]Journey, error) {
+	journeys, err := c.planJourneys(ctx, origin, dest, opts)
+	if err != nil {
+		return nil, err
+	}
+	journeys, _ = keepTransfers(journeys, opts)
+	return journeys, nil
+}
+
+// planJourneys requests journeys from the planner, keeping those with
+// tight changes
+func (c *Client) planJourneys(ctx context.Context, origin, dest *Location, opts TripOptions) ([]Journey, error) {
 	params := url.Values{}
-	params.Set("type_origin", "any")
-	params.Set("name_origin", originID)
-	params.Set("type_destination", "any")
-	params.Set("name_destination", destID)
+	originType, originName := origin.tripParams()
+	params.Set("type_origin", originType)
+	params.Set("name_origin", originName)
+	destType, destName := dest.tripParams()
+	params.Set("type_destination", destType)
+	params.Set("name_destination", destName)
 	params.Set("calc_number_of_trips", fmt.Sprintf("%d", opts.NumResults))
 
 	if opts.Language != "" {
@@ -119,29 +407,50 @@ func (c *Client) PlanTrip(originID, destID string, opts TripOptions) ([]Journey,
 		params.Set("maxChanges", fmt.Sprintf("%d", opts.MaxChanges))
 	}
 
+	if opts.Via != nil {
+		viaType, viaName := opts.Via.tripParams()
+		params.Set("type_via", viaType)
+		params.Set("name_via", viaName)
+		if opts.ViaDwellMinutes > 0 {
+			// Dwell time is given as HHMM
+			params.Set("dwellTime", fmt.Sprintf("%02d%02d", opts.ViaDwellMinutes/60, opts.ViaDwellMinutes%60))
+		}
+	}
+
+	opts.setPreferenceParams(params)
+
 	reqURL := fmt.Sprintf("%s/trips?%s", c.baseURL, params.Encode())
 
-	resp, err := c.httpClient.Get(reqURL)
+	resp, err := c.get(ctx, reqURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to plan trip: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return nil, errs.FromResponse("SL", resp)
 	}
 
 	var result TripsResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, errs.Unavailable("SL", fmt.Errorf("failed to decode response: %w", err))
 	}
 
 	// Check for API errors (but not if we have journeys)
 	if len(result.Journeys) == 0 {
 		for _, msg := range result.SystemMessages {
 			if msg.Type == "error" {
-				return nil, fmt.Errorf("API error: %s", msg.Text)
+				return nil, &errs.UpstreamError{Service: "SL", Message: msg.Text}
+			}
+		}
+	}
+
+	// The planner always lists the stops each leg passes; drop them unless
+	// asked for so they don't end up in the output
+	if !opts.IntermediateStops {
+		for i := range result.Journeys {
+			for j := range result.Journeys[i].Legs {
+				result.Journeys[i].Legs[j].StopSequence = nil
 			}
 		}
 	}
@@ -149,59 +458,50 @@ func (c *Client) PlanTrip(originID, destID string, opts TripOptions) ([]Journey,
 	return result.Journeys, nil
 }
 
-// PlanTripByName is a convenience method that resolves stop names first
-func (c *Client) PlanTripByName(origin, dest string, opts TripOptions) ([]Journey, error) {
-	// Resolve origin
-	originStops, err := c.SearchStops(origin)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find origin '%s': %w", origin, err)
-	}
-	if len(originStops) == 0 {
-		return nil, fmt.Errorf("no stops found for origin '%s'", origin)
+// keepTransfers drops the journeys with a change shorter than opts allow
+// and returns the rest with the number dropped. The planner has no
+// minimum transfer time, so this is done here.
+func keepTransfers(journeys []Journey, opts TripOptions) (kept []Journey, dropped int) {
+	if opts.MinTransferMinutes <= 0 {
+		return journeys, 0
+	}
+	minTransfer := time.Duration(opts.MinTransferMinutes) * time.Minute
+	for _, journey := range journeys {
+		if shortest, ok := journey.ShortestTransfer(); !ok || shortest >= minTransfer {
+			kept = append(kept, journey)
+		}
 	}
+	return kept, len(journeys) - len(kept)
+}
 
-	// Resolve destination
-	destStops, err := c.SearchStops(dest)
+// PlanTripByName is a convenience method that resolves stop names,
+// addresses, POIs or coordinates first
+func (c *Client) PlanTripByName(ctx context.Context, origin, dest string, opts TripOptions) ([]Journey, error) {
+	originLoc, destLoc, err := c.ResolveTripEndpoints(ctx, origin, dest)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find destination '%s': %w", dest, err)
-	}
-	if len(destStops) == 0 {
-		return nil, fmt.Errorf("no stops found for destination '%s'", dest)
+		return nil, err
 	}
 
-	// Use the best match for each
-	return c.PlanTrip(originStops[0].ID, destStops[0].ID, opts)
+	return c.PlanTripBetween(ctx, originLoc, destLoc, opts)
 }
 
 // SearchSites searches for sites by name using the Transport API
-func (c *Client) SearchSites(query string) ([]Site, error) {
-	reqURL := fmt.Sprintf("%s/sites", transportBaseURL)
-
-	resp, err := c.httpClient.Get(reqURL)
+func (c *Client) SearchSites(ctx context.Context, query string) ([]Site, error) {
+	index, err := c.sites(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch sites: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-	}
-
-	var allSites []Site
-	if err := json.NewDecoder(resp.Body).Decode(&allSites); err != nil {
-		return nil, fmt.Errorf("failed to decode sites: %w", err)
+		return nil, err
 	}
 
 	// Filter by query (matches name, aliases, or abbreviation)
+	m := match.New(query)
 	var matches []Site
-	for _, site := range allSites {
-		if site.MatchesQuery(query) {
+	for _, site := range index.sites {
+		if _, ok := site.matchScore(m, query); ok {
 			matches = append(matches, site)
 		}
 	}
 
-	// Sort: exact matches first, then by name length (shorter = better)
+	// Sort: exact matches first, then prefix, substring and fuzzy matches
 	sortSiteMatches(matches, query)
 
 	return matches, nil
@@ -209,56 +509,29 @@ func (c *Client) SearchSites(query string) ([]Site, error) {
 
 // sortSiteMatches sorts sites with better matches first
 func sortSiteMatches(sites []Site, query string) {
-	queryLower := toLower(query)
-
-	// Simple bubble sort (sites list is small after filtering)
-	for i := 0; i < len(sites); i++ {
-		for j := i + 1; j < len(sites); j++ {
-			// Prefer exact name match
-			iExact := toLower(sites[i].Name) == queryLower
-			jExact := toLower(sites[j].Name) == queryLower
-
-			if jExact && !iExact {
-				sites[i], sites[j] = sites[j], sites[i]
-				continue
-			}
-
-			// Prefer exact alias match
-			for _, alias := range sites[j].Alias {
-				if toLower(alias) == queryLower {
-					if !iExact {
-						sites[i], sites[j] = sites[j], sites[i]
-					}
-					break
-				}
-			}
-		}
+	m := match.New(query)
+	scores := make(map[int]int, len(sites))
+	for _, site := range sites {
+		score, _ := site.matchScore(m, query)
+		scores[site.ID] = score
 	}
+
+	sort.SliceStable(sites, func(i, j int) bool {
+		return scores[sites[i].ID] < scores[sites[j].ID]
+	})
 }
 
 // NearestSite finds the closest site to the given coordinates.
 // Uses the Haversine formula for distance calculation.
-func (c *Client) NearestSite(lat, lon float64) (*Site, float64, error) {
-	reqURL := fmt.Sprintf("%s/sites", transportBaseURL)
-
-	resp, err := c.httpClient.Get(reqURL)
+func (c *Client) NearestSite(ctx context.Context, lat, lon float64) (*Site, float64, error) {
+	index, err := c.sites(ctx)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to fetch sites: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, 0, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-	}
-
-	var allSites []Site
-	if err := json.NewDecoder(resp.Body).Decode(&allSites); err != nil {
-		return nil, 0, fmt.Errorf("failed to decode sites: %w", err)
+		return nil, 0, err
 	}
+	allSites := index.sites
 
 	if len(allSites) == 0 {
-		return nil, 0, fmt.Errorf("no sites available")
+		return nil, 0, &errs.NotFoundError{What: "sites"}
 	}
 
 	var nearest *Site
@@ -276,7 +549,7 @@ func (c *Client) NearestSite(lat, lon float64) (*Site, float64, error) {
 	}
 
 	if nearest == nil {
-		return nil, 0, fmt.Errorf("no sites with coordinates found")
+		return nil, 0, &errs.NotFoundError{What: "sites with coordinates"}
 	}
 
 	return nearest, minDist, nil
@@ -295,174 +568,227 @@ func haversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
 }
 
 // GetDepartures fetches departures from a site
-func (c *Client) GetDepartures(siteID int) ([]Departure, error) {
-	reqURL := fmt.Sprintf("%s/sites/%d/departures", transportBaseURL, siteID)
+func (c *Client) GetDepartures(ctx context.Context, siteID int) ([]Departure, error) {
+	result, err := c.getDeparturesResponse(ctx, siteID)
+	if err != nil {
+		return nil, err
+	}
+	return result.Departures, nil
+}
+
+// getDeparturesResponse fetches the full departures response, including
+// stop-level deviations
+func (c *Client) getDeparturesResponse(ctx context.Context, siteID int) (*DeparturesResponse, error) {
+	reqURL := fmt.Sprintf("%s/sites/%d/departures", c.transportURL, siteID)
 
-	resp, err := c.httpClient.Get(reqURL)
+	resp, err := c.get(ctx, reqURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch departures: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return nil, errs.FromResponse("SL", resp)
 	}
 
 	var result DeparturesResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode departures: %w", err)
+		return nil, errs.Unavailable("SL", fmt.Errorf("failed to decode departures: %w", err))
 	}
 
-	return result.Departures, nil
+	return &result, nil
 }
 
 // GetNextDepartures gets filtered departures by mode and destination
-func (c *Client) GetNextDepartures(location string, mode string, towards string, count int) ([]Departure, *Site, error) {
-	// Find the site
-	sites, err := c.SearchSites(location)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to search sites: %w", err)
-	}
-	if len(sites) == 0 {
-		// Try a fuzzy search to suggest alternatives
-		suggestions := c.findSimilarSites(location)
-		if len(suggestions) > 0 {
-			return nil, nil, fmt.Errorf("no sites found for '%s'. Did you mean: %s", location, formatSuggestions(suggestions))
-		}
-		return nil, nil, fmt.Errorf("no sites found for '%s'", location)
+func (c *Client) GetNextDepartures(ctx context.Context, location string, mode string, towards string, count int) (*DepartureBoard, error) {
+	return c.stopDepartures(ctx, StopQuery{Location: location, Mode: mode}, DepartureFilter{Towards: towards}, count)
+}
+
+// GetMergedDepartures fetches the departures of several stops concurrently
+// and merges them into one board, sorted by when you have to leave to
+// catch each departure. Stops that fail are listed in the board's
+// warnings; only if every stop fails is the board an error.
+func (c *Client) GetMergedDepartures(ctx context.Context, stops []StopQuery, filter DepartureFilter, count int) (*DepartureBoard, error) {
+	if len(stops) == 0 {
+		return nil, errs.Invalidf("no stops given")
 	}
 
-	site := sites[0]
+	results := make([]*DepartureBoard, len(stops))
+	stopErrs := make([]error, len(stops))
 
-	// Get departures
-	departures, err := c.GetDepartures(site.ID)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get departures: %w", err)
+	var wg sync.WaitGroup
+	for i, stop := range stops {
+		wg.Add(1)
+		go func(i int, stop StopQuery) {
+			defer wg.Done()
+			results[i], stopErrs[i] = c.stopDepartures(ctx, stop, filter, count)
+		}(i, stop)
 	}
+	wg.Wait()
 
-	// Normalize mode for comparison
-	modeUpper := toUpper(mode)
-	towardsLower := toLower(towards)
-
-	// Filter departures
-	var filtered []Departure
-	for _, dep := range departures {
-		// Filter by transport mode
-		if modeUpper != "" && dep.Line.TransportMode != modeUpper {
-			continue
-		}
+	if len(stops) == 1 {
+		return results[0], stopErrs[0]
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-		// Filter by destination (substring match)
-		if towardsLower != "" && !containsIgnoreCase(dep.Destination, towardsLower) {
+	var boards []*DepartureBoard
+	var warnings []StopWarning
+	for i, board := range results {
+		if stopErrs[i] != nil {
+			warnings = append(warnings, StopWarning{Location: stops[i].Location, Err: stopErrs[i]})
 			continue
 		}
-
-		filtered = append(filtered, dep)
-		if len(filtered) >= count {
-			break
-		}
+		boards = append(boards, board)
+	}
+	if len(boards) == 0 {
+		return nil, errors.Join(stopErrs...)
 	}
 
-	return filtered, &site, nil
-}
+	merged := &DepartureBoard{Site: boards[0].Site, Warnings: warnings}
+	seenDepartures := make(map[string]bool)
+	seenDeviations := make(map[string]bool)
+	for _, board := range boards {
+		merged.Sites = append(merged.Sites, board.Site)
 
-// Helper functions for case-insensitive matching
-func toLower(s string) string {
-	b := make([]byte, len(s))
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= 'A' && c <= 'Z' {
-			b[i] = c + 32
-		} else {
-			b[i] = c
+		// The same stop may be listed twice, e.g. once per mode
+		for _, dep := range board.Departures {
+			if !seenDepartures[dep.Key()] {
+				seenDepartures[dep.Key()] = true
+				merged.Departures = append(merged.Departures, dep)
+			}
+		}
+		for _, dev := range board.StopDeviations {
+			if !seenDeviations[dev.Message] {
+				seenDeviations[dev.Message] = true
+				merged.StopDeviations = append(merged.StopDeviations, dev)
+			}
 		}
 	}
-	return string(b)
-}
 
-func toUpper(s string) string {
-	b := make([]byte, len(s))
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= 'a' && c <= 'z' {
-			b[i] = c - 32
-		} else {
-			b[i] = c
-		}
+	sort.SliceStable(merged.Departures, func(i, j int) bool {
+		return merged.Departures[i].LeaveTime().Before(merged.Departures[j].LeaveTime())
+	})
+	if len(merged.Departures) > count {
+		merged.Departures = merged.Departures[:count]
 	}
-	return string(b)
-}
 
-func containsIgnoreCase(s, substr string) bool {
-	sLower := toLower(s)
-	return len(sLower) >= len(substr) && (sLower == substr || indexString(sLower, substr) >= 0)
+	return merged, nil
 }
 
-func indexString(s, substr string) int {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return i
+// FindSite finds the site a stop name refers to. Names that are only
+// close to a site, typos or not, are a not-found error suggesting the
+// sites they are close to ("Kisa" is not Kista).
+func (c *Client) FindSite(ctx context.Context, query string) (*Site, error) {
+	sites, err := c.SearchSites(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search sites: %w", err)
+	}
+	if len(sites) == 0 {
+		// Try a fuzzy search to suggest alternatives
+		suggestions := c.findSimilarSites(ctx, query)
+		return nil, &errs.NotFoundError{What: "sites", Query: query, Suggestions: suggestions}
+	}
+
+	site := sites[0]
+	if score, _ := site.matchScore(match.New(query), query); match.Tier(score) == match.TierFuzzy {
+		var suggestions []string
+		for i := 0; i < len(sites) && i < 3; i++ {
+			suggestions = append(suggestions, sites[i].Name)
 		}
+		return nil, &errs.NotFoundError{What: "sites", Query: query, Suggestions: suggestions}
 	}
-	return -1
+	return &site, nil
 }
 
-// findSimilarSites finds sites that partially match the query
-func (c *Client) findSimilarSites(query string) []string {
-	reqURL := fmt.Sprintf("%s/sites", transportBaseURL)
+// stopDepartures fetches the board for one stop, keeping departures that
+// pass the filter and that can still be reached on foot
+func (c *Client) stopDepartures(ctx context.Context, stop StopQuery, filter DepartureFilter, count int) (*DepartureBoard, error) {
+	site, err := c.FindSite(ctx, stop.Location)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := c.httpClient.Get(reqURL)
+	// Get departures
+	result, err := c.getDeparturesResponse(ctx, site.ID)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to get departures: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil
+	mode := filter.Mode
+	if stop.Mode != "" {
+		mode = stop.Mode
 	}
 
-	var allSites []Site
-	if err := json.NewDecoder(resp.Body).Decode(&allSites); err != nil {
-		return nil
+	// Lines that pass the Towards stop on their way somewhere else
+	var via []lineTowards
+	if filter.Towards != "" {
+		via = c.linesTowards(ctx, *site, filter.Towards)
+	}
+
+	// Departures leaving before we can walk there are no use
+	earliest := c.now().Add(time.Duration(stop.WalkMinutes) * time.Minute)
+
+	// Filter departures
+	var filtered []Departure
+	for _, dep := range result.Departures {
+		if !filter.matches(&dep, mode, via) {
+			continue
+		}
+
+		if stop.WalkMinutes > 0 {
+			if expected, ok := dep.ExpectedTime(); ok && expected.Before(earliest) {
+				continue
+			}
+			dep.WalkMinutes = stop.WalkMinutes
+		}
+
+		filtered = append(filtered, dep)
+		if len(filtered) >= count {
+			break
+		}
 	}
 
-	// Extract first word of query for fuzzy matching
-	queryLower := toLower(query)
-	words := splitWords(queryLower)
-	if len(words) == 0 {
+	return &DepartureBoard{
+		Site:           *site,
+		Departures:     filtered,
+		StopDeviations: result.StopDeviations,
+	}, nil
+}
+
+// findSimilarSites finds sites that partially match the query
+func (c *Client) findSimilarSites(ctx context.Context, query string) []string {
+	index, err := c.sites(ctx)
+	if err != nil {
 		return nil
 	}
-	firstWord := words[0]
 
-	type match struct {
+	type scored struct {
 		name  string
 		score int // lower is better
 	}
-	var matches []match
 
-	for _, site := range allSites {
-		nameLower := toLower(site.Name)
-		// Check if first word matches start of site name
-		if len(nameLower) >= len(firstWord) && nameLower[:len(firstWord)] == firstWord {
-			// Prefer exact first-word matches (e.g., "Spånga" over "Spångavägen")
-			score := len(site.Name)
-			if nameLower == firstWord {
-				score = 0 // Exact match gets best score
+	similar := func(m *match.Matcher) []scored {
+		var matches []scored
+		for i, site := range index.sites {
+			if score, ok := m.ScoreNormalized(index.names[i]); ok {
+				matches = append(matches, scored{site.Name, score})
 			}
-			matches = append(matches, match{site.Name, score})
 		}
+		return matches
 	}
 
-	// Sort by score (shorter/exact matches first)
-	for i := 0; i < len(matches); i++ {
-		for j := i + 1; j < len(matches); j++ {
-			if matches[j].score < matches[i].score {
-				matches[i], matches[j] = matches[j], matches[i]
-			}
-		}
+	// Fall back to the first word of the query (e.g., "Spånga xyz" → "Spånga")
+	matches := similar(match.New(query))
+	if words := strings.Fields(query); len(matches) == 0 && len(words) > 1 {
+		matches = similar(match.New(words[0]))
 	}
 
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score < matches[j].score
+	})
+
 	// Return top 3
 	var suggestions []string
 	for i := 0; i < len(matches) && i < 3; i++ {
@@ -472,26 +798,6 @@ func (c *Client) findSimilarSites(query string) []string {
 	return suggestions
 }
 
-// splitWords splits a string into words
-func splitWords(s string) []string {
-	var words []string
-	var current []byte
-	for i := 0; i < len(s); i++ {
-		if s[i] == ' ' || s[i] == '\t' {
-			if len(current) > 0 {
-				words = append(words, string(current))
-				current = nil
-			}
-		} else {
-			current = append(current, s[i])
-		}
-	}
-	if len(current) > 0 {
-		words = append(words, string(current))
-	}
-	return words
-}
-
 // formatSuggestions formats a list of suggestions for display
 func formatSuggestions(suggestions []string) string {
 	if len(suggestions) == 0 {
@@ -2,7 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
 	"strings"
+	"time"
+
+	"transport/internal/geo"
+	"transport/internal/match"
+	"transport/internal/tz"
 )
 
 // StopFinderResponse represents the response from /stop-finder endpoint
@@ -22,6 +29,66 @@ type Location struct {
 	Parent           *Parent   `json:"parent,omitempty"`
 	ProductClasses   []int     `json:"productClasses,omitempty"`
 	MatchQuality     int       `json:"matchQuality,omitempty"`
+	IsBest           bool      `json:"isBest,omitempty"`
+}
+
+// Location kinds, as reported by Location.Kind
+const (
+	LocationKindStop    = "stop"
+	LocationKindAddress = "address"
+	LocationKindPOI     = "poi"
+	LocationKindCoord   = "coord"
+	LocationKindArea    = "area"
+)
+
+// Kind classifies the location as a stop, address, POI, coordinate or area
+func (l *Location) Kind() string {
+	switch l.Type {
+	case "stop", "platform":
+		return LocationKindStop
+	case "singlehouse", "street", "address", "crossing":
+		return LocationKindAddress
+	case "poi":
+		return LocationKindPOI
+	case "coord":
+		return LocationKindCoord
+	default:
+		return LocationKindArea
+	}
+}
+
+// Municipality returns the name of the locality the location belongs to
+func (l *Location) Municipality() string {
+	for p := l.Parent; p != nil; p = p.Parent {
+		if p.Type == "locality" {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// DisplayName returns the short name of the location for display
+func (l *Location) DisplayName() string {
+	if l.DisassembledName != "" {
+		return l.DisassembledName
+	}
+	return l.Name
+}
+
+// tripParams returns the type_/name_ parameter values used to pass this
+// location to the trips endpoint
+func (l *Location) tripParams() (string, string) {
+	if (l.Type == LocationKindCoord || l.ID == "") && len(l.Coord) >= 2 {
+		// Journey planner expects "lon:lat:WGS84[dd.ddddd]"
+		return "coord", fmt.Sprintf("%.6f:%.6f:WGS84[dd.ddddd]", l.Coord[1], l.Coord[0])
+	}
+	return "any", l.ID
+}
+
+// ParseCoordinates parses a "lat,lon" pair such as "59.3293,18.0686"
+func ParseCoordinates(s string) (lat, lon float64, ok bool) {
+	p, ok := geo.ParseLatLon(s)
+	return p.Lat, p.Lon, ok
 }
 
 // Parent represents a parent location
@@ -64,10 +131,138 @@ type Leg struct {
 	Origin         StopPoint       `json:"origin"`
 	Destination    StopPoint       `json:"destination"`
 	Transportation *Transportation `json:"transportation,omitempty"`
-	Infos          json.RawMessage `json:"infos,omitempty"`
+	Infos          []Info          `json:"infos,omitempty"`
 	StopSequence   []StopPoint     `json:"stopSequence,omitempty"`
 	Coords         json.RawMessage `json:"coords,omitempty"`
 	FootPathInfo   json.RawMessage `json:"footPathInfo,omitempty"`
+	Distance       int             `json:"distance,omitempty"` // meters, for walking legs
+
+	// Real-time state, e.g. ["MONITORED"] or ["TRIP_CANCELLED"]
+	IsRealtimeControlled bool     `json:"isRealtimeControlled,omitempty"`
+	RealtimeStatus       []string `json:"realtimeStatus,omitempty"`
+}
+
+// IsCancelled returns true if the leg's trip has been cancelled
+func (l *Leg) IsCancelled() bool {
+	for _, status := range l.RealtimeStatus {
+		if status == "TRIP_CANCELLED" || status == "CANCELLED" {
+			return true
+		}
+	}
+	return false
+}
+
+// IntermediateStops returns the stops the leg passes between its origin and
+// destination. The stop sequence usually includes both ends, which are
+// left out here.
+func (l *Leg) IntermediateStops() []StopPoint {
+	stops := l.StopSequence
+	if len(stops) > 0 && stops[0].ID == l.Origin.ID {
+		stops = stops[1:]
+	}
+	if len(stops) > 0 && stops[len(stops)-1].ID == l.Destination.ID {
+		stops = stops[:len(stops)-1]
+	}
+	return stops
+}
+
+// Geometry returns the path of the leg: its polyline if the planner sent
+// one, otherwise a straight line through the coordinates of its stops
+func (l *Leg) Geometry() []geo.Point {
+	if points, err := geo.DecodeCoords(l.Coords); err == nil && len(points) > 0 {
+		return points
+	}
+
+	var points []geo.Point
+	stops := append([]StopPoint{l.Origin}, l.IntermediateStops()...)
+	for _, stop := range append(stops, l.Destination) {
+		if p, ok := geo.FromLatLon(stop.Coord); ok {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+// IsCancelled returns true if any leg of the journey has been cancelled
+func (j *Journey) IsCancelled() bool {
+	for i := range j.Legs {
+		if j.Legs[i].IsCancelled() {
+			return true
+		}
+	}
+	return false
+}
+
+// RealtimeDuration returns the real-time trip duration in seconds, falling
+// back to the planned duration when no real-time data is available
+func (j *Journey) RealtimeDuration() int {
+	if j.TripRTDuration > 0 {
+		return j.TripRTDuration
+	}
+	return j.TripDuration
+}
+
+// ShortestTransfer returns the shortest time between arriving on one ride
+// and departing on the next, including any walk in between. ok is false
+// for journeys without transfers.
+func (j *Journey) ShortestTransfer() (shortest time.Duration, ok bool) {
+	var prevArrival time.Time
+	for _, leg := range j.Legs {
+		if leg.Transportation.IsWalking() {
+			continue
+		}
+		departure, depOK := leg.Origin.DepartureTime()
+		if depOK && !prevArrival.IsZero() {
+			if gap := departure.Sub(prevArrival); !ok || gap < shortest {
+				shortest, ok = gap, true
+			}
+		}
+		prevArrival, _ = leg.Destination.ArrivalTime()
+	}
+	return shortest, ok
+}
+
+// PlannedDeparture returns the planned departure time from the first stop
+func (j *Journey) PlannedDeparture() (time.Time, bool) {
+	if len(j.Legs) == 0 {
+		return time.Time{}, false
+	}
+	return ParseTime(j.Legs[0].Origin.DepartureTimePlanned)
+}
+
+// PlannedArrival returns the planned arrival time at the last stop
+func (j *Journey) PlannedArrival() (time.Time, bool) {
+	if len(j.Legs) == 0 {
+		return time.Time{}, false
+	}
+	return ParseTime(j.Legs[len(j.Legs)-1].Destination.ArrivalTimePlanned)
+}
+
+// Key identifies a journey across overlapping result pages
+func (j *Journey) Key() string {
+	parts := make([]string, len(j.Legs))
+	for i, leg := range j.Legs {
+		line := "walk"
+		if !leg.Transportation.IsWalking() {
+			line = leg.Transportation.GetLineName()
+		}
+		parts[i] = fmt.Sprintf("%s@%s", line, leg.Origin.DepartureTimePlanned)
+	}
+	return strings.Join(parts, "|")
+}
+
+// ViaLeg returns the index of the leg that continues the journey after
+// passing loc, or -1 if the journey doesn't stop there between legs
+func (j *Journey) ViaLeg(loc *Location) int {
+	if loc == nil {
+		return -1
+	}
+	for i := 1; i < len(j.Legs); i++ {
+		if j.Legs[i-1].Destination.IsAt(loc) || j.Legs[i].Origin.IsAt(loc) {
+			return i
+		}
+	}
+	return -1
 }
 
 // StopPoint represents a stop with timing information
@@ -109,6 +304,67 @@ func (s *StopPoint) GetPlatform() string {
 	return props.Platform
 }
 
+// DepartureTime returns the estimated departure time, or the planned one
+// if no estimate is available
+func (s *StopPoint) DepartureTime() (time.Time, bool) {
+	if t, ok := ParseTime(s.DepartureTimeEstimated); ok {
+		return t, true
+	}
+	return ParseTime(s.DepartureTimePlanned)
+}
+
+// ArrivalTime returns the estimated arrival time, or the planned one
+// if no estimate is available
+func (s *StopPoint) ArrivalTime() (time.Time, bool) {
+	if t, ok := ParseTime(s.ArrivalTimeEstimated); ok {
+		return t, true
+	}
+	return ParseTime(s.ArrivalTimePlanned)
+}
+
+// DepartureDelay returns how much later than planned the departure is
+// estimated to leave (negative if early, zero without real-time data)
+func (s *StopPoint) DepartureDelay() time.Duration {
+	return timeDelay(s.DepartureTimePlanned, s.DepartureTimeEstimated)
+}
+
+// ArrivalDelay returns how much later than planned the arrival is estimated
+func (s *StopPoint) ArrivalDelay() time.Duration {
+	return timeDelay(s.ArrivalTimePlanned, s.ArrivalTimeEstimated)
+}
+
+// timeDelay returns estimated minus planned, or zero if either is missing
+func timeDelay(planned, estimated string) time.Duration {
+	p, ok := ParseTime(planned)
+	if !ok {
+		return 0
+	}
+	e, ok := ParseTime(estimated)
+	if !ok {
+		return 0
+	}
+	return e.Sub(p)
+}
+
+// ParseTime parses a journey planner timestamp into Stockholm time.
+// Timestamps without a zone are treated as Swedish local time.
+func ParseTime(timeStr string) (time.Time, bool) {
+	if timeStr == "" {
+		return time.Time{}, false
+	}
+
+	// RFC3339 covers both explicit offsets and the "Z" (UTC) suffix
+	if t, err := time.Parse(time.RFC3339, timeStr); err == nil {
+		return t.In(tz.Stockholm), true
+	}
+
+	if t, err := tz.ParseStockholm("2006-01-02T15:04:05", timeStr); err == nil {
+		return t, true
+	}
+
+	return time.Time{}, false
+}
+
 // GetStopName returns the clean stop name
 func (s *StopPoint) GetStopName() string {
 	// For platforms, get parent stop name
@@ -124,16 +380,24 @@ func (s *StopPoint) GetStopName() string {
 	return s.Name
 }
 
+// IsAt reports whether the stop point is at loc, by stop ID or by name
+func (s *StopPoint) IsAt(loc *Location) bool {
+	if loc.ID != "" && (s.ID == loc.ID || (s.Parent != nil && s.Parent.ID == loc.ID)) {
+		return true
+	}
+	return loc.Kind() == LocationKindStop && match.Equal(s.GetStopName(), loc.DisplayName())
+}
+
 // Transportation represents the vehicle/mode of transport
 type Transportation struct {
-	ID          string              `json:"id,omitempty"`
-	Name        string              `json:"name"`
-	Number      string              `json:"number,omitempty"`
-	Description string              `json:"description,omitempty"`
-	Product     *Product            `json:"product,omitempty"`
-	Operator    *Operator           `json:"operator,omitempty"`
-	Destination *TransportDest      `json:"destination,omitempty"`
-	Properties  json.RawMessage     `json:"properties,omitempty"`
+	ID          string          `json:"id,omitempty"`
+	Name        string          `json:"name"`
+	Number      string          `json:"number,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Product     *Product        `json:"product,omitempty"`
+	Operator    *Operator       `json:"operator,omitempty"`
+	Destination *TransportDest  `json:"destination,omitempty"`
+	Properties  json.RawMessage `json:"properties,omitempty"`
 }
 
 // Product represents product/line information
@@ -286,28 +550,28 @@ type Site struct {
 	Lon          float64  `json:"lon"`
 }
 
-// MatchesQuery checks if the site matches a search query
+// MatchesQuery checks if the site matches a search query. Case, diacritics
+// and small typos are ignored, so "sodermalm" matches "Södermalm".
 func (s *Site) MatchesQuery(query string) bool {
-	queryLower := strings.ToLower(query)
+	_, ok := s.matchScore(match.New(query), query)
+	return ok
+}
 
-	// Check name
-	if strings.Contains(strings.ToLower(s.Name), queryLower) {
-		return true
+// matchScore returns the best score of the site's name, aliases and
+// abbreviation against the query; lower is better
+func (s *Site) matchScore(m *match.Matcher, query string) (int, bool) {
+	// Abbreviations are only matched exactly
+	if s.Abbreviation != "" && strings.EqualFold(s.Abbreviation, strings.TrimSpace(query)) {
+		return 0, true
 	}
 
-	// Check aliases
+	best, found := m.Score(s.Name)
 	for _, alias := range s.Alias {
-		if strings.Contains(strings.ToLower(alias), queryLower) {
-			return true
+		if score, ok := m.Score(alias); ok && (!found || score < best) {
+			best, found = score, true
 		}
 	}
-
-	// Check abbreviation
-	if s.Abbreviation != "" && strings.EqualFold(s.Abbreviation, query) {
-		return true
-	}
-
-	return false
+	return best, found
 }
 
 // DeparturesResponse represents the response from the departures endpoint
@@ -330,6 +594,53 @@ type Departure struct {
 	StopPoint     StopPointInfo `json:"stop_point"`
 	Line          LineInfo      `json:"line"`
 	Deviations    []Deviation   `json:"deviations,omitempty"`
+	WalkMinutes   int           `json:"-"` // walking time to the stop, set from StopQuery
+}
+
+// Departure states reported by the Transport API
+const (
+	DepartureStateCancelled = "CANCELLED"
+	PredictionStateNormal   = "NORMAL"
+)
+
+// IsCancelled returns true if the departure has been cancelled
+func (d *Departure) IsCancelled() bool {
+	return d.State == DepartureStateCancelled || d.Journey.State == DepartureStateCancelled
+}
+
+// IsRealtime returns true if the expected time is based on live vehicle
+// data rather than the timetable
+func (d *Departure) IsRealtime() bool {
+	return d.Journey.PredictionState == "" || d.Journey.PredictionState == PredictionStateNormal
+}
+
+// DelayMinutes returns how many minutes after the scheduled time the
+// departure is expected, to the nearest minute (negative if early)
+func (d *Departure) DelayMinutes() int {
+	scheduled, err := tz.ParseStockholm("2006-01-02T15:04:05", d.Scheduled)
+	if err != nil {
+		return 0
+	}
+	expected, err := tz.ParseStockholm("2006-01-02T15:04:05", d.Expected)
+	if err != nil {
+		return 0
+	}
+	return int(expected.Sub(scheduled).Round(time.Minute).Minutes())
+}
+
+// ExpectedTime returns the expected departure time
+func (d *Departure) ExpectedTime() (time.Time, bool) {
+	t, err := tz.ParseStockholm("2006-01-02T15:04:05", d.Expected)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// LeaveTime returns when you have to start walking to catch the departure
+func (d *Departure) LeaveTime() time.Time {
+	t, _ := d.ExpectedTime()
+	return t.Add(-time.Duration(d.WalkMinutes) * time.Minute)
 }
 
 // JourneyInfo contains journey state information
@@ -364,18 +675,137 @@ type LineInfo struct {
 
 // Deviation represents a service deviation
 type Deviation struct {
-	Importance  int    `json:"importance"`
+	Importance  int    `json:"importance_level"`
 	Consequence string `json:"consequence"`
 	Message     string `json:"message"`
 }
 
 // StopDeviation represents a stop-level deviation
 type StopDeviation struct {
-	Importance  int    `json:"importance"`
+	Importance  int    `json:"importance_level"`
 	Consequence string `json:"consequence"`
 	Message     string `json:"message"`
 }
 
+// DepartureBoard is the result of a departure lookup for one site, or for
+// several sites merged by GetMergedDepartures
+type DepartureBoard struct {
+	Site           Site
+	Sites          []Site // all sites of a merged board
+	Departures     []Departure
+	StopDeviations []StopDeviation
+	Arrivals       bool          // times are arrivals, and Destination is where each vehicle comes from
+	Warnings       []StopWarning // stops of a merged board whose departures are missing
+}
+
+// StopWarning tells why the departures of one stop of a merged board are
+// missing
+type StopWarning struct {
+	Location string // the stop as asked for
+	Err      error
+}
+
+func (w StopWarning) String() string {
+	return fmt.Sprintf("no departures from '%s': %v", w.Location, w.Err)
+}
+
+// StopQuery selects departures from one stop of a merged board
+type StopQuery struct {
+	Location    string
+	Mode        string // empty for all modes
+	WalkMinutes int    // departures you can't walk to in time are left out
+}
+
+// IsMerged returns true if the board combines departures from several sites
+func (b *DepartureBoard) IsMerged() bool {
+	return len(b.Sites) > 1
+}
+
+// SiteNames returns the distinct names of the board's sites
+func (b *DepartureBoard) SiteNames() []string {
+	if !b.IsMerged() {
+		return []string{b.Site.Name}
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, site := range b.Sites {
+		if !seen[site.Name] {
+			seen[site.Name] = true
+			names = append(names, site.Name)
+		}
+	}
+	return names
+}
+
+// SortedDeviations returns the departure's deviations, most important first
+func (d *Departure) SortedDeviations() []Deviation {
+	sorted := make([]Deviation, len(d.Deviations))
+	copy(sorted, d.Deviations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Importance > sorted[j].Importance
+	})
+	return sorted
+}
+
+// SortedStopDeviations returns the board's stop deviations, most important first
+func (b *DepartureBoard) SortedStopDeviations() []StopDeviation {
+	sorted := make([]StopDeviation, len(b.StopDeviations))
+	copy(sorted, b.StopDeviations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Importance > sorted[j].Importance
+	})
+	return sorted
+}
+
+// Info represents a service message attached to a journey leg
+type Info struct {
+	ID       string `json:"id,omitempty"`
+	Priority string `json:"priority"` // veryLow, low, normal, high, veryHigh
+	Type     string `json:"infoType,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Content  string `json:"content,omitempty"`
+	URL      string `json:"url,omitempty"`
+	URLText  string `json:"urlText,omitempty"`
+}
+
+// Importance maps the info priority onto a number, higher is more important
+func (i *Info) Importance() int {
+	switch i.Priority {
+	case "veryHigh":
+		return 4
+	case "high":
+		return 3
+	case "normal":
+		return 2
+	case "low":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Text returns the most descriptive text available for the info
+func (i *Info) Text() string {
+	if i.Subtitle != "" {
+		return i.Subtitle
+	}
+	if i.Title != "" {
+		return i.Title
+	}
+	return i.Content
+}
+
+// SortedInfos returns the leg's service messages, most important first
+func (l *Leg) SortedInfos() []Info {
+	sorted := make([]Info, len(l.Infos))
+	copy(sorted, l.Infos)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Importance() > sorted[j].Importance()
+	})
+	return sorted
+}
+
 // IsWalking returns true if this leg is a walking segment
 func (t *Transportation) IsWalking() bool {
 	if t == nil {
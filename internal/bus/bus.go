@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+
+	"transport/internal/match"
 )
 
 // BusRoute represents a long-distance bus route
@@ -410,9 +412,20 @@ func LookupCity(name string) (*City, bool) {
 	if ok {
 		return &city, true
 	}
+
+	// Fall back to forgiving matching ("Goteborg", "MALMÖ", "Jonkoping")
+	if key, found := match.BestKey(name, Cities); found {
+		city := Cities[key]
+		return &city, true
+	}
 	return nil, false
 }
 
+// SuggestCities returns known cities close to an unknown name, best first
+func SuggestCities(name string) []string {
+	return match.Suggest(name, Cities, 3)
+}
+
 // RouteInfo contains pre-defined route information
 type RouteInfo struct {
 	Duration  string
@@ -5,34 +5,73 @@ import (
 	"strings"
 
 	"transport/internal/api"
+	"transport/internal/planner"
 	"transport/internal/tz"
 )
 
 // FormatDepartures formats departures for display
-func (f *Formatter) FormatDepartures(site *api.Site, mode, towards string, departures []api.Departure) string {
+func (f *Formatter) FormatDepartures(board *api.DepartureBoard, mode, towards string) string {
+	return f.formatBoard(board, mode, towards, nil)
+}
+
+// formatBoard formats a departure board, highlighting departures in changes
+func (f *Formatter) formatBoard(board *api.DepartureBoard, mode, towards string, changes map[string]api.DepartureChange) string {
 	var sb strings.Builder
 
 	// Header
 	sb.WriteString(strings.Repeat("━", lineWidth) + "\n")
 
-	modeIcon := getModeIcon(mode)
-	header := fmt.Sprintf(" %s Nästa %s från %s", modeIcon, getModeName(mode), site.Name)
+	modeIcon := planner.ModeIcon(mode)
+	header := fmt.Sprintf(" %s Nästa %s från %s", modeIcon, getModeName(mode), strings.Join(board.SiteNames(), " + "))
 	if towards != "" {
 		header += fmt.Sprintf(" mot %s", towards)
 	}
+	if board.Arrivals {
+		header = fmt.Sprintf(" %s %s till %s", modeIcon, arrivalsTitle(mode), strings.Join(board.SiteNames(), " + "))
+		if towards != "" {
+			header += fmt.Sprintf(" från %s", towards)
+		}
+	}
 
 	sb.WriteString(header + "\n")
 	sb.WriteString(strings.Repeat("━", lineWidth) + "\n\n")
 
-	if len(departures) == 0 {
+	// Stop-level deviations (e.g., closed entrances, track works)
+	if len(board.StopDeviations) > 0 {
+		for _, dev := range board.SortedStopDeviations() {
+			sb.WriteString(formatDeviationText("  ⚠️  ", "      ", dev.Message))
+		}
+		sb.WriteString("\n")
+	}
+
+	// Stops of a merged board that couldn't be fetched
+	if len(board.Warnings) > 0 {
+		for _, w := range board.Warnings {
+			sb.WriteString(formatDeviationText("  ⚠️  ", "      ", fmt.Sprintf("Avgångar från %s saknas: %v", w.Location, w.Err)))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(board.Departures) == 0 && board.Arrivals {
+		sb.WriteString("  Inga ankomster hittades.\n\n")
+		sb.WriteString(strings.Repeat("━", lineWidth) + "\n")
+		return sb.String()
+	}
+	if len(board.Departures) == 0 {
 		sb.WriteString("  Inga avgångar hittades.\n\n")
 		sb.WriteString(strings.Repeat("━", lineWidth) + "\n")
 		return sb.String()
 	}
 
-	// Each departure
-	for _, dep := range departures {
-		sb.WriteString(f.FormatDeparture(dep))
+	// Each departure. A deviation shared by several departures is only
+	// written out in full the first time; later rows just get the marker.
+	seen := make(map[string]bool)
+	for _, dep := range board.Departures {
+		sb.WriteString(f.formatDeparture(dep, seen, departureRow{
+			change:   changes[dep.Key()],
+			showStop: board.IsMerged(),
+			arrival:  board.Arrivals,
+		}))
 	}
 
 	sb.WriteString("\n" + strings.Repeat("━", lineWidth) + "\n")
@@ -42,30 +81,91 @@ func (f *Formatter) FormatDepartures(site *api.Site, mode, towards string, depar
 
 // FormatDeparture formats a single departure
 func (f *Formatter) FormatDeparture(dep api.Departure) string {
+	return f.formatDeparture(dep, nil, departureRow{})
+}
+
+// departureRow holds per-row options for formatDeparture
+type departureRow struct {
+	change   api.DepartureChange // highlighted if it changed since the last refresh
+	showStop bool                // merged boards show which stop each row leaves from
+	arrival  bool                // Destination is where the vehicle comes from
+}
+
+// formatDeparture formats a departure, skipping deviation texts already in seen
+func (f *Formatter) formatDeparture(dep api.Departure, seen map[string]bool, row departureRow) string {
 	var sb strings.Builder
 
-	icon := getDepartureIcon(dep.Line.TransportMode)
+	icon := planner.ModeIcon(dep.Line.TransportMode)
 	line := dep.Line.Designation
 	destination := dep.Destination
+	if row.arrival {
+		destination = "från " + destination
+	}
 
-	// Calculate time until departure
-	inMinutes := f.calculateMinutesUntil(dep.Expected)
+	// Calculate time until departure, or until we have to start walking
+	inMinutes := max(0, f.calculateMinutesUntil(dep.Expected)-dep.WalkMinutes)
 	timeDisplay := formatTimeUntil(inMinutes)
 
 	// Get actual time
 	actualTime := parseTimeOnly(dep.Expected)
 
+	// Real-time state: cancelled, delayed or timetable-only
+	if dep.IsCancelled() {
+		timeDisplay = f.cancelledLabel()
+		actualTime = parseTimeOnly(dep.Scheduled)
+	} else {
+		if delay := dep.DelayMinutes(); delay > 0 {
+			actualTime += fmt.Sprintf(", +%d min", delay)
+		}
+		if !dep.IsRealtime() {
+			actualTime += ", " + f.timetableLabel()
+		}
+	}
+
+	marker := ""
+	if dep.IsCancelled() {
+		marker = " ❌"
+	} else if len(dep.Deviations) > 0 {
+		marker = " ⚠️"
+	}
+
 	// Format: 🚌 117  Brommaplan           om 7 min (11:09)
-	sb.WriteString(fmt.Sprintf("  %s %-4s %-25s %s (%s)\n",
+	text := fmt.Sprintf("  %s %-4s %-25s %s (%s)%s",
 		icon,
 		line,
 		truncate(destination, 25),
 		timeDisplay,
-		actualTime))
+		actualTime,
+		marker)
+	if row.change != 0 {
+		text = f.highlightChange(text, row.change)
+	}
+	sb.WriteString(text + "\n")
 
-	// Show platform/stop point if available
+	// Show stop, platform and walking time if available
+	var details []string
+	if row.showStop && dep.StopArea.Name != "" {
+		details = append(details, dep.StopArea.Name)
+	}
 	if dep.StopPoint.Designation != "" {
-		sb.WriteString(fmt.Sprintf("         Läge %s\n", dep.StopPoint.Designation))
+		details = append(details, "Läge "+dep.StopPoint.Designation)
+	}
+	if dep.WalkMinutes > 0 {
+		details = append(details, fmt.Sprintf("🚶 %d min", dep.WalkMinutes))
+	}
+	if len(details) > 0 {
+		sb.WriteString("         " + strings.Join(details, " · ") + "\n")
+	}
+
+	// Deviations, most important first
+	for _, dev := range dep.SortedDeviations() {
+		if seen != nil {
+			if seen[dev.Message] {
+				continue
+			}
+			seen[dev.Message] = true
+		}
+		sb.WriteString(formatDeviationText("         ⚠️  ", "             ", dev.Message))
 	}
 
 	return sb.String()
@@ -92,6 +192,24 @@ func (f *Formatter) calculateMinutesUntil(timeStr string) int {
 	return minutes
 }
 
+// cancelledLabel returns the label shown instead of the time until a
+// cancelled departure, padded to the width of formatTimeUntil
+func (f *Formatter) cancelledLabel() string {
+	if f.Language == "en" {
+		return "Cancelled"
+	}
+	return "Inställd "
+}
+
+// timetableLabel marks times that come from the timetable rather than
+// live vehicle data
+func (f *Formatter) timetableLabel() string {
+	if f.Language == "en" {
+		return "timetable"
+	}
+	return "enl. tidtabell"
+}
+
 // formatTimeUntil formats the time until departure
 func formatTimeUntil(minutes int) string {
 	if minutes <= 0 {
@@ -118,42 +236,6 @@ func parseTimeOnly(timeStr string) string {
 	return timeStr[11:16]
 }
 
-// getModeIcon returns the icon for a transport mode
-func getModeIcon(mode string) string {
-	switch strings.ToUpper(mode) {
-	case "BUS":
-		return "🚌"
-	case "METRO":
-		return "🚇"
-	case "TRAIN":
-		return "🚂"
-	case "TRAM":
-		return "🚊"
-	case "SHIP":
-		return "⛴️"
-	default:
-		return "🚍"
-	}
-}
-
-// getDepartureIcon returns the icon based on API transport mode
-func getDepartureIcon(transportMode string) string {
-	switch transportMode {
-	case api.TransportModeBus:
-		return "🚌"
-	case api.TransportModeMetro:
-		return "🚇"
-	case api.TransportModeTrain:
-		return "🚂"
-	case api.TransportModeTram:
-		return "🚊"
-	case api.TransportModeShip:
-		return "⛴️"
-	default:
-		return "🚍"
-	}
-}
-
 // getModeName returns the Swedish name for a transport mode
 func getModeName(mode string) string {
 	switch strings.ToUpper(mode) {
@@ -172,6 +254,49 @@ func getModeName(mode string) string {
 	}
 }
 
+// arrivalsTitle returns the Swedish title of an arrival board for a mode
+func arrivalsTitle(mode string) string {
+	if mode == "" {
+		return "Ankomster"
+	}
+	return "Nästa " + getModeName(mode)
+}
+
+// formatDeviationText word-wraps a deviation message to the line width,
+// using prefix for the first line and indent for continuation lines
+func formatDeviationText(prefix, indent, message string) string {
+	var sb strings.Builder
+	lines := wrapText(strings.TrimSpace(message), lineWidth-len([]rune(indent)))
+	for i, line := range lines {
+		if i == 0 {
+			sb.WriteString(prefix + line + "\n")
+		} else {
+			sb.WriteString(indent + line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// wrapText splits text into lines of at most width characters
+func wrapText(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	var lines []string
+	current := words[0]
+	for _, word := range words[1:] {
+		if len([]rune(current))+1+len([]rune(word)) > width {
+			lines = append(lines, current)
+			current = word
+			continue
+		}
+		current += " " + word
+	}
+	return append(lines, current)
+}
+
 // truncate truncates a string to max length
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -2,11 +2,12 @@ package display
 
 import (
 	"fmt"
-	"net/url"
 	"strings"
 	"time"
 
 	"transport/internal/api"
+	"transport/internal/planner"
+	"transport/internal/rank"
 	"transport/internal/tz"
 )
 
@@ -27,148 +28,295 @@ func NewFormatter(lang string) *Formatter {
 	return &Formatter{Language: lang}
 }
 
-// FormatJourneys formats multiple journeys for display
-func (f *Formatter) FormatJourneys(origin, dest string, journeys []api.Journey) string {
+// FormatTrips formats a page of trips for display, whichever planner
+// found them
+func (f *Formatter) FormatTrips(page *planner.Page) string {
 	var sb strings.Builder
 
 	// Header
+	names := []string{page.Origin.Name}
+	for _, via := range page.Via {
+		names = append(names, via.Name)
+	}
+	names = append(names, page.Destination.Name)
+	route := " " + strings.Join(names, " → ")
+
 	sb.WriteString(strings.Repeat("━", lineWidth) + "\n")
-	sb.WriteString(fmt.Sprintf(" %s → %s", origin, dest))
-	sb.WriteString(strings.Repeat(" ", max(0, lineWidth-len(origin)-len(dest)-6-len(tz.Now().Format("Mon 2 Jan")))))
+	sb.WriteString(route)
+	sb.WriteString(strings.Repeat(" ", max(0, lineWidth-len(route)-len(tz.Now().Format("Mon 2 Jan")))))
 	sb.WriteString(tz.Now().Format("Mon 2 Jan") + "\n")
 	sb.WriteString(strings.Repeat("━", lineWidth) + "\n\n")
 
-	// Each journey
-	for i, journey := range journeys {
-		sb.WriteString(f.FormatJourney(i+1, journey))
+	// Each trip, pointing to a safer one when a change is tight
+	alternatives := planner.SaferAlternatives(page.Trips)
+	for i, trip := range page.Trips {
+		sb.WriteString(f.FormatTrip(i+1, trip))
+		if alt := alternatives[i]; alt >= 0 {
+			sb.WriteString(f.formatSaferAlternative(alt+1, page.Trips[alt]))
+		}
 		sb.WriteString("\n")
 	}
+	if page.Dropped > 0 {
+		sb.WriteString(f.formatDropped(page.Dropped))
+	}
 
 	sb.WriteString(strings.Repeat("━", lineWidth) + "\n")
 
 	return sb.String()
 }
 
-// FormatJourney formats a single journey
-func (f *Formatter) FormatJourney(num int, journey api.Journey) string {
+// formatDropped tells how many trips were left out for too short a change
+func (f *Formatter) formatDropped(n int) string {
+	if f.Language == "en" {
+		if n == 1 {
+			return " ℹ️  1 more trip has a shorter change than --min-transfer\n\n"
+		}
+		return fmt.Sprintf(" ℹ️  %d more trips have a shorter change than --min-transfer\n\n", n)
+	}
+	if n == 1 {
+		return " ℹ️  1 resa till har ett kortare byte än --min-transfer\n\n"
+	}
+	return fmt.Sprintf(" ℹ️  %d resor till har kortare byten än --min-transfer\n\n", n)
+}
+
+// FormatTrip formats a single trip
+func (f *Formatter) FormatTrip(num int, trip planner.Trip) string {
 	var sb strings.Builder
 
-	// Journey header
-	duration := formatDuration(journey.TripDuration)
-	changes := f.formatChanges(journey.Interchanges)
+	// Trip header
+	duration := formatDuration(int(trip.Duration.Seconds()))
+	if diff := int((trip.RealtimeDuration - trip.Duration).Minutes()); diff != 0 {
+		duration = fmt.Sprintf("%s (%+d min)", formatDuration(int(trip.RealtimeDuration.Seconds())), diff)
+	}
+	changes := f.formatChanges(trip.Changes)
+	risky := rank.RiskyTransfers(trip.Transfers())
+	if len(risky) > 0 {
+		changes += " ⚠️"
+	}
 	header := fmt.Sprintf(" Resa %d", num)
+	if status := f.formatStatus(trip); status != "" {
+		header += "  " + status
+	}
 	stats := fmt.Sprintf("%s │ %s", duration, changes)
 	padding := max(0, lineWidth-len(header)-len(stats)-2)
 	sb.WriteString(fmt.Sprintf("%s%s%s\n", header, strings.Repeat(" ", padding), stats))
 	sb.WriteString(strings.Repeat("─", lineWidth) + "\n")
 
-	// Each leg
-	for i, leg := range journey.Legs {
-		sb.WriteString(f.FormatLeg(leg, i == 0, i == len(journey.Legs)-1))
+	// Each leg, marking where the trip passes a via stop
+	for i, leg := range trip.Legs {
+		if i > 0 && leg.From.Via {
+			sb.WriteString(f.formatVia(trip.Legs[i-1], leg))
+		}
+		sb.WriteString(f.FormatLeg(leg, i == 0, i == len(trip.Legs)-1))
+	}
+
+	for _, t := range risky {
+		sb.WriteString(f.formatRiskyTransfer(t))
 	}
 
 	// Google Maps link
-	if mapsURL := GenerateJourneyMapsURL(journey); mapsURL != "" {
+	if mapsURL := trip.MapsURL(); mapsURL != "" {
 		sb.WriteString(fmt.Sprintf("  🗺️  %s\n", mapsURL))
 	}
 
 	return sb.String()
 }
 
-// GenerateJourneyMapsURL creates a Google Maps URL for a transit journey
-func GenerateJourneyMapsURL(journey api.Journey) string {
-	if len(journey.Legs) == 0 {
+// formatStatus describes a trip that doesn't run as planned, e.g.
+// "❌ Inställd". Returns "" if it does.
+func (f *Formatter) formatStatus(trip planner.Trip) string {
+	switch {
+	case trip.Cancelled:
+		return "❌ " + strings.TrimSpace(f.cancelledLabel())
+	case trip.Status == "":
 		return ""
+	case trip.Status == planner.StatusPartiallyCancelled && f.Language == "en":
+		return "⚠️  Partially cancelled"
+	case trip.Status == planner.StatusPartiallyCancelled:
+		return "⚠️  Delvis inställd"
+	default:
+		return "⚠️  Status: " + trip.Status
 	}
+}
 
-	// Get origin from first leg
-	origin := journey.Legs[0].Origin
-	// Get destination from last leg
-	dest := journey.Legs[len(journey.Legs)-1].Destination
-
-	// Build URL with coordinates if available, otherwise use names
-	params := url.Values{}
-	params.Set("api", "1")
-
-	if len(origin.Coord) >= 2 {
-		params.Set("origin", fmt.Sprintf("%f,%f", origin.Coord[1], origin.Coord[0]))
-	} else {
-		params.Set("origin", origin.GetStopName())
-	}
-
-	if len(dest.Coord) >= 2 {
-		params.Set("destination", fmt.Sprintf("%f,%f", dest.Coord[1], dest.Coord[0]))
-	} else {
-		params.Set("destination", dest.GetStopName())
-	}
-
-	params.Set("travelmode", "transit")
-
-	// Add waypoints for intermediate stops (transfers)
-	if len(journey.Legs) > 1 {
-		var waypoints []string
-		for i := 0; i < len(journey.Legs)-1; i++ {
-			transfer := journey.Legs[i].Destination
-			if len(transfer.Coord) >= 2 {
-				waypoints = append(waypoints, fmt.Sprintf("%f,%f", transfer.Coord[1], transfer.Coord[0]))
-			}
-		}
-		if len(waypoints) > 0 {
-			params.Set("waypoints", strings.Join(waypoints, "|"))
+// formatVia marks the via stop between two legs, with the time spent there
+func (f *Formatter) formatVia(arriving, leaving planner.Leg) string {
+	line := fmt.Sprintf("  📍 Via %s", leaving.From.Name)
+	if !arriving.Arrival.IsZero() && !leaving.Departure.IsZero() {
+		wait := int(leaving.Departure.Sub(arriving.Arrival).Minutes())
+		if f.Language == "en" {
+			line += fmt.Sprintf(" · %d min stop", wait)
+		} else {
+			line += fmt.Sprintf(" · %d min uppehåll", wait)
 		}
 	}
-
-	return "https://www.google.com/maps/dir/?" + params.Encode()
+	return line + "\n"
 }
 
-// FormatLeg formats a single leg of a journey
-func (f *Formatter) FormatLeg(leg api.Leg, isFirst, isLast bool) string {
+// FormatLeg formats a single leg of a trip
+func (f *Formatter) FormatLeg(leg planner.Leg, isFirst, isLast bool) string {
 	var sb strings.Builder
 
-	// Get times
-	depTime := parseTime(leg.Origin.DepartureTimePlanned)
-	arrTime := parseTime(leg.Destination.ArrivalTimePlanned)
-
 	// Origin
-	originName := leg.Origin.GetStopName()
-	platform := formatPlatform(leg.Origin.GetPlatform())
-
 	sb.WriteString(fmt.Sprintf("  %s  %-30s  %s\n",
-		depTime,
-		originName,
-		platform))
+		formatClock(leg.Departure),
+		leg.From.Name,
+		formatPlatform(leg.From.Platform)))
+
+	// Real-time status for the departure
+	if leg.Cancelled {
+		sb.WriteString(fmt.Sprintf("         ❌ %s\n", strings.TrimSpace(f.cancelledLabel())))
+	} else if status := f.formatRealtime(leg.ExpectedDeparture, leg.DepartureDelay(), false); status != "" {
+		sb.WriteString("         " + status + "\n")
+	}
 
 	// Transport line or walking
-	if leg.Transportation != nil && !leg.Transportation.IsWalking() {
-		icon := TransportIcon(leg.Transportation)
-		lineName := leg.Transportation.GetLineName()
+	if !leg.IsWalk() {
 		direction := ""
-		if d := leg.Transportation.GetDirection(); d != "" {
-			direction = " → " + d
+		if leg.Direction != "" {
+			direction = " → " + leg.Direction
+		}
+		operator := ""
+		if leg.Operator != "" && leg.Operator != leg.Line {
+			operator = " (" + leg.Operator + ")"
 		}
-		sb.WriteString(fmt.Sprintf("    │    %s %s%s\n", icon, lineName, direction))
+		sb.WriteString(fmt.Sprintf("    │    %s %s%s%s\n", planner.ModeIcon(leg.Mode), leg.Line, direction, operator))
+		sb.WriteString(f.formatAttributes(leg))
 	} else {
-		// Walking
-		walkMin := leg.Duration / 60
+		walkMin := int(leg.Duration.Minutes())
 		if walkMin < 1 {
 			walkMin = 1
 		}
-		sb.WriteString(fmt.Sprintf("    │    🚶 Gång %d min\n", walkMin))
+		walk := fmt.Sprintf("    │    🚶 Gång %d min", walkMin)
+		if leg.Distance > 0 {
+			walk += fmt.Sprintf(" (%d m)", leg.Distance)
+		}
+		sb.WriteString(walk + "\n")
+	}
+
+	for _, stop := range leg.Stops {
+		sb.WriteString(formatStop(stop))
+	}
+
+	// Service messages for this leg (track works, cancellations, ...)
+	for _, notice := range leg.Notices {
+		if notice.Message == "" {
+			continue
+		}
+		if notice.Info {
+			sb.WriteString(formatDeviationText("    │    ℹ️  ", "    │        ", notice.Message))
+		} else {
+			sb.WriteString(formatDeviationText("    │    ⚠️  ", "    │        ", notice.Message))
+		}
 	}
 
 	// Destination (only show if last leg or if there's a transfer)
 	if isLast {
-		destName := leg.Destination.GetStopName()
-		destPlatform := formatPlatform(leg.Destination.GetPlatform())
 		sb.WriteString(fmt.Sprintf("  %s  %-30s  %s\n",
-			arrTime,
-			destName,
-			destPlatform))
+			formatClock(leg.Arrival),
+			leg.To.Name,
+			formatPlatform(leg.To.Platform)))
+
+		if !leg.Cancelled {
+			if status := f.formatRealtime(leg.ExpectedArrival, leg.ArrivalDelay(), true); status != "" {
+				sb.WriteString("         " + status + "\n")
+			}
+		}
 	}
 
 	return sb.String()
 }
 
+// formatAttributes lists what travellers need to know about the vehicle
+// or the ticket: required reservations, bikes and wheelchairs. Other
+// attributes, such as a bistro, are left to the JSON output.
+func (f *Formatter) formatAttributes(leg planner.Leg) string {
+	var sb strings.Builder
+	if leg.ReservationRequired {
+		if f.Language == "en" {
+			sb.WriteString("    │    🎫 Seat reservation required\n")
+		} else {
+			sb.WriteString("    │    🎫 Platsbokning krävs\n")
+		}
+	}
+	if leg.HasAttribute(planner.AttributeBikeAllowed) {
+		if f.Language == "en" {
+			sb.WriteString("    │    🚲 Bikes allowed\n")
+		} else {
+			sb.WriteString("    │    🚲 Cykel får tas med\n")
+		}
+	}
+	if leg.HasAttribute(planner.AttributeWheelchair) {
+		if f.Language == "en" {
+			sb.WriteString("    │    ♿ Wheelchair space\n")
+		} else {
+			sb.WriteString("    │    ♿ Plats för rullstol\n")
+		}
+	}
+	return sb.String()
+}
+
+// formatRealtime describes how an expected time differs from the plan,
+// e.g. "⚠️  Försenad, ny avgång 08:18 (+3 min)". Returns "" if on time.
+func (f *Formatter) formatRealtime(expected time.Time, delay time.Duration, arrival bool) string {
+	mins := int(delay.Round(time.Minute).Minutes())
+	if mins == 0 {
+		return ""
+	}
+	newTime := formatClock(expected)
+
+	if f.Language == "en" {
+		what := "departure"
+		if arrival {
+			what = "arrival"
+		}
+		if mins > 0 {
+			return fmt.Sprintf("⚠️  Delayed, new %s %s (+%d min)", what, newTime, mins)
+		}
+		return fmt.Sprintf("ℹ️  Early, new %s %s (%d min)", what, newTime, mins)
+	}
+
+	what := "avgång"
+	if arrival {
+		what = "ankomst"
+	}
+	if mins > 0 {
+		return fmt.Sprintf("⚠️  Försenad, ny %s %s (+%d min)", what, newTime, mins)
+	}
+	return fmt.Sprintf("ℹ️  Tidigare, ny %s %s (%d min)", what, newTime, mins)
+}
+
+// LocationKindName returns a readable name for a location kind
+func (f *Formatter) LocationKindName(kind string) string {
+	if f.Language == "en" {
+		switch kind {
+		case api.LocationKindStop:
+			return "stop"
+		case api.LocationKindAddress:
+			return "address"
+		case api.LocationKindPOI:
+			return "place"
+		case api.LocationKindCoord:
+			return "coordinates"
+		default:
+			return "area"
+		}
+	}
+
+	switch kind {
+	case api.LocationKindStop:
+		return "hållplats"
+	case api.LocationKindAddress:
+		return "adress"
+	case api.LocationKindPOI:
+		return "plats"
+	case api.LocationKindCoord:
+		return "koordinater"
+	default:
+		return "område"
+	}
+}
+
 // formatDuration formats seconds to "X min" or "X h Y min"
 func formatDuration(seconds int) string {
 	mins := seconds / 60
@@ -203,32 +351,13 @@ func (f *Formatter) formatChanges(n int) string {
 	return fmt.Sprintf("%d byten", n)
 }
 
-// parseTime parses API time format and returns HH:MM in Stockholm time
-func parseTime(timeStr string) string {
-	if timeStr == "" {
+// formatClock returns a time as HH:MM in Stockholm time, or blanks of
+// the same width if it is unknown
+func formatClock(t time.Time) string {
+	if t.IsZero() {
 		return "     "
 	}
-
-	// Try RFC3339 first (has explicit timezone info)
-	if t, err := time.Parse(time.RFC3339, timeStr); err == nil {
-		return t.In(tz.Stockholm).Format("15:04")
-	}
-
-	// Try ISO with Z suffix (UTC)
-	if t, err := time.Parse("2006-01-02T15:04:05Z", timeStr); err == nil {
-		return t.In(tz.Stockholm).Format("15:04")
-	}
-
-	// Without timezone — Swedish APIs return local Swedish time
-	if t, err := time.ParseInLocation("2006-01-02T15:04:05", timeStr, tz.Stockholm); err == nil {
-		return t.Format("15:04")
-	}
-
-	// Fallback: extract HH:MM from ISO string
-	if len(timeStr) >= 16 {
-		return timeStr[11:16]
-	}
-	return timeStr
+	return t.In(tz.Stockholm).Format("15:04")
 }
 
 // shortName returns the disassembled name if available, otherwise the full name
@@ -0,0 +1,23 @@
+package display
+
+import (
+	"fmt"
+	"time"
+
+	"transport/internal/planner"
+)
+
+// formatStop formats a stop a leg passes, with its real-time delay,
+// e.g. "    │    · 10:04 Fridhemsplan (+1)"
+func formatStop(stop planner.StopTime) string {
+	planned, delay := stop.Departure, stop.DepartureDelay()
+	if planned.IsZero() {
+		planned, delay = stop.Arrival, stop.ArrivalDelay()
+	}
+
+	line := fmt.Sprintf("    │    · %s %s", formatClock(planned), stop.Name)
+	if mins := int(delay.Round(time.Minute).Minutes()); mins > 0 {
+		line += fmt.Sprintf(" (+%d)", mins)
+	}
+	return line + "\n"
+}
@@ -0,0 +1,48 @@
+package display
+
+import (
+	"fmt"
+
+	"transport/internal/planner"
+	"transport/internal/rank"
+)
+
+// formatRiskyTransfer warns about a change that is likely to be missed,
+// e.g. "⚠️  Kort byte vid Slussen: 1 min marginal (150 m gång)"
+func (f *Formatter) formatRiskyTransfer(t rank.Transfer) string {
+	slack := int(t.Slack().Minutes())
+
+	var line string
+	switch {
+	case f.Language == "en" && slack < 0:
+		line = fmt.Sprintf("  ⚠️  Tight change at %s: likely to be missed", t.Stop)
+	case f.Language == "en":
+		line = fmt.Sprintf("  ⚠️  Tight change at %s: %d min to spare", t.Stop, slack)
+	case slack < 0:
+		line = fmt.Sprintf("  ⚠️  Kort byte vid %s: hinns troligen inte", t.Stop)
+	default:
+		line = fmt.Sprintf("  ⚠️  Kort byte vid %s: %d min marginal", t.Stop, slack)
+	}
+
+	if t.WalkMeters > 0 {
+		if f.Language == "en" {
+			line += fmt.Sprintf(" (%d m walk)", t.WalkMeters)
+		} else {
+			line += fmt.Sprintf(" (%d m gång)", t.WalkMeters)
+		}
+	}
+	return line + "\n"
+}
+
+// formatSaferAlternative points to a trip without risky changes,
+// e.g. "💡 Säkrare: Resa 3, framme 08:42"
+func (f *Formatter) formatSaferAlternative(num int, trip planner.Trip) string {
+	arrival := "?"
+	if t := trip.ExpectedArrival(); !t.IsZero() {
+		arrival = formatClock(t)
+	}
+	if f.Language == "en" {
+		return fmt.Sprintf("  💡 Safer: Trip %d, arrives %s\n", num, arrival)
+	}
+	return fmt.Sprintf("  💡 Säkrare: Resa %d, framme %s\n", num, arrival)
+}
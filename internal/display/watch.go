@@ -0,0 +1,104 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"transport/internal/api"
+)
+
+// ANSI escape sequences used by the live departure board
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+)
+
+// WatchFrame is one rendering of the live departure board
+type WatchFrame struct {
+	Board     *api.DepartureBoard
+	Mode      string
+	Towards   string
+	Changes   map[string]api.DepartureChange // since the previous refresh
+	UpdatedAt time.Time                      // when Board was fetched
+	NextFetch time.Time
+	Err       error // last refresh error, if it failed
+}
+
+// FormatDepartureWatch formats a frame of the live departure board. Times
+// until departure are computed from the current time, so re-rendering the
+// same frame every second keeps "om N min" up to date.
+func (f *Formatter) FormatDepartureWatch(frame WatchFrame) string {
+	var sb strings.Builder
+	sb.WriteString(f.formatBoard(frame.Board, frame.Mode, frame.Towards, frame.Changes))
+
+	if frame.Err != nil {
+		if f.Language == "en" {
+			sb.WriteString(fmt.Sprintf("  ⚠️  Update failed: %v\n", frame.Err))
+		} else {
+			sb.WriteString(fmt.Sprintf("  ⚠️  Kunde inte uppdatera: %v\n", frame.Err))
+		}
+	}
+
+	next := int(time.Until(frame.NextFetch).Seconds())
+	if next < 0 {
+		next = 0
+	}
+	if f.Language == "en" {
+		sb.WriteString(fmt.Sprintf("  Updated %s · next update in %d s · Ctrl-C to quit\n",
+			frame.UpdatedAt.Format("15:04:05"), next))
+	} else {
+		sb.WriteString(fmt.Sprintf("  Uppdaterad %s · nästa uppdatering om %d s · Ctrl-C avslutar\n",
+			frame.UpdatedAt.Format("15:04:05"), next))
+	}
+
+	return sb.String()
+}
+
+// highlightChange colours a departure row by its most serious change and
+// tags it with what changed
+func (f *Formatter) highlightChange(row string, change api.DepartureChange) string {
+	colour := ansiGreen
+	switch {
+	case change.Has(api.ChangeCancelled):
+		colour = ansiRed
+	case change.Has(api.ChangeDelayed):
+		colour = ansiYellow
+	case change.Has(api.ChangePlatform):
+		colour = ansiCyan
+	}
+
+	var tags []string
+	for _, name := range change.Names() {
+		tags = append(tags, f.changeLabel(name))
+	}
+
+	return fmt.Sprintf("%s%s%s  « %s »%s", ansiBold, colour, row, strings.Join(tags, ", "), ansiReset)
+}
+
+// changeLabel returns the label for a change name from api.DepartureChange.Names
+func (f *Formatter) changeLabel(name string) string {
+	if f.Language == "en" {
+		if name == "platform" {
+			return "new platform"
+		}
+		return name
+	}
+
+	switch name {
+	case "new":
+		return "ny"
+	case "delayed":
+		return "försenad"
+	case "cancelled":
+		return "inställd"
+	case "platform":
+		return "nytt läge"
+	default:
+		return name
+	}
+}
@@ -0,0 +1,214 @@
+// Package errs defines the errors returned by the transport clients, so
+// callers can tell what went wrong without matching on message text.
+//
+// Every error the clients return can be tested with errors.Is against one
+// of the sentinel errors below, and Classify maps any error to a stable,
+// machine-readable Code.
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error classes. Test with errors.Is.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAmbiguous     = errors.New("ambiguous")
+	ErrUpstream      = errors.New("upstream error")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrMissingAPIKey = errors.New("missing API key")
+	ErrTimeout       = errors.New("timeout")
+	ErrInvalidInput  = errors.New("invalid input")
+)
+
+// Code is a machine-readable error class
+type Code string
+
+// Codes returned by Classify
+const (
+	CodeNotFound      Code = "not_found"
+	CodeAmbiguous     Code = "ambiguous_location"
+	CodeUpstream      Code = "upstream_error"
+	CodeRateLimited   Code = "rate_limited"
+	CodeMissingAPIKey Code = "missing_api_key"
+	CodeTimeout       Code = "timeout"
+	CodeInvalidInput  Code = "invalid_input"
+	CodeInternal      Code = "internal_error"
+)
+
+// Classify returns the class of err. Errors that don't belong to any
+// class are CodeInternal.
+func Classify(err error) Code {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrAmbiguous):
+		return CodeAmbiguous
+	case errors.Is(err, ErrMissingAPIKey):
+		return CodeMissingAPIKey
+	case errors.Is(err, ErrRateLimited):
+		return CodeRateLimited
+	case IsTimeout(err):
+		return CodeTimeout
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrInvalidInput):
+		return CodeInvalidInput
+	case errors.Is(err, ErrUpstream):
+		return CodeUpstream
+	}
+	return CodeInternal
+}
+
+// IsTimeout reports whether err is a timeout, either reported by a client
+// or a network timeout from the HTTP transport
+func IsTimeout(err error) bool {
+	if errors.Is(err, ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// NotFoundError is returned when a stop, site, location or route doesn't
+// exist
+type NotFoundError struct {
+	What        string   // "stops", "sites", "location", "route", ...
+	Query       string   // what was searched for, if anything
+	Role        string   // "origin", "destination" or "via", if known
+	Suggestions []string // close matches to offer instead
+}
+
+func (e *NotFoundError) Error() string {
+	var msg string
+	switch {
+	case e.Query == "":
+		msg = fmt.Sprintf("no %s found", e.What)
+	case e.Role != "":
+		msg = fmt.Sprintf("no %s found for %s '%s'", e.What, e.Role, e.Query)
+	default:
+		msg = fmt.Sprintf("no %s found for '%s'", e.What, e.Query)
+	}
+	if n := len(e.Suggestions); n > 0 {
+		msg += ". Did you mean: " + strings.Join(e.Suggestions[:n-1], ", ")
+		if n > 1 {
+			msg += " or "
+		}
+		msg += e.Suggestions[n-1]
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, ErrNotFound) true
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// UpstreamError is returned when a backend API fails: an HTTP status
+// other than success, an error message in an otherwise valid response, or
+// a response that never arrived or can't be read
+type UpstreamError struct {
+	Service    string        // "SL", "ResRobot", ...
+	StatusCode int           // HTTP status, 0 for an error in the response body
+	Message    string        // the body or error text from the API
+	RetryAfter time.Duration // from the Retry-After header, 0 if not sent
+	Err        error         // the network or decoding error, if that was the cause
+}
+
+func (e *UpstreamError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s API error: %s", e.Service, e.Message)
+	}
+	return fmt.Sprintf("%s API error %d: %s", e.Service, e.StatusCode, e.Message)
+}
+
+// Unwrap returns the network or decoding error, so that network timeouts
+// are still timeouts
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrUpstream) true, and ErrRateLimited or
+// ErrTimeout for the HTTP statuses that mean so
+func (e *UpstreamError) Is(target error) bool {
+	switch target {
+	case ErrUpstream:
+		return true
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrTimeout:
+		return e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusGatewayTimeout
+	}
+	return false
+}
+
+// maxErrorBody bounds how much of an error response is kept
+const maxErrorBody = 512
+
+// FromResponse makes an UpstreamError from an unsuccessful HTTP response,
+// reading (part of) its body
+func FromResponse(service string, resp *http.Response) *UpstreamError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	e := &UpstreamError{
+		Service:    service,
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return e
+}
+
+// Unavailable wraps an error reaching service or reading its response as
+// an UpstreamError. Cancellation is returned as it is: the API didn't
+// fail, the user gave up.
+func Unavailable(service string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	return &UpstreamError{Service: service, Message: err.Error(), Err: err}
+}
+
+// MissingAPIKeyError is returned when a service needs an API key that
+// isn't configured
+type MissingAPIKeyError struct {
+	EnvVar    string // environment variable holding the key
+	SignupURL string // where to get a key
+}
+
+func (e *MissingAPIKeyError) Error() string {
+	return fmt.Sprintf("%s not set. Get a free key at %s", e.EnvVar, e.SignupURL)
+}
+
+// Is makes errors.Is(err, ErrMissingAPIKey) true
+func (e *MissingAPIKeyError) Is(target error) bool {
+	return target == ErrMissingAPIKey
+}
+
+// inputError is an invalid argument, made by Invalidf
+type inputError struct {
+	msg string
+}
+
+func (e *inputError) Error() string {
+	return e.msg
+}
+
+func (e *inputError) Is(target error) bool {
+	return target == ErrInvalidInput
+}
+
+// Invalidf returns an error for invalid input, for which
+// errors.Is(err, ErrInvalidInput) is true
+func Invalidf(format string, args ...any) error {
+	return &inputError{msg: fmt.Sprintf(format, args...)}
+}
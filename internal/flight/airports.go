@@ -1,6 +1,7 @@
 package flight
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
 	"io"
@@ -10,6 +11,10 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"transport/internal/errs"
+	"transport/internal/httpx"
+	"transport/internal/match"
 )
 
 const (
@@ -49,23 +54,45 @@ type NearbyResult struct {
 	DistanceKm float64
 }
 
-// FetchSwedishAirports fetches all Swedish airports from OurAirports
-func FetchSwedishAirports() ([]Airport, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// Client downloads the OurAirports database
+type Client struct {
+	httpClient  *http.Client
+	airportsURL string
+}
+
+// NewClient creates a client for the public OurAirports data
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient:  httpx.NewClient(30 * time.Second),
+		airportsURL: airportsURL,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
+}
 
-	resp, err := client.Get(airportsURL)
+// FetchSwedishAirports fetches all Swedish airports from OurAirports
+func (c *Client) FetchSwedishAirports(ctx context.Context) ([]Airport, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.airportsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch airports: %w", err)
+		return nil, fmt.Errorf("failed to fetch airports: %w", errs.Unavailable("OurAirports", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+		return nil, errs.FromResponse("OurAirports", resp)
 	}
 
-	return parseAirportsCSV(resp.Body, "SE")
+	airports, err := parseAirportsCSV(resp.Body, "SE")
+	if err != nil {
+		return nil, errs.Unavailable("OurAirports", err)
+	}
+	return airports, nil
 }
 
 // parseAirportsCSV parses the OurAirports CSV format
@@ -251,9 +278,21 @@ func GetCoordinates(location string) (lat, lon float64, found bool) {
 	if ok {
 		return coords.Lat, coords.Lon, true
 	}
+
+	// Fall back to forgiving matching ("Goteborg", "LULEÅ", "Vaxjo")
+	if key, found := match.BestKey(location, CommonLocations); found {
+		coords := CommonLocations[key]
+		return coords.Lat, coords.Lon, true
+	}
 	return 0, 0, false
 }
 
+// SuggestLocations returns known locations close to an unknown name, best
+// first
+func SuggestLocations(location string) []string {
+	return match.Suggest(location, CommonLocations, 3)
+}
+
 // GetAirportTypeLabel returns a Swedish label for airport type
 func GetAirportTypeLabel(airportType string) string {
 	switch airportType {
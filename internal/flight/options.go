@@ -0,0 +1,21 @@
+package flight
+
+import "net/http"
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAirportsURL downloads the airports CSV from url instead of
+// OurAirports, e.g. from a fake in transporttest
+func WithAirportsURL(url string) Option {
+	return func(c *Client) {
+		c.airportsURL = url
+	}
+}
+
+// WithHTTPClient sends requests with hc instead of the shared client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
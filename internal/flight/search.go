@@ -5,6 +5,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"transport/internal/match"
 )
 
 // FlightSearch contains parameters for a flight search
@@ -383,7 +385,20 @@ func LookupAirportCode(city string) (string, bool) {
 
 	// Look up by city name
 	code, ok := CityToAirport[strings.ToLower(strings.TrimSpace(city))]
-	return code, ok
+	if ok {
+		return code, true
+	}
+
+	// Fall back to forgiving matching ("Goteborg", "MALMÖ", "BARCELONA")
+	if key, found := match.BestKey(city, CityToAirport); found {
+		return CityToAirport[key], true
+	}
+	return "", false
+}
+
+// SuggestCities returns known cities close to an unknown name, best first
+func SuggestCities(city string) []string {
+	return match.Suggest(city, CityToAirport, 3)
 }
 
 func isAllLetters(s string) bool {
@@ -0,0 +1,112 @@
+// Package geo holds the geometry of planned journeys and exports it for
+// map tools.
+//
+// Each planner describes where a journey goes in its own way: SL legs carry
+// a polyline of [lat, lon] pairs, ResRobot only the coordinates of the
+// stops. Both are converted to a Route of typed points, which can be
+// written as a GeoJSON FeatureCollection or as GPX.
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Point is a WGS84 coordinate
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// IsZero reports whether the point is unset. (0, 0) is in the Gulf of
+// Guinea, so the planners use it for "no coordinates".
+func (p Point) IsZero() bool {
+	return p.Lat == 0 && p.Lon == 0
+}
+
+// FromLatLon makes a point from a [lat, lon] pair as used by SL. ok is
+// false if coord isn't a usable pair.
+func FromLatLon(coord []float64) (p Point, ok bool) {
+	if len(coord) < 2 {
+		return Point{}, false
+	}
+	p = Point{Lat: coord[0], Lon: coord[1]}
+	return p, !p.IsZero()
+}
+
+// ParseLatLon parses a "lat,lon" pair such as "59.3293,18.0686"
+func ParseLatLon(s string) (p Point, ok bool) {
+	latStr, lonStr, found := strings.Cut(s, ",")
+	if !found || strings.Contains(lonStr, ",") {
+		return Point{}, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	if err != nil {
+		return Point{}, false
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	if err != nil {
+		return Point{}, false
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return Point{}, false
+	}
+	return Point{Lat: lat, Lon: lon}, true
+}
+
+// DecodeCoords decodes an SL leg polyline, a JSON array of [lat, lon]
+// pairs. An empty or null polyline has no points.
+func DecodeCoords(raw json.RawMessage) ([]Point, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var pairs [][]float64
+	if err := json.Unmarshal(raw, &pairs); err != nil {
+		return nil, fmt.Errorf("failed to decode coords: %w", err)
+	}
+
+	points := make([]Point, 0, len(pairs))
+	for i, pair := range pairs {
+		p, ok := FromLatLon(pair)
+		if !ok {
+			return nil, fmt.Errorf("invalid coordinate %d in coords", i)
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// Leg is one ride or walk of a route
+type Leg struct {
+	Mode      string // metro, bus, train, tram, ship, walk
+	Line      string
+	Direction string
+	From      string
+	To        string
+	Departure time.Time
+	Arrival   time.Time
+	Points    []Point // from the origin to the destination
+}
+
+// Route is the geometry of a whole journey
+type Route struct {
+	Name string // e.g. "Slussen → Kista"
+	Legs []Leg
+}
+
+// InPolygon reports whether p lies inside the polygon, given as its
+// corners in order. Points on the edge may fall either way.
+func InPolygon(p Point, polygon []Point) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,75 @@
+package geo
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// featureCollection and feature follow RFC 7946. Positions are
+// [lon, lat], the other way around from SL.
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Name     string    `json:"name,omitempty"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string         `json:"type"`
+	Geometry   geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// GeoJSON returns the route as a FeatureCollection with one LineString per
+// leg. Each feature has the leg's mode, line, direction, stops and times as
+// properties. Legs without coordinates are left out.
+func (r Route) GeoJSON() ([]byte, error) {
+	fc := featureCollection{Type: "FeatureCollection", Name: r.Name, Features: []feature{}}
+	for i, leg := range r.Legs {
+		if len(leg.Points) == 0 {
+			continue
+		}
+
+		var geom geometry
+		if len(leg.Points) == 1 {
+			geom = geometry{Type: "Point", Coordinates: position(leg.Points[0])}
+		} else {
+			line := make([][2]float64, len(leg.Points))
+			for j, p := range leg.Points {
+				line[j] = position(p)
+			}
+			geom = geometry{Type: "LineString", Coordinates: line}
+		}
+
+		props := map[string]any{
+			"leg":  i + 1,
+			"mode": leg.Mode,
+			"from": leg.From,
+			"to":   leg.To,
+		}
+		if leg.Line != "" {
+			props["line"] = leg.Line
+		}
+		if leg.Direction != "" {
+			props["direction"] = leg.Direction
+		}
+		if !leg.Departure.IsZero() {
+			props["departure"] = leg.Departure.Format(time.RFC3339)
+		}
+		if !leg.Arrival.IsZero() {
+			props["arrival"] = leg.Arrival.Format(time.RFC3339)
+		}
+
+		fc.Features = append(fc.Features, feature{Type: "Feature", Geometry: geom, Properties: props})
+	}
+	return json.MarshalIndent(fc, "", "  ")
+}
+
+// position returns a GeoJSON position
+func position(p Point) [2]float64 {
+	return [2]float64{p.Lon, p.Lat}
+}
@@ -0,0 +1,107 @@
+package geo
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+// GPX 1.1 document, see https://www.topografix.com/GPX/1/1/
+type gpxDoc struct {
+	XMLName   xml.Name      `xml:"gpx"`
+	Version   string        `xml:"version,attr"`
+	Creator   string        `xml:"creator,attr"`
+	Namespace string        `xml:"xmlns,attr"`
+	Metadata  *gpxMetadata  `xml:"metadata,omitempty"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+	Tracks    []gpxTrack    `xml:"trk"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name,omitempty"`
+	Time string `xml:"time,omitempty"`
+}
+
+type gpxWaypoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time,omitempty"`
+	Name string  `xml:"name,omitempty"`
+}
+
+type gpxTrack struct {
+	Name    string     `xml:"name,omitempty"`
+	Type    string     `xml:"type,omitempty"`
+	Segment gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxWaypoint `xml:"trkpt"`
+}
+
+// GPX returns the route as a GPX 1.1 document with a track per leg and a
+// waypoint at each stop where a leg starts or ends. Legs without
+// coordinates are left out.
+func (r Route) GPX() ([]byte, error) {
+	doc := gpxDoc{
+		Version:   "1.1",
+		Creator:   "transport",
+		Namespace: "http://www.topografix.com/GPX/1/1",
+	}
+	if r.Name != "" {
+		doc.Metadata = &gpxMetadata{Name: r.Name}
+	}
+	if len(r.Legs) > 0 && !r.Legs[0].Departure.IsZero() {
+		if doc.Metadata == nil {
+			doc.Metadata = &gpxMetadata{}
+		}
+		doc.Metadata.Time = gpxTime(r.Legs[0].Departure)
+	}
+
+	for i, leg := range r.Legs {
+		if len(leg.Points) == 0 {
+			continue
+		}
+
+		// A waypoint where the leg starts, and one at the end of the journey
+		first, last := leg.Points[0], leg.Points[len(leg.Points)-1]
+		doc.Waypoints = append(doc.Waypoints, gpxWaypoint{Lat: first.Lat, Lon: first.Lon, Time: gpxTime(leg.Departure), Name: leg.From})
+		if i == len(r.Legs)-1 {
+			doc.Waypoints = append(doc.Waypoints, gpxWaypoint{Lat: last.Lat, Lon: last.Lon, Time: gpxTime(leg.Arrival), Name: leg.To})
+		}
+
+		track := gpxTrack{Name: trackName(leg), Type: leg.Mode}
+		for _, p := range leg.Points {
+			track.Segment.Points = append(track.Segment.Points, gpxWaypoint{Lat: p.Lat, Lon: p.Lon})
+		}
+		track.Segment.Points[0].Time = gpxTime(leg.Departure)
+		track.Segment.Points[len(track.Segment.Points)-1].Time = gpxTime(leg.Arrival)
+		doc.Tracks = append(doc.Tracks, track)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// trackName names a leg's track, e.g. "14 Slussen → Kista"
+func trackName(leg Leg) string {
+	parts := []string{}
+	if leg.Line != "" {
+		parts = append(parts, leg.Line)
+	} else if leg.Mode != "" {
+		parts = append(parts, leg.Mode)
+	}
+	parts = append(parts, leg.From+" → "+leg.To)
+	return strings.Join(parts, " ")
+}
+
+// gpxTime formats a time for GPX, or "" if unknown
+func gpxTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,221 @@
+// Package httpx is the HTTP layer shared by every upstream client.
+//
+// Requests go through one Transport that rate limits per host, retries
+// idempotent requests with jittered backoff when the server is failing or
+// asks us to slow down, tags each request with an ID and, when enabled,
+// traces every call to a debug log.
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// UserAgent is sent with requests that don't set their own. Some services,
+// Nominatim in particular, refuse requests without a descriptive one.
+var UserAgent = "transport-cli/1.0"
+
+// Retry policy
+const (
+	maxAttempts  = 3                      // tries per request, including the first
+	baseBackoff  = 250 * time.Millisecond // first retry waits up to this long
+	maxBackoff   = 4 * time.Second        // longest backoff between tries
+	maxRetryWait = 10 * time.Second       // don't honour Retry-After beyond this
+)
+
+// RequestIDHeader carries the ID of each request, the same across retries
+const RequestIDHeader = "X-Request-ID"
+
+// Transport is an http.RoundTripper with rate limiting, retries, request
+// IDs and tracing. The zero value is not usable; use NewTransport.
+type Transport struct {
+	base http.RoundTripper
+
+	mu     sync.Mutex
+	limits map[string]*limiter // by host
+	debug  io.Writer           // trace destination, nil when tracing is off
+}
+
+// NewTransport returns a transport sending requests through base, or
+// http.DefaultTransport if base is nil
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, limits: make(map[string]*limiter)}
+}
+
+// Default is the transport used by clients made with NewClient
+var Default = NewTransport(nil)
+
+func init() {
+	// Nominatim's usage policy allows at most one request per second
+	Default.SetRateLimit("nominatim.openstreetmap.org", time.Second)
+}
+
+// NewClient returns an HTTP client using the Default transport. The
+// timeout covers the whole call, retries included.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: Default}
+}
+
+// SetRateLimit spaces requests to host at least interval apart. Zero
+// removes the limit.
+func (t *Transport) SetRateLimit(host string, interval time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if interval <= 0 {
+		delete(t.limits, host)
+		return
+	}
+	t.limits[host] = &limiter{interval: interval}
+}
+
+// SetDebug traces every upstream call to w, or turns tracing off if w is nil
+func (t *Transport) SetDebug(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.debug = w
+}
+
+// RoundTrip sends req, waiting for the host's rate limit and retrying
+// GET and HEAD requests on network errors, 429 and 5xx responses
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", UserAgent)
+	}
+	id := req.Header.Get(RequestIDHeader)
+	if id == "" {
+		id = newRequestID()
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	t.mu.Lock()
+	limit := t.limits[req.URL.Hostname()]
+	t.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		if err := limit.wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := t.base.RoundTrip(req)
+		t.trace(id, attempt, req, resp, err, time.Since(start))
+
+		wait, retry := retryAfter(req, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			// Drain so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter decides whether a failed attempt should be retried, and
+// how long to wait first
+func retryAfter(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= maxAttempts || req.Context().Err() != nil {
+		return 0, false
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return 0, false
+	}
+
+	if err == nil {
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return 0, false
+		}
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return wait, wait <= maxRetryWait
+		}
+	}
+	return backoff(attempt), true
+}
+
+// backoff returns a random wait up to baseBackoff doubled per attempt
+// ("full jitter"), so clients that failed together don't retry together
+func backoff(attempt int) time.Duration {
+	ceiling := baseBackoff << (attempt - 1)
+	if ceiling > maxBackoff {
+		ceiling = maxBackoff
+	}
+	return time.Duration(rand.Int64N(int64(ceiling)))
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as a date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(0, time.Until(t)), true
+	}
+	return 0, false
+}
+
+// trace writes one line about an attempt to the debug log
+func (t *Transport) trace(id string, attempt int, req *http.Request, resp *http.Response, err error, took time.Duration) {
+	t.mu.Lock()
+	w := t.debug
+	t.mu.Unlock()
+	if w == nil {
+		return
+	}
+
+	result := ""
+	if err != nil {
+		result = "error: " + err.Error()
+	} else {
+		result = resp.Status
+	}
+	fmt.Fprintf(w, "[http %s #%d] %s %s → %s (%d ms)\n",
+		id, attempt, req.Method, redact(req.URL), result, took.Milliseconds())
+}
+
+// secretParams are query parameters that hold API keys
+var secretParams = []string{"accessId", "key", "apikey", "api_key"}
+
+// redact returns u with API keys hidden, for logging
+func redact(u *url.URL) string {
+	q := u.Query()
+	changed := false
+	for _, name := range secretParams {
+		if q.Has(name) {
+			q.Set(name, "REDACTED")
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+// newRequestID returns a short random ID
+func newRequestID() string {
+	return fmt.Sprintf("%012x", rand.Uint64()&(1<<48-1))
+}
@@ -0,0 +1,149 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"Mon, 02 Mar 2020 08:00:00 GMT", 0, true}, // in the past
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got, ok := parseRetryAfter(future); !ok || got <= 50*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %v, %v; want about a minute", future, got, ok)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt, ceiling := range map[int]time.Duration{
+		1: baseBackoff,
+		2: 2 * baseBackoff,
+		3: 4 * baseBackoff,
+		9: maxBackoff,
+	} {
+		for range 100 {
+			if got := backoff(attempt); got < 0 || got >= ceiling {
+				t.Fatalf("backoff(%d) = %v, want below %v", attempt, got, ceiling)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	networkErr := errors.New("connection reset")
+	tests := []struct {
+		name       string
+		method     string
+		status     int
+		retryAfter string
+		err        error
+		attempt    int
+		retry      bool
+		wait       time.Duration // exact wait, -1 for a random backoff
+	}{
+		{"success", http.MethodGet, 200, "", nil, 1, false, 0},
+		{"not found", http.MethodGet, 404, "", nil, 1, false, 0},
+		{"server error", http.MethodGet, 503, "", nil, 1, true, -1},
+		{"network error", http.MethodGet, 0, "", networkErr, 1, true, -1},
+		{"rate limited", http.MethodGet, 429, "2", nil, 1, true, 2 * time.Second},
+		{"retry after too long", http.MethodGet, 429, "60", nil, 1, false, 60 * time.Second},
+		{"last attempt", http.MethodGet, 503, "", nil, maxAttempts, false, 0},
+		{"not idempotent", http.MethodPost, 503, "", nil, 1, false, 0},
+		{"head", http.MethodHead, 502, "", nil, 1, true, -1},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "http://example.com/", nil)
+		var resp *http.Response
+		if tt.err == nil {
+			resp = &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+		}
+		wait, retry := retryAfter(req, resp, tt.err, tt.attempt)
+		if retry != tt.retry {
+			t.Errorf("%s: retry = %v, want %v", tt.name, retry, tt.retry)
+		}
+		if tt.wait >= 0 && wait != tt.wait {
+			t.Errorf("%s: wait = %v, want %v", tt.name, wait, tt.wait)
+		}
+	}
+}
+
+func TestRoundTripRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int // answers in order; the last repeats
+		want     int   // final status
+		attempts int
+	}{
+		{"recovers", http.MethodGet, []int{503, 200}, 200, 2},
+		{"gives up", http.MethodGet, []int{502}, 502, maxAttempts},
+		{"rate limited", http.MethodGet, []int{429, 429, 200}, 200, 3},
+		{"no retry for POST", http.MethodPost, []int{503, 200}, 503, 1},
+		{"client error", http.MethodGet, []int{400, 200}, 400, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var ids []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				ids = append(ids, r.Header.Get(RequestIDHeader))
+				status := tt.statuses[min(len(ids), len(tt.statuses))-1]
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(status)
+			}))
+			defer srv.Close()
+
+			var trace strings.Builder
+			transport := NewTransport(nil)
+			transport.SetDebug(&trace)
+			client := &http.Client{Transport: transport}
+
+			req, _ := http.NewRequest(tt.method, srv.URL+"/trip?accessId=secret", nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.want || len(ids) != tt.attempts {
+				t.Errorf("status %d after %d attempts, want %d after %d", resp.StatusCode, len(ids), tt.want, tt.attempts)
+			}
+			for _, id := range ids {
+				if id == "" || id != ids[0] {
+					t.Errorf("request IDs %q, want one ID for every attempt", ids)
+					break
+				}
+			}
+			if strings.Contains(trace.String(), "secret") {
+				t.Errorf("trace shows the API key:\n%s", trace.String())
+			}
+		})
+	}
+}
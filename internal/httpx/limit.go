@@ -0,0 +1,44 @@
+package httpx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter spaces requests to one host at least interval apart
+type limiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time // earliest time the next request may start
+}
+
+// wait blocks until a request may start. A nil limiter never waits.
+func (l *limiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,48 @@
+package match
+
+// Candidate is a place a name lookup found, as Choose weighs it
+type Candidate[T any] struct {
+	Names  func(*T) []string // the names the place goes by
+	Weight func(*T) int      // how well the provider rates the match, higher is better
+}
+
+// Choose picks the candidate query means. A unique exact name match always
+// wins; otherwise the first candidate wins only if the runner-up weighs
+// less than ratio times as much. With no clear winner it returns the
+// candidates still in the running, at most max of them. Both results are
+// nil if there are no candidates.
+func Choose[T any](query string, candidates []T, c Candidate[T], ratio float64, max int) (*T, []T) {
+	switch len(candidates) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &candidates[0], nil
+	}
+
+	var exact []T
+	for i := range candidates {
+		for _, name := range c.Names(&candidates[i]) {
+			if Equal(name, query) {
+				exact = append(exact, candidates[i])
+				break
+			}
+		}
+	}
+	if len(exact) == 1 {
+		return &exact[0], nil
+	}
+
+	if len(exact) == 0 {
+		best, runnerUp := c.Weight(&candidates[0]), c.Weight(&candidates[1])
+		if best > 0 && float64(runnerUp) < float64(best)*ratio {
+			return &candidates[0], nil
+		}
+	} else {
+		candidates = exact
+	}
+
+	if len(candidates) > max {
+		candidates = candidates[:max]
+	}
+	return nil, candidates
+}
@@ -0,0 +1,83 @@
+package match
+
+import (
+	"slices"
+	"testing"
+)
+
+type place struct {
+	name   string
+	weight int
+}
+
+var placeCandidate = Candidate[place]{
+	Names:  func(p *place) []string { return []string{p.name} },
+	Weight: func(p *place) int { return p.weight },
+}
+
+func TestChoose(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		candidates []place
+		want       string   // chosen name, "" for none
+		others     []string // candidates offered instead
+	}{
+		{
+			name:  "no candidates",
+			query: "Odenplan",
+		},
+		{
+			name:       "single candidate",
+			query:      "Odenpla",
+			candidates: []place{{"Odenplan", 10}},
+			want:       "Odenplan",
+		},
+		{
+			name:       "unique exact name wins over weight",
+			query:      "Solna",
+			candidates: []place{{"Solna centrum", 900}, {"Solna", 100}},
+			want:       "Solna",
+		},
+		{
+			name:       "clear winner by weight",
+			query:      "Slus",
+			candidates: []place{{"Slussen", 1000}, {"Slussen (Stadsgårdsleden)", 400}},
+			want:       "Slussen",
+		},
+		{
+			name:       "close weights are ambiguous",
+			query:      "Solna",
+			candidates: []place{{"Solna centrum", 1000}, {"Solna station", 950}},
+			others:     []string{"Solna centrum", "Solna station"},
+		},
+		{
+			name:       "several exact names are ambiguous",
+			query:      "Centrum",
+			candidates: []place{{"Centrum", 900}, {"Kista centrum", 800}, {"centrum", 100}},
+			others:     []string{"Centrum", "centrum"},
+		},
+		{
+			name:       "at most max offered",
+			query:      "Torget",
+			candidates: []place{{"A torget", 1}, {"B torget", 1}, {"C torget", 1}, {"D torget", 1}},
+			others:     []string{"A torget", "B torget", "C torget"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, others := Choose(tt.query, tt.candidates, placeCandidate, 0.9, 3)
+			name := ""
+			if got != nil {
+				name = got.name
+			}
+			var offered []string
+			for _, p := range others {
+				offered = append(offered, p.name)
+			}
+			if name != tt.want || !slices.Equal(offered, tt.others) {
+				t.Errorf("Choose(%q) = %q, %q; want %q, %q", tt.query, name, offered, tt.want, tt.others)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+// Package match provides forgiving name matching for stops, sites and cities.
+//
+// Names are compared after Unicode case folding, removal of diacritics
+// (å/ä → a, ö → o, é → e, ...), punctuation normalisation and expansion of
+// common abbreviations, so "ÖSTERMALMSTORG", "ostermalmstorg" and
+// "Östermalmstorg" are all the same name. Near misses are ranked by edit
+// distance.
+package match
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Match tiers, best first. Scores are tier*tierWeight + distance*distanceWeight + length.
+const (
+	TierExact = iota
+	TierPrefix
+	TierContains
+	TierFuzzy
+)
+
+const (
+	tierWeight     = 1000
+	distanceWeight = 100
+	maxLengthScore = 99
+
+	// minLookupLength is the shortest query Suggest matches other than exactly
+	minLookupLength = 3
+)
+
+// foldRunes maps accented letters to their plain base letters
+var foldRunes = map[rune]string{
+	'å': "a", 'ä': "a", 'à': "a", 'á': "a", 'â': "a", 'ã': "a",
+	'æ': "ae",
+	'ö': "o", 'ø': "o", 'ó': "o", 'ò': "o", 'ô': "o", 'õ': "o",
+	'é': "e", 'è': "e", 'ê': "e", 'ë': "e",
+	'ü': "u", 'ú': "u", 'ù': "u", 'û': "u",
+	'í': "i", 'ì': "i", 'î': "i", 'ï': "i",
+	'ç': "c", 'č': "c", 'ñ': "n", 'ń': "n",
+	'š': "s", 'ś': "s", 'ž': "z", 'ż': "z", 'ł': "l",
+	'ý': "y", 'ÿ': "y", 'ß': "ss",
+}
+
+// wordAbbreviations expands abbreviated words, applied after folding
+var wordAbbreviations = map[string]string{
+	"sthlm": "stockholm",
+	"gbg":   "goteborg",
+	"st":    "sankt", // "S:t Eriksplan", "St Eriksplan"
+}
+
+// nameAbbreviations expands whole names, keyed by the folded name without spaces
+var nameAbbreviations = map[string]string{
+	"tc":         "t centralen",
+	"tcentral":   "t centralen",
+	"tcentralen": "t centralen",
+	"cst":        "stockholm central",
+}
+
+// Normalize folds s for comparison: lower case, no diacritics, punctuation
+// turned into single spaces and abbreviations expanded.
+func Normalize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for _, r := range s {
+		r = unicode.ToLower(r)
+		switch {
+		case r == ':' || r == '.' || r == '\'' || r == '’':
+			// "S:t" → "st", "St." → "st"
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			if folded, ok := foldRunes[r]; ok {
+				b.WriteString(folded)
+			} else {
+				b.WriteRune(r)
+			}
+		default:
+			space = true
+		}
+	}
+
+	folded := b.String()
+	if expanded, ok := nameAbbreviations[strings.ReplaceAll(folded, " ", "")]; ok {
+		return expanded
+	}
+
+	words := strings.Split(folded, " ")
+	changed := false
+	for i, w := range words {
+		if expanded, ok := wordAbbreviations[w]; ok {
+			words[i] = expanded
+			changed = true
+		}
+	}
+	if changed {
+		return strings.Join(words, " ")
+	}
+	return folded
+}
+
+// Equal reports whether a and b are the same name after normalisation
+func Equal(a, b string) bool {
+	return Normalize(a) == Normalize(b)
+}
+
+// Contains reports whether s contains substr after normalisation
+func Contains(s, substr string) bool {
+	return strings.Contains(Normalize(s), Normalize(substr))
+}
+
+// Matcher scores candidates against a single query. Build it once with New
+// when matching many candidates.
+type Matcher struct {
+	query   string
+	compact string
+	runes   []rune
+}
+
+// New prepares a matcher for query
+func New(query string) *Matcher {
+	q := Normalize(query)
+	return &Matcher{
+		query:   q,
+		compact: strings.ReplaceAll(q, " ", ""),
+		runes:   []rune(q),
+	}
+}
+
+// Score rates how well candidate matches the query; lower is better.
+// ok is false when the candidate doesn't match at all.
+func (m *Matcher) Score(candidate string) (score int, ok bool) {
+	return m.ScoreNormalized(Normalize(candidate))
+}
+
+// ScoreNormalized is Score for a candidate already passed through Normalize
+func (m *Matcher) ScoreNormalized(candidate string) (score int, ok bool) {
+	if m.query == "" || candidate == "" {
+		return 0, false
+	}
+
+	compact := strings.ReplaceAll(candidate, " ", "")
+	length := min(len([]rune(candidate)), maxLengthScore)
+
+	switch {
+	case candidate == m.query || compact == m.compact:
+		return TierExact*tierWeight + length, true
+	case strings.HasPrefix(candidate, m.query) || strings.HasPrefix(compact, m.compact):
+		return TierPrefix*tierWeight + length, true
+	case strings.Contains(candidate, m.query) || strings.Contains(compact, m.compact):
+		return TierContains*tierWeight + length, true
+	}
+
+	limit := maxEdits(len(m.runes))
+	if limit == 0 {
+		return 0, false
+	}
+
+	// Compare against the whole name and against a prefix of the same
+	// length, so typos in a partially typed name still match
+	runes := []rune(candidate)
+	distance := levenshtein(m.runes, runes)
+	if distance > limit {
+		if len(runes) <= len(m.runes) {
+			return 0, false
+		}
+		// Try a prefix one rune longer too, for a missed letter ("Spnga")
+		d := levenshtein(m.runes, runes[:len(m.runes)])
+		if n := len(m.runes) + 1; n <= len(runes) {
+			d = min(d, levenshtein(m.runes, runes[:n]))
+		}
+		if d > limit {
+			return 0, false
+		}
+		// Rank prefix typos below typos in the whole name
+		distance = d + 1
+	}
+	return TierFuzzy*tierWeight + distance*distanceWeight + length, true
+}
+
+// Score rates how well candidate matches query; lower is better.
+// ok is false when the candidate doesn't match at all.
+func Score(query, candidate string) (score int, ok bool) {
+	return New(query).Score(candidate)
+}
+
+// Matches reports whether candidate matches query at all
+func Matches(query, candidate string) bool {
+	_, ok := Score(query, candidate)
+	return ok
+}
+
+// BestKey returns the map key that is the same name as query after
+// normalisation, ignoring spaces. Ties are broken alphabetically so results are stable.
+// Near misses are never picked, so that a typo can't swap one place for
+// another; offer them with Suggest instead.
+func BestKey[V any](query string, m map[string]V) (string, bool) {
+	q := compact(Normalize(query))
+	if q == "" {
+		return "", false
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if compact(Normalize(k)) == q {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// Suggest returns up to n map keys that match query, best first, for a
+// "did you mean" list. Keys that are the same name after normalisation
+// ("göteborg", "goteborg") are listed once, preferring the accented one.
+func Suggest[V any](query string, m map[string]V, n int) []string {
+	matcher := New(query)
+	if matcher.query == "" || n <= 0 {
+		return nil
+	}
+
+	type scored struct {
+		key   string
+		score int
+	}
+	best := make(map[string]scored)
+	for k := range m {
+		score, ok := matcher.Score(k)
+		if !ok || (score >= tierWeight && len(matcher.runes) < minLookupLength) {
+			continue
+		}
+		name := Normalize(k)
+		if prev, seen := best[name]; seen && (len(prev.key) > len(k) || len(prev.key) == len(k) && prev.key < k) {
+			continue
+		}
+		best[name] = scored{k, score}
+	}
+
+	matches := make([]scored, 0, len(best))
+	for _, s := range best {
+		matches = append(matches, s)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score < matches[j].score
+		}
+		return matches[i].key < matches[j].key
+	})
+
+	var keys []string
+	for i := 0; i < len(matches) && i < n; i++ {
+		keys = append(keys, matches[i].key)
+	}
+	return keys
+}
+
+// Tier returns the match tier of a score: TierExact, TierPrefix,
+// TierContains or TierFuzzy
+func Tier(score int) int {
+	return score / tierWeight
+}
+
+// compact removes the spaces from a normalised name
+func compact(s string) string {
+	return strings.ReplaceAll(s, " ", "")
+}
+
+// maxEdits returns how many typos are tolerated for a query of n runes
+func maxEdits(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,107 @@
+package match
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Östermalmstorg", "ostermalmstorg"},
+		{"ÖSTERMALMSTORG", "ostermalmstorg"},
+		{"S:t Eriksplan", "sankt eriksplan"},
+		{"St. Eriksplan", "sankt eriksplan"},
+		{"T-Centralen", "t centralen"},
+		{"TC", "t centralen"},
+		{"Sthlm  City", "stockholm city"},
+		{"  Göteborg  ", "goteborg"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestScoreTiers(t *testing.T) {
+	tests := []struct {
+		query, candidate string
+		tier             int
+		ok               bool
+	}{
+		{"odenplan", "Odenplan", TierExact, true},
+		{"t centralen", "T-Centralen", TierExact, true},
+		{"Oden", "Odenplan", TierPrefix, true},
+		{"plan", "Odenplan", TierContains, true},
+		{"Odenpaln", "Odenplan", TierFuzzy, true},
+		{"Spnga", "Spånga station", TierFuzzy, true},
+		{"Kisa", "Kista", TierFuzzy, true},
+		{"Kis", "Kista", TierPrefix, true},
+		{"Kix", "Kista", 0, false}, // too short for typos
+		{"Slussen", "Odenplan", 0, false},
+	}
+	for _, tt := range tests {
+		score, ok := Score(tt.query, tt.candidate)
+		if ok != tt.ok || (ok && Tier(score) != tt.tier) {
+			t.Errorf("Score(%q, %q) = tier %d, %v; want tier %d, %v", tt.query, tt.candidate, Tier(score), ok, tt.tier, tt.ok)
+		}
+	}
+}
+
+func TestScoreRanksBetterMatchesFirst(t *testing.T) {
+	m := New("Solna")
+	exact, _ := m.Score("Solna")
+	prefix, _ := m.Score("Solna centrum")
+	contains, _ := m.Score("Norra Solna")
+	if !(exact < prefix && prefix < contains) {
+		t.Errorf("scores exact %d, prefix %d, contains %d are not in order", exact, prefix, contains)
+	}
+}
+
+func TestBestKey(t *testing.T) {
+	cities := map[string]int{"göteborg": 1, "goteborg": 1, "kista": 2, "stockholm": 3, "lund": 4}
+	tests := []struct {
+		query  string
+		want   string
+		wantOK bool
+	}{
+		{"Göteborg", "goteborg", true}, // ties go to the first key in order
+		{"GÖTEBORG", "goteborg", true},
+		{"göte borg", "goteborg", true},
+		{"Sthlm", "stockholm", true},
+		{"Kisa", "", false}, // a typo is not picked
+		{"Stock", "", false},
+		{"Lun", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := BestKey(tt.query, cities)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("BestKey(%q) = %q, %v; want %q, %v", tt.query, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	cities := map[string]int{"göteborg": 1, "goteborg": 1, "kista": 2, "kiruna": 3, "stockholm": 4, "lund": 5}
+	tests := []struct {
+		query string
+		n     int
+		want  []string
+	}{
+		{"Kisa", 3, []string{"kista"}},
+		{"Gotebrg", 3, []string{"göteborg"}}, // listed once, accented
+		{"Ki", 3, nil},                       // too short for anything but exact matches
+		{"Lund", 3, []string{"lund"}},
+		{"Stock", 3, []string{"stockholm"}},
+		{"Kista", 0, nil},
+		{"Malmö", 3, nil},
+	}
+	for _, tt := range tests {
+		if got := Suggest(tt.query, cities, tt.n); !slices.Equal(got, tt.want) {
+			t.Errorf("Suggest(%q, %d) = %q, want %q", tt.query, tt.n, got, tt.want)
+		}
+	}
+}
@@ -20,6 +20,13 @@ type Response struct {
 	ID      json.RawMessage `json:"id"`
 }
 
+// CancelledParams are the params of a notifications/cancelled notification,
+// sent by the client when it no longer wants the result of a request.
+type CancelledParams struct {
+	RequestID json.RawMessage `json:"requestId"`
+	Reason    string          `json:"reason,omitempty"`
+}
+
 // RPCError is a JSON-RPC 2.0 error object.
 type RPCError struct {
 	Code    int    `json:"code"`
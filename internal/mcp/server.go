@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"sync"
 )
 
 // Server is an MCP server that communicates over stdio (newline-delimited JSON-RPC).
@@ -14,6 +15,11 @@ type Server struct {
 	info     ServerInfo
 	registry *ToolRegistry
 	logger   *slog.Logger
+
+	writeMu sync.Mutex // serializes responses from concurrent tool calls
+
+	mu       sync.Mutex
+	inFlight map[string]context.CancelFunc // request ID → cancel, for tool calls
 }
 
 // NewServer creates a new MCP server.
@@ -22,14 +28,21 @@ func NewServer(name, version string, registry *ToolRegistry, logger *slog.Logger
 		info:     ServerInfo{Name: name, Version: version},
 		registry: registry,
 		logger:   logger,
+		inFlight: make(map[string]context.CancelFunc),
 	}
 }
 
 // Serve reads JSON-RPC requests from reader and writes responses to writer.
+// Tool calls run concurrently and are cancelled when ctx is done or the
+// client sends notifications/cancelled for them. Serve returns once reader
+// is exhausted and every tool call has finished.
 func (s *Server) Serve(ctx context.Context, reader io.Reader, writer io.Writer) error {
 	scanner := bufio.NewScanner(reader)
 	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
 
+	var calls sync.WaitGroup
+	defer calls.Wait()
+
 	for scanner.Scan() {
 		line := scanner.Bytes()
 		if len(line) == 0 {
@@ -43,8 +56,18 @@ func (s *Server) Serve(ctx context.Context, reader io.Reader, writer io.Writer)
 			continue
 		}
 
-		resp := s.handleRequest(ctx, req)
-		s.writeResponse(writer, resp)
+		switch req.Method {
+		case "tools/call":
+			calls.Add(1)
+			go func() {
+				defer calls.Done()
+				s.runToolCall(ctx, req, writer)
+			}()
+		case "notifications/cancelled":
+			s.handleCancelled(req)
+		default:
+			s.writeResponse(writer, s.handleRequest(ctx, req))
+		}
 	}
 
 	return scanner.Err()
@@ -60,8 +83,6 @@ func (s *Server) handleRequest(ctx context.Context, req Request) Response {
 		return NewResponse(req.ID, map[string]string{})
 	case "tools/list":
 		return s.handleToolsList(req)
-	case "tools/call":
-		return s.handleToolsCall(ctx, req)
 	default:
 		return NewErrorResponse(req.ID, ErrCodeMethodNotFound, "method not found: "+req.Method)
 	}
@@ -99,6 +120,49 @@ func (s *Server) handleToolsCall(ctx context.Context, req Request) Response {
 	return NewResponse(req.ID, result)
 }
 
+// runToolCall handles a tool call that the client can cancel. A cancelled
+// call gets no response, as the client has stopped waiting for one.
+func (s *Server) runToolCall(ctx context.Context, req Request, writer io.Writer) {
+	s.logger.Info("handling request", "method", req.Method)
+
+	ctx, cancel := context.WithCancel(ctx)
+	id := string(req.ID)
+	s.mu.Lock()
+	s.inFlight[id] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.inFlight, id)
+		s.mu.Unlock()
+		cancel()
+	}()
+
+	resp := s.handleToolsCall(ctx, req)
+	if ctx.Err() != nil {
+		s.logger.Info("tool call cancelled", "id", id)
+		return
+	}
+	s.writeResponse(writer, resp)
+}
+
+// handleCancelled cancels the tool call named by a notifications/cancelled
+// notification. Unknown or finished requests are ignored.
+func (s *Server) handleCancelled(req Request) {
+	var params CancelledParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		s.logger.Warn("invalid cancellation", "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	cancel, ok := s.inFlight[string(params.RequestID)]
+	s.mu.Unlock()
+	if ok {
+		s.logger.Info("cancelling request", "id", string(params.RequestID), "reason", params.Reason)
+		cancel()
+	}
+}
+
 func (s *Server) writeResponse(w io.Writer, resp Response) {
 	data, err := json.Marshal(resp)
 	if err != nil {
@@ -106,5 +170,8 @@ func (s *Server) writeResponse(w io.Writer, resp Response) {
 		return
 	}
 	data = append(data, '\n')
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 	w.Write(data)
 }
@@ -2,15 +2,22 @@ package output
 
 import (
 	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 
 	"transport/internal/api"
+	"transport/internal/errs"
+	"transport/internal/geo"
+	"transport/internal/planner"
+	"transport/internal/rank"
+	"transport/internal/resrobot"
 	"transport/internal/tz"
 )
 
 // JSONOutput represents the standardized JSON output format
 type JSONOutput struct {
-	Type      string      `json:"type"`       // trip, departures, car, flight, taxi, bus
+	Type      string      `json:"type"` // trip, departures, car, flight, taxi, bus
 	Timestamp string      `json:"timestamp"`
 	Origin    string      `json:"origin,omitempty"`
 	Dest      string      `json:"destination,omitempty"`
@@ -19,30 +26,80 @@ type JSONOutput struct {
 
 // TripResult represents trip planning results
 type TripResult struct {
-	Trips []Trip `json:"trips"`
+	Planner string   `json:"planner"` // SL or ResRobot
+	From    *Place   `json:"from,omitempty"`
+	To      *Place   `json:"to,omitempty"`
+	Via     []*Place `json:"via,omitempty"`
+	Trips   []Trip   `json:"trips"`
+	Dropped int      `json:"dropped,omitempty"` // trips left out for a change shorter than the minimum transfer time
+	Earlier string   `json:"earlier,omitempty"` // page token for earlier trips (--page)
+	Later   string   `json:"later,omitempty"`   // page token for later trips (--page)
+}
+
+// Place describes what a trip endpoint was resolved to
+type Place struct {
+	Name string  `json:"name"`
+	Kind string  `json:"kind"` // stop, address, poi, coord, area
+	Lat  float64 `json:"lat,omitempty"`
+	Lon  float64 `json:"lon,omitempty"`
 }
 
 // Trip represents a single trip option
 type Trip struct {
-	Departure       string `json:"departure"`        // HH:MM
-	Arrival         string `json:"arrival"`          // HH:MM
-	DurationMinutes int    `json:"duration_minutes"`
-	Changes         int    `json:"changes"`
-	Legs            []Leg  `json:"legs"`
-	GoogleMapsURL   string `json:"google_maps_url,omitempty"`
+	Departure               string `json:"departure"` // HH:MM
+	Arrival                 string `json:"arrival"`   // HH:MM
+	DurationMinutes         int    `json:"duration_minutes"`
+	RealtimeDurationMinutes int    `json:"realtime_duration_minutes,omitempty"` // only if different from planned
+	Changes                 int    `json:"changes"`
+	Score                   int    `json:"score"` // generalised travel time in minutes, lower is better
+	Cancelled               bool   `json:"cancelled,omitempty"`
+	Status                  string `json:"status,omitempty"` // ResRobot: partially_cancelled, etc.; omitted when normal
+	Legs                    []Leg  `json:"legs"`
+	GoogleMapsURL           string `json:"google_maps_url,omitempty"`
+
+	Transfers        []Transfer `json:"transfers,omitempty"`
+	SaferAlternative int        `json:"safer_alternative,omitempty"` // 1-based index of a trip without risky changes
+}
+
+// Transfer describes a change between two rides
+type Transfer struct {
+	Stop         string `json:"stop"`
+	SlackMinutes int    `json:"slack_minutes"` // time to spare after walking, from real-time data
+	WalkMinutes  int    `json:"walk_minutes,omitempty"`
+	WalkMeters   int    `json:"walk_meters,omitempty"`
+	AtRisk       bool   `json:"at_risk,omitempty"`
 }
 
 // Leg represents one segment of a trip
 type Leg struct {
-	Mode      string   `json:"mode"`       // metro, bus, train, tram, ship, walk
-	Line      string   `json:"line,omitempty"`
-	Direction string   `json:"direction,omitempty"`
-	From      StopInfo `json:"from"`
-	To        StopInfo `json:"to"`
-	Departure string   `json:"departure"`
-	Arrival   string   `json:"arrival"`
-	Duration  int      `json:"duration_minutes"`
-	Coords    []Coord  `json:"coords,omitempty"`
+	Mode              string      `json:"mode"` // metro, bus, train, tram, ship, walk
+	Line              string      `json:"line,omitempty"`
+	Direction         string      `json:"direction,omitempty"`
+	Operator          string      `json:"operator,omitempty"`
+	From              StopInfo    `json:"from"`
+	To                StopInfo    `json:"to"`
+	Departure         string      `json:"departure"`
+	Arrival           string      `json:"arrival"`
+	DepartureExpected string      `json:"departure_expected,omitempty"` // real-time, only if different from planned
+	ArrivalExpected   string      `json:"arrival_expected,omitempty"`
+	DepartureDelay    int         `json:"departure_delay_minutes,omitempty"`
+	ArrivalDelay      int         `json:"arrival_delay_minutes,omitempty"`
+	Cancelled         bool        `json:"cancelled,omitempty"`
+	Duration          int         `json:"duration_minutes"`
+	Coords            []Coord     `json:"coords,omitempty"` // the path of the leg, as in --export
+	Deviations        []Deviation `json:"deviations,omitempty"`
+	Stops             []StopTime  `json:"stops,omitempty"` // intermediate stops, with --stops
+
+	ReservationRequired bool        `json:"reservation_required,omitempty"` // a seat must be booked (ResRobot)
+	Attributes          []Attribute `json:"attributes,omitempty"`           // facilities and ticket rules (ResRobot)
+}
+
+// Attribute describes the vehicle or the ticket of a leg, e.g. that bikes
+// are allowed
+type Attribute struct {
+	Kind string `json:"kind"` // reservation_required, bike_allowed, wheelchair or attribute
+	Code string `json:"code,omitempty"`
+	Text string `json:"text"`
 }
 
 // StopInfo represents a stop/station
@@ -51,30 +108,60 @@ type StopInfo struct {
 	Platform string  `json:"platform,omitempty"`
 	Lat      float64 `json:"lat,omitempty"`
 	Lon      float64 `json:"lon,omitempty"`
+	Via      bool    `json:"via,omitempty"` // the trip passes a requested via stop here
+}
+
+// StopTime is a stop a leg passes between its origin and destination
+type StopTime struct {
+	Name              string  `json:"name"`
+	Lat               float64 `json:"lat,omitempty"`
+	Lon               float64 `json:"lon,omitempty"`
+	Arrival           string  `json:"arrival,omitempty"`
+	Departure         string  `json:"departure,omitempty"`
+	ArrivalExpected   string  `json:"arrival_expected,omitempty"` // real-time, only if different from planned
+	DepartureExpected string  `json:"departure_expected,omitempty"`
 }
 
 // Coord represents a coordinate point
-type Coord struct {
-	Lat float64 `json:"lat"`
-	Lon float64 `json:"lon"`
+type Coord = geo.Point
+
+// Deviation represents a service disruption or notice
+type Deviation struct {
+	Importance  int    `json:"importance"` // higher is more important
+	Consequence string `json:"consequence,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Message     string `json:"message"`
+	URL         string `json:"url,omitempty"`
 }
 
 // DeparturesResult represents departure board results
 type DeparturesResult struct {
-	StopName   string      `json:"stop_name"`
-	Departures []Departure `json:"departures"`
+	StopName       string      `json:"stop_name"`
+	Stops          []string    `json:"stops,omitempty"` // merged boards only
+	StopDeviations []Deviation `json:"stop_deviations,omitempty"`
+	Warnings       []string    `json:"warnings,omitempty"` // stops of a merged board whose departures are missing
+	Departures     []Departure `json:"departures"`
 }
 
 // Departure represents a single departure
 type Departure struct {
-	Line        string `json:"line"`
-	Destination string `json:"destination"`
-	Departure   string `json:"departure"`      // HH:MM
-	Expected    string `json:"expected"`       // HH:MM (real-time)
-	MinutesAway int    `json:"minutes_away"`
-	Platform    string `json:"platform,omitempty"`
-	Mode        string `json:"mode"`           // bus, metro, train, etc.
-	Delayed     bool   `json:"delayed"`
+	Line         string      `json:"line"`
+	Destination  string      `json:"destination,omitempty"`
+	Origin       string      `json:"origin,omitempty"` // arrival boards only
+	Departure    string      `json:"departure"`        // HH:MM, the arrival time on arrival boards
+	Expected     string      `json:"expected"`         // HH:MM (real-time)
+	MinutesAway  int         `json:"minutes_away"`
+	Stop         string      `json:"stop,omitempty"` // merged boards only
+	Platform     string      `json:"platform,omitempty"`
+	WalkMinutes  int         `json:"walk_minutes,omitempty"`
+	LeaveIn      int         `json:"leave_in_minutes,omitempty"` // minutes_away minus walk_minutes
+	Mode         string      `json:"mode"`                       // bus, metro, train, etc.
+	Delayed      bool        `json:"delayed"`
+	DelayMinutes int         `json:"delay_minutes,omitempty"`
+	Cancelled    bool        `json:"cancelled,omitempty"`
+	Realtime     bool        `json:"realtime"` // false if the time is from the timetable only
+	Deviations   []Deviation `json:"deviations,omitempty"`
+	Changes      []string    `json:"changes,omitempty"` // watch mode: new, delayed, cancelled, platform
 }
 
 // CarResult represents car journey results
@@ -140,6 +227,24 @@ type BusRoute struct {
 	BookingURL string `json:"booking_url"`
 }
 
+// NearbyStopsResult represents the stops around a place
+type NearbyStopsResult struct {
+	Place   string       `json:"place"`
+	Coord   Coord        `json:"coord"`
+	RadiusM int          `json:"radius_m"`
+	Stops   []NearbyStop `json:"stops"`
+}
+
+// NearbyStop represents one stop near a place
+type NearbyStop struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Municipality string   `json:"municipality,omitempty"`
+	Coord        Coord    `json:"coord"`
+	DistanceM    int      `json:"distance_m"`
+	Products     []string `json:"products,omitempty"` // product classes, e.g. regional_train
+}
+
 // NewOutput creates a new JSON output wrapper
 func NewOutput(outputType, origin, dest string) *JSONOutput {
 	return &JSONOutput{
@@ -159,45 +264,56 @@ func (o *JSONOutput) Marshal() (string, error) {
 	return string(data), nil
 }
 
-// FormatJourneysJSON converts API journeys to JSON format
-func FormatJourneysJSON(origin, dest string, journeys []api.Journey, mapsURLGen func(api.Journey) string) string {
-	output := NewOutput("trip", origin, dest)
+// FormatTripsJSON converts a page of trips to JSON format, whichever
+// planner found them
+func FormatTripsJSON(page *planner.Page) string {
+	output := NewOutput("trip", page.Origin.Name, page.Destination.Name)
 
-	trips := make([]Trip, 0, len(journeys))
-	for _, j := range journeys {
+	alternatives := planner.SaferAlternatives(page.Trips)
+	trips := make([]Trip, 0, len(page.Trips))
+	for n, t := range page.Trips {
 		trip := Trip{
-			DurationMinutes: j.TripDuration / 60,
-			Changes:         j.Interchanges,
-			Legs:            make([]Leg, 0, len(j.Legs)),
+			DurationMinutes: int(t.Duration.Minutes()),
+			Changes:         t.Changes,
+			Score:           t.Score(),
+			Cancelled:       t.Cancelled,
+			Status:          t.Status,
+			Legs:            make([]Leg, 0, len(t.Legs)),
+			GoogleMapsURL:   t.MapsURL(),
+			Transfers:       newTransfers(t.Transfers()),
 		}
-
-		if mapsURLGen != nil {
-			trip.GoogleMapsURL = mapsURLGen(j)
+		if alt := alternatives[n]; alt >= 0 {
+			trip.SaferAlternative = alt + 1
+		}
+		if rt := int(t.RealtimeDuration.Minutes()); rt != trip.DurationMinutes {
+			trip.RealtimeDurationMinutes = rt
 		}
 
-		for i, l := range j.Legs {
+		for i, l := range t.Legs {
 			leg := Leg{
-				From: StopInfo{
-					Name:     l.Origin.GetStopName(),
-					Platform: l.Origin.GetPlatform(),
-				},
-				To: StopInfo{
-					Name:     l.Destination.GetStopName(),
-					Platform: l.Destination.GetPlatform(),
-				},
-				Departure: extractTime(l.Origin.DepartureTimePlanned),
-				Arrival:   extractTime(l.Destination.ArrivalTimePlanned),
-				Duration:  l.Duration / 60,
+				Mode:      l.Mode,
+				Line:      l.Line,
+				Direction: l.Direction,
+				Operator:  l.Operator,
+				From:      newStopInfo(l.From),
+				To:        newStopInfo(l.To),
+				Departure: formatClock(l.Departure),
+				Arrival:   formatClock(l.Arrival),
+				Duration:  int(l.Duration.Minutes()),
+				Cancelled: l.Cancelled,
+				Coords:    l.Geometry,
+
+				ReservationRequired: l.ReservationRequired,
 			}
 
-			// Add coordinates if available
-			if len(l.Origin.Coord) >= 2 {
-				leg.From.Lat = l.Origin.Coord[0]
-				leg.From.Lon = l.Origin.Coord[1]
+			// Real-time estimates
+			if delay := int(l.DepartureDelay().Round(time.Minute).Minutes()); delay != 0 {
+				leg.DepartureExpected = formatClock(l.ExpectedDeparture)
+				leg.DepartureDelay = delay
 			}
-			if len(l.Destination.Coord) >= 2 {
-				leg.To.Lat = l.Destination.Coord[0]
-				leg.To.Lon = l.Destination.Coord[1]
+			if delay := int(l.ArrivalDelay().Round(time.Minute).Minutes()); delay != 0 {
+				leg.ArrivalExpected = formatClock(l.ExpectedArrival)
+				leg.ArrivalDelay = delay
 			}
 
 			// Set first leg departure as trip departure
@@ -205,17 +321,37 @@ func FormatJourneysJSON(origin, dest string, journeys []api.Journey, mapsURLGen
 				trip.Departure = leg.Departure
 			}
 			// Set last leg arrival as trip arrival
-			if i == len(j.Legs)-1 {
+			if i == len(t.Legs)-1 {
 				trip.Arrival = leg.Arrival
 			}
 
-			// Determine mode and line
-			if l.Transportation != nil && !l.Transportation.IsWalking() {
-				leg.Line = l.Transportation.GetLineName()
-				leg.Direction = l.Transportation.GetDirection()
-				leg.Mode = getModeString(l.Transportation)
-			} else {
-				leg.Mode = "walk"
+			for _, s := range l.Stops {
+				stop := StopTime{
+					Name:      s.Name,
+					Lat:       s.Coord.Lat,
+					Lon:       s.Coord.Lon,
+					Arrival:   formatClock(s.Arrival),
+					Departure: formatClock(s.Departure),
+				}
+				if s.ArrivalDelay().Round(time.Minute) != 0 {
+					stop.ArrivalExpected = formatClock(s.ExpectedArrival)
+				}
+				if s.DepartureDelay().Round(time.Minute) != 0 {
+					stop.DepartureExpected = formatClock(s.ExpectedDeparture)
+				}
+				leg.Stops = append(leg.Stops, stop)
+			}
+
+			for _, notice := range l.Notices {
+				leg.Deviations = append(leg.Deviations, Deviation{
+					Importance: notice.Importance,
+					Title:      notice.Title,
+					Message:    notice.Message,
+					URL:        notice.URL,
+				})
+			}
+			for _, attr := range l.Attributes {
+				leg.Attributes = append(leg.Attributes, Attribute{Kind: attr.Kind, Code: attr.Code, Text: attr.Text})
 			}
 
 			trip.Legs = append(trip.Legs, leg)
@@ -224,20 +360,177 @@ func FormatJourneysJSON(origin, dest string, journeys []api.Journey, mapsURLGen
 		trips = append(trips, trip)
 	}
 
-	output.Data = TripResult{Trips: trips}
+	tripResult := TripResult{
+		Planner: page.Planner,
+		From:    newPlace(page.Origin),
+		To:      newPlace(page.Destination),
+		Trips:   trips,
+		Dropped: page.Dropped,
+		Earlier: page.Earlier,
+		Later:   page.Later,
+	}
+	for _, via := range page.Via {
+		tripResult.Via = append(tripResult.Via, newPlace(via))
+	}
+	output.Data = tripResult
+
+	result, _ := output.Marshal()
+	return result
+}
+
+// newTransfers converts transfers to their JSON form
+func newTransfers(transfers []rank.Transfer) []Transfer {
+	var result []Transfer
+	for _, t := range transfers {
+		result = append(result, Transfer{
+			Stop:         t.Stop,
+			SlackMinutes: int(t.Slack().Minutes()),
+			WalkMinutes:  int(t.Walk.Minutes()),
+			WalkMeters:   t.WalkMeters,
+			AtRisk:       t.AtRisk(),
+		})
+	}
+	return result
+}
+
+// newPlace converts a resolved place to its JSON form
+func newPlace(place planner.Place) *Place {
+	return &Place{
+		Name: place.Name,
+		Kind: place.Kind,
+		Lat:  place.Coord.Lat,
+		Lon:  place.Coord.Lon,
+	}
+}
+
+// newStopInfo converts the end of a leg to its JSON form
+func newStopInfo(stop planner.Stop) StopInfo {
+	return StopInfo{
+		Name:     stop.Name,
+		Platform: stop.Platform,
+		Lat:      stop.Coord.Lat,
+		Lon:      stop.Coord.Lon,
+		Via:      stop.Via,
+	}
+}
+
+// ErrorResult represents a failed request that scripts can act on
+type ErrorResult struct {
+	Code       string      `json:"code"`
+	Message    string      `json:"message"`
+	Query      string      `json:"query,omitempty"`
+	Role       string      `json:"role,omitempty"` // origin or destination
+	Candidates []Candidate `json:"candidates,omitempty"`
+	RetryAfter int         `json:"retry_after_seconds,omitempty"` // when rate limited
+}
+
+// Candidate represents one possible match for an ambiguous location
+type Candidate struct {
+	Index        int      `json:"index"` // value to pass to --select
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Kind         string   `json:"kind"`
+	Municipality string   `json:"municipality,omitempty"`
+	Products     []string `json:"products,omitempty"`
+}
+
+// FormatAmbiguityJSON converts an ambiguous SL location to JSON format
+func FormatAmbiguityJSON(err *api.AmbiguousLocationError) string {
+	output := NewOutput("error", "", "")
+
+	candidates := make([]Candidate, 0, len(err.Candidates))
+	for i, loc := range err.Candidates {
+		candidate := Candidate{
+			Index:        i + 1,
+			ID:           loc.ID,
+			Name:         loc.Name,
+			Kind:         loc.Kind(),
+			Municipality: loc.Municipality(),
+		}
+		for _, class := range loc.ProductClasses {
+			candidate.Products = append(candidate.Products, getClassModeString(class))
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	output.Data = ErrorResult{
+		Code:       string(errs.CodeAmbiguous),
+		Message:    err.Error(),
+		Query:      err.Query,
+		Role:       err.Role,
+		Candidates: candidates,
+	}
+
+	result, _ := output.Marshal()
+	return result
+}
+
+// FormatErrorJSON converts an error to JSON format, with a code from
+// errs.Classify. Ambiguous locations list their candidates.
+func FormatErrorJSON(err error) string {
+	var slAmbiguous *api.AmbiguousLocationError
+	if errors.As(err, &slAmbiguous) {
+		return FormatAmbiguityJSON(slAmbiguous)
+	}
+	var rrAmbiguous *resrobot.AmbiguousStopError
+	if errors.As(err, &rrAmbiguous) {
+		return FormatResRobotAmbiguityJSON(rrAmbiguous)
+	}
+
+	errResult := ErrorResult{
+		Code:    string(errs.Classify(err)),
+		Message: err.Error(),
+	}
+	var notFound *errs.NotFoundError
+	if errors.As(err, &notFound) {
+		errResult.Query = notFound.Query
+		errResult.Role = notFound.Role
+	}
+	var upstream *errs.UpstreamError
+	if errors.As(err, &upstream) {
+		errResult.RetryAfter = int(upstream.RetryAfter.Seconds())
+	}
+
+	output := NewOutput("error", "", "")
+	output.Data = errResult
 
 	result, _ := output.Marshal()
 	return result
 }
 
 // FormatDeparturesJSON converts departures to JSON format
-func FormatDeparturesJSON(siteName string, departures []api.Departure) string {
-	output := NewOutput("departures", siteName, "")
+func FormatDeparturesJSON(board *api.DepartureBoard) string {
+	output := NewOutput(boardType(board), strings.Join(board.SiteNames(), " + "), "")
+	output.Data = newDeparturesResult(board, nil)
+
+	result, _ := output.Marshal()
+	return result
+}
+
+// FormatDeparturesFrameJSON converts a live board refresh to a single line
+// of JSON, for NDJSON streams. changes are tagged on each departure.
+func FormatDeparturesFrameJSON(board *api.DepartureBoard, changes map[string]api.DepartureChange) string {
+	output := NewOutput(boardType(board), strings.Join(board.SiteNames(), " + "), "")
+	output.Data = newDeparturesResult(board, changes)
 
-	deps := make([]Departure, 0, len(departures))
+	data, _ := json.Marshal(output)
+	return string(data)
+}
+
+// boardType returns the output type of a departure or arrival board
+func boardType(board *api.DepartureBoard) string {
+	if board.Arrivals {
+		return "arrivals"
+	}
+	return "departures"
+}
+
+// newDeparturesResult converts a departure board to its JSON representation
+func newDeparturesResult(board *api.DepartureBoard, changes map[string]api.DepartureChange) DeparturesResult {
+	deps := make([]Departure, 0, len(board.Departures))
 	now := tz.Now()
 
-	for _, d := range departures {
+	for _, d := range board.Departures {
 		dep := Departure{
 			Line:        d.Line.Designation,
 			Destination: d.Destination,
@@ -246,12 +539,13 @@ func FormatDeparturesJSON(siteName string, departures []api.Departure) string {
 			Platform:    d.StopPoint.Designation,
 			Mode:        d.Line.TransportMode,
 		}
+		if board.Arrivals {
+			dep.Origin, dep.Destination = d.Destination, ""
+		}
 
 		// Calculate minutes away
-		if expectedTime, err := tz.ParseStockholm("15:04:05", d.Expected); err == nil {
-			expectedToday := time.Date(now.Year(), now.Month(), now.Day(),
-				expectedTime.Hour(), expectedTime.Minute(), expectedTime.Second(), 0, tz.Stockholm)
-			dep.MinutesAway = int(expectedToday.Sub(now).Minutes())
+		if expectedTime, err := tz.ParseStockholm("2006-01-02T15:04:05", d.Expected); err == nil {
+			dep.MinutesAway = int(expectedTime.Sub(now).Minutes())
 			if dep.MinutesAway < 0 {
 				dep.MinutesAway = 0
 			}
@@ -259,34 +553,69 @@ func FormatDeparturesJSON(siteName string, departures []api.Departure) string {
 
 		// Check if delayed
 		dep.Delayed = d.Scheduled != d.Expected
+		dep.DelayMinutes = d.DelayMinutes()
+		dep.Cancelled = d.IsCancelled()
+		dep.Realtime = d.IsRealtime()
+		dep.Changes = changes[d.Key()].Names()
+		dep.WalkMinutes = d.WalkMinutes
+		if d.WalkMinutes > 0 {
+			dep.LeaveIn = max(0, dep.MinutesAway-d.WalkMinutes)
+		}
+		if board.IsMerged() {
+			dep.Stop = d.StopArea.Name
+		}
+
+		for _, dev := range d.SortedDeviations() {
+			dep.Deviations = append(dep.Deviations, Deviation{
+				Importance:  dev.Importance,
+				Consequence: dev.Consequence,
+				Message:     dev.Message,
+			})
+		}
 
 		deps = append(deps, dep)
 	}
 
-	output.Data = DeparturesResult{
-		StopName:   siteName,
-		Departures: deps,
+	var stopDeviations []Deviation
+	for _, dev := range board.SortedStopDeviations() {
+		stopDeviations = append(stopDeviations, Deviation{
+			Importance:  dev.Importance,
+			Consequence: dev.Consequence,
+			Message:     dev.Message,
+		})
 	}
 
-	result, _ := output.Marshal()
-	return result
-}
+	var stops []string
+	if board.IsMerged() {
+		stops = board.SiteNames()
+	}
 
-// Helper to extract time from ISO format
-func extractTime(isoTime string) string {
-	if len(isoTime) < 16 {
-		return isoTime
+	var warnings []string
+	for _, w := range board.Warnings {
+		warnings = append(warnings, w.String())
+	}
+
+	return DeparturesResult{
+		StopName:       strings.Join(board.SiteNames(), " + "),
+		Stops:          stops,
+		StopDeviations: stopDeviations,
+		Warnings:       warnings,
+		Departures:     deps,
 	}
-	// Format: 2024-01-20T14:35:00
-	return isoTime[11:16]
 }
 
-// Helper to get mode string from transportation
-func getModeString(t *api.Transportation) string {
-	if t == nil || t.Product == nil {
-		return "unknown"
+// formatClock returns a time as HH:MM in Stockholm time, or "" if it is
+// unknown
+func formatClock(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
-	switch t.Product.Class {
+	return t.In(tz.Stockholm).Format("15:04")
+}
+
+// Helper to get mode string from an SL product class
+func getClassModeString(class int) string {
+	switch class {
 	case api.ProductClassMetro:
 		return "metro"
 	case api.ProductClassBus:
@@ -6,6 +6,7 @@ import (
 
 	"transport/internal/bus"
 	"transport/internal/car"
+	"transport/internal/errs"
 	"transport/internal/flight"
 	"transport/internal/resrobot"
 	"transport/internal/taxi"
@@ -132,69 +133,56 @@ func FormatBusJSON(search bus.BusSearch) string {
 	return result
 }
 
-// FormatResRobotJSON converts ResRobot trip results to JSON format
-func FormatResRobotJSON(origin, dest string, trips []resrobot.ParsedTrip) string {
-	output := NewOutput("trip", origin, dest)
-
-	tripResults := make([]Trip, 0, len(trips))
-	for _, t := range trips {
-		trip := Trip{
-			DurationMinutes: int(t.Duration.Minutes()),
-			Changes:         t.Interchanges,
-			Legs:            make([]Leg, 0, len(t.Legs)),
-		}
-
-		for i, l := range t.Legs {
-			mode := "unknown"
-			switch l.Category {
-			case resrobot.CatMetro:
-				mode = "metro"
-			case resrobot.CatBus, resrobot.CatNattbus:
-				mode = "bus"
-			case resrobot.CatPendeltag, resrobot.CatRegionaltag, resrobot.CatSJ, resrobot.CatSnabbtag, resrobot.CatNorrtag:
-				mode = "train"
-			case resrobot.CatSparvagn:
-				mode = "tram"
-			case resrobot.CatFerry:
-				mode = "ship"
-			}
-			if l.IsWalk {
-				mode = "walk"
-			}
-
-			leg := Leg{
-				Mode:      mode,
-				Line:      l.Line,
-				Direction: l.Direction,
-				From: StopInfo{
-					Name:     l.Origin,
-					Platform: l.OriginTrack,
-				},
-				To: StopInfo{
-					Name:     l.Destination,
-					Platform: l.DestTrack,
-				},
-				Departure: l.DepartureTime.Format("15:04"),
-				Arrival:   l.ArrivalTime.Format("15:04"),
-				Duration:  int(l.ArrivalTime.Sub(l.DepartureTime).Minutes()),
-			}
-
-			// Set first leg departure as trip departure
-			if i == 0 {
-				trip.Departure = leg.Departure
-			}
-			// Set last leg arrival as trip arrival
-			if i == len(t.Legs)-1 {
-				trip.Arrival = leg.Arrival
-			}
-
-			trip.Legs = append(trip.Legs, leg)
-		}
-
-		tripResults = append(tripResults, trip)
+// FormatResRobotAmbiguityJSON converts an ambiguous ResRobot stop to JSON format
+func FormatResRobotAmbiguityJSON(err *resrobot.AmbiguousStopError) string {
+	output := NewOutput("error", "", "")
+
+	candidates := make([]Candidate, 0, len(err.Candidates))
+	for i, stop := range err.Candidates {
+		candidates = append(candidates, Candidate{
+			Index:        i + 1,
+			ID:           stop.ID,
+			Name:         stop.DisplayName(),
+			Kind:         "stop",
+			Municipality: stop.Municipality(),
+			Products:     stop.Products.Modes(),
+		})
+	}
+
+	output.Data = ErrorResult{
+		Code:       string(errs.CodeAmbiguous),
+		Message:    err.Error(),
+		Query:      err.Query,
+		Role:       err.Role,
+		Candidates: candidates,
+	}
+
+	result, _ := output.Marshal()
+	return result
+}
+
+// FormatNearbyStopsJSON converts the stops around a place to JSON format
+func FormatNearbyStopsJSON(place string, lat, lon float64, radius int, stops []resrobot.StopLocationData) string {
+	output := NewOutput("nearby_stops", place, "")
+
+	nearby := NearbyStopsResult{
+		Place:   place,
+		Coord:   Coord{Lat: lat, Lon: lon},
+		RadiusM: radius,
+		Stops:   make([]NearbyStop, 0, len(stops)),
+	}
+	for _, stop := range stops {
+		nearby.Stops = append(nearby.Stops, NearbyStop{
+			ID:           stop.ID,
+			Name:         stop.DisplayName(),
+			Municipality: stop.Municipality(),
+			Coord:        Coord{Lat: stop.Lat, Lon: stop.Lon},
+			DistanceM:    stop.Dist,
+			Products:     stop.Products.IDs(),
+		})
 	}
+	output.Data = nearby
 
-	output.Data = TripResult{Trips: tripResults}
 	result, _ := output.Marshal()
 	return result
 }
@@ -0,0 +1,58 @@
+// Package pagetoken encodes the page tokens the trip planners hand out
+// for continuing a search before or after the trips they returned.
+//
+// A token is a client's prefix, such as "sl.", followed by the URL-safe
+// base64 of "<search>@<kind>@<value>". The search is a hash of the query
+// the token was made for, so that a token is only accepted for the same
+// trip and options; kind says what the value is, e.g. a time to continue
+// from or the planner's own scroll context.
+package pagetoken
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"transport/internal/errs"
+)
+
+// Search returns a short hash of the parts of a query that decide which
+// trips it finds, such as the stop IDs and the search options
+func Search(parts ...any) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%v\x1f", part)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Encode makes a token with prefix for a search from Search, holding a
+// value of the given kind
+func Encode(prefix, search, kind, value string) string {
+	payload := search + "@" + kind + "@" + value
+	return prefix + base64.RawURLEncoding.EncodeToString([]byte(payload))
+}
+
+// Decode returns the kind and value of a token made by Encode with the
+// same prefix. A token made for another search is rejected, as it would
+// continue a different list of trips.
+func Decode(token, prefix, search string) (kind, value string, err error) {
+	invalid := errs.Invalidf("invalid page token '%s'", token)
+	if !strings.HasPrefix(token, prefix) {
+		return "", "", invalid
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, prefix))
+	if err != nil {
+		return "", "", invalid
+	}
+	parts := strings.SplitN(string(data), "@", 3)
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return "", "", invalid
+	}
+	if parts[0] != search {
+		return "", "", errs.Invalidf("page token '%s' belongs to another search; repeat the search it came from", token)
+	}
+	return parts[1], parts[2], nil
+}
@@ -0,0 +1,78 @@
+package pagetoken
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"transport/internal/errs"
+)
+
+func TestSearch(t *testing.T) {
+	a := Search("9192", "9117", 0, []string{"metro"})
+	if b := Search("9192", "9117", 0, []string{"metro"}); a != b {
+		t.Errorf("the same search hashes to %q and %q", a, b)
+	}
+	others := [][]any{
+		{"9117", "9192", 0, []string{"metro"}},
+		{"9192", "9117", 5, []string{"metro"}},
+		{"9192", "9117", 0, []string{"bus"}},
+		{"919", "29117", 0, []string{"metro"}},
+	}
+	for _, parts := range others {
+		if b := Search(parts...); a == b {
+			t.Errorf("Search(%v) = %q, the same as another search", parts, b)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	search := Search("740000002", "740000001")
+	tests := []struct {
+		kind, value string
+	}{
+		{"later", "2026-03-02T08:12:00+01:00"},
+		{"ctx", "3|OF|MT µ14µ8565µ8565µ8763@0"}, // values may hold "@"
+	}
+	for _, tt := range tests {
+		token := Encode("rr.", search, tt.kind, tt.value)
+		if !strings.HasPrefix(token, "rr.") {
+			t.Errorf("Encode() = %q, want the rr. prefix", token)
+		}
+		kind, value, err := Decode(token, "rr.", search)
+		if err != nil || kind != tt.kind || value != tt.value {
+			t.Errorf("Decode(Encode(%q, %q)) = %q, %q, %v", tt.kind, tt.value, kind, value, err)
+		}
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	search := Search("9192", "9117")
+	valid := Encode("sl.", search, "later", "2026-03-02T08:12:00+01:00")
+	tests := []struct {
+		name   string
+		token  string
+		prefix string
+		search string
+	}{
+		{"another search", valid, "sl.", Search("9192", "9001")},
+		{"another client", valid, "rr.", search},
+		{"not base64", "sl.!!!", "sl.", search},
+		{"no kind", "sl." + encode(search+"@@x"), "sl.", search},
+		{"no value", "sl." + encode(search+"@later@"), "sl.", search},
+		{"no search", "sl." + encode("later@2026-03-02T08:12:00+01:00"), "sl.", search},
+		{"empty", "", "sl.", search},
+	}
+	for _, tt := range tests {
+		_, _, err := Decode(tt.token, tt.prefix, tt.search)
+		if !errors.Is(err, errs.ErrInvalidInput) {
+			t.Errorf("%s: Decode() error = %v, want invalid input", tt.name, err)
+		}
+	}
+}
+
+// encode makes a token body from a raw payload
+func encode(payload string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(payload))
+}
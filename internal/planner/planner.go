@@ -0,0 +1,82 @@
+// Package planner plans public transport trips with whichever journey
+// planner covers them.
+//
+// SL plans trips in Stockholm County and ResRobot in all of Sweden, and
+// each describes its trips in its own way. Both convert their trips to the
+// Trip model here, so that the command, the MCP tools and every output
+// format work the same for either. A Router picks the planner for each
+// search and falls back to the other one when it fails.
+package planner
+
+import (
+	"context"
+	"time"
+
+	"transport/internal/geo"
+)
+
+// Planner plans trips with one journey planner
+type Planner interface {
+	// Name returns the name of the journey planner, e.g. "SL"
+	Name() string
+
+	// Covers reports whether the planner has the full timetable for the
+	// place a query (a stop, address or "lat,lon") refers to
+	Covers(ctx context.Context, query string) (bool, error)
+
+	// MaxVias returns the number of via stops a trip can go through
+	MaxVias() int
+
+	// Plan finds trips. Places that can't be told apart are returned as
+	// errors matching errs.ErrAmbiguous.
+	Plan(ctx context.Context, req Request) (*Page, error)
+}
+
+// Request is a trip search, with places as the user wrote them
+type Request struct {
+	Origin      string
+	Destination string
+	Via         []string // stops to travel via, in order
+	ViaDwell    int      // minutes to stay at each via stop
+
+	Time       time.Time
+	ArriveBy   bool // Time is when to arrive, not when to leave
+	NumResults int
+
+	IntermediateStops bool // list the stops each leg passes
+}
+
+// Places returns every place of the request: origin, destination and vias
+func (r *Request) Places() []string {
+	return append([]string{r.Origin, r.Destination}, r.Via...)
+}
+
+// Page is a list of trips found by one planner, with tokens for
+// continuing before or after them
+type Page struct {
+	Planner     string // name of the planner that found the trips
+	Origin      Place
+	Destination Place
+	Via         []Place
+	Trips       []Trip
+	Dropped     int    // trips left out for a change shorter than asked for
+	Earlier     string // page token for trips before these, "" if unknown
+	Later       string // page token for trips after these, "" if unknown
+}
+
+// Place is what a trip endpoint or via stop was resolved to
+type Place struct {
+	Name  string
+	Kind  string // stop, address, poi, coord or area
+	Coord geo.Point
+}
+
+// Kinds of place. The SL planner also finds addresses and points of
+// interest; ResRobot only stops and coordinates.
+const (
+	KindStop    = "stop"
+	KindAddress = "address"
+	KindPOI     = "poi"
+	KindCoord   = "coord"
+	KindArea    = "area"
+)
@@ -0,0 +1,106 @@
+package planner
+
+import (
+	"context"
+	"errors"
+
+	"transport/internal/errs"
+)
+
+// Router plans each trip with the local planner when it covers every place
+// of the search, and with the nationwide planner otherwise. When the
+// planner it picks fails, it tries the other one.
+type Router struct {
+	Local      Planner // plans trips in part of the country, e.g. SL in Stockholm County
+	Nationwide Planner // plans trips in all of Sweden, e.g. ResRobot; nil if unavailable
+
+	// OnFallback, if set, is called when a planner fails and the search
+	// is tried again with the other one
+	OnFallback func(failed, next Planner, err error)
+}
+
+// Name returns the names of the planners the router picks between
+func (r *Router) Name() string {
+	if r.Nationwide == nil {
+		return r.Local.Name()
+	}
+	return r.Local.Name() + "/" + r.Nationwide.Name()
+}
+
+// MaxVias returns the most via stops either planner takes
+func (r *Router) MaxVias() int {
+	n := 0
+	for _, p := range r.planners() {
+		n = max(n, p.MaxVias())
+	}
+	return n
+}
+
+// Covers reports whether either planner covers the place
+func (r *Router) Covers(ctx context.Context, query string) (bool, error) {
+	for _, p := range r.planners() {
+		if ok, err := p.Covers(ctx, query); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// Plan finds trips with the planner that covers the search, falling back
+// to the other planner if that fails. Ambiguous places and invalid
+// searches are not retried, as the other planner would fail the same
+// way, and neither is a planner without an API key, as it never got to
+// search. If both fail, the first error is returned.
+func (r *Router) Plan(ctx context.Context, req Request) (*Page, error) {
+	first, second := r.Choose(ctx, req)
+	page, err := first.Plan(ctx, req)
+	if err == nil || second == nil || !retryable(ctx, err) {
+		return page, err
+	}
+
+	if r.OnFallback != nil {
+		r.OnFallback(first, second, err)
+	}
+	if page, fallbackErr := second.Plan(ctx, req); fallbackErr == nil {
+		return page, nil
+	}
+	return nil, err
+}
+
+// Choose returns the planner to search with first and the one to fall
+// back to, or nil if there is none. The local planner is chosen only if
+// it is sure to cover the origin, the destination and every via stop.
+// Searches with more via stops than the local planner takes go to the
+// nationwide planner alone.
+func (r *Router) Choose(ctx context.Context, req Request) (first, second Planner) {
+	if r.Nationwide == nil {
+		return r.Local, nil
+	}
+	if len(req.Via) > r.Local.MaxVias() {
+		return r.Nationwide, nil
+	}
+	for _, place := range req.Places() {
+		if ok, err := r.Local.Covers(ctx, place); !ok || err != nil {
+			return r.Nationwide, r.Local
+		}
+	}
+	return r.Local, r.Nationwide
+}
+
+// planners returns the planners the router has
+func (r *Router) planners() []Planner {
+	if r.Nationwide == nil {
+		return []Planner{r.Local}
+	}
+	return []Planner{r.Local, r.Nationwide}
+}
+
+// retryable reports whether a search that failed with err might succeed
+// with another planner
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return !errors.Is(err, errs.ErrAmbiguous) && !errors.Is(err, errs.ErrInvalidInput) &&
+		!errors.Is(err, errs.ErrMissingAPIKey)
+}
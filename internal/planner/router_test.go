@@ -0,0 +1,123 @@
+package planner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"transport/internal/errs"
+)
+
+// fakePlanner covers the places in covers and answers every search with err,
+// or a page naming itself
+type fakePlanner struct {
+	name    string
+	covers  []string
+	maxVias int
+	err     error
+	plans   int
+}
+
+func (p *fakePlanner) Name() string { return p.name }
+
+func (p *fakePlanner) MaxVias() int { return p.maxVias }
+
+func (p *fakePlanner) Covers(ctx context.Context, query string) (bool, error) {
+	return slices.Contains(p.covers, query), nil
+}
+
+func (p *fakePlanner) Plan(ctx context.Context, req Request) (*Page, error) {
+	p.plans++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Page{Planner: p.name}, nil
+}
+
+func TestRouterChoose(t *testing.T) {
+	stockholm := []string{"Slussen", "Odenplan", "Kista", "Solna"}
+	tests := []struct {
+		name        string
+		req         Request
+		nationwide  bool // the router has a nationwide planner
+		first, next string
+	}{
+		{"inside", Request{Origin: "Slussen", Destination: "Odenplan"}, true, "SL", "ResRobot"},
+		{"origin outside", Request{Origin: "Uppsala", Destination: "Slussen"}, true, "ResRobot", "SL"},
+		{"destination outside", Request{Origin: "Slussen", Destination: "Uppsala"}, true, "ResRobot", "SL"},
+		{"via outside", Request{Origin: "Slussen", Destination: "Kista", Via: []string{"Uppsala"}}, true, "ResRobot", "SL"},
+		{"via inside", Request{Origin: "Slussen", Destination: "Kista", Via: []string{"Odenplan"}}, true, "SL", "ResRobot"},
+		{"more vias than SL takes", Request{Origin: "Slussen", Destination: "Kista", Via: []string{"Odenplan", "Solna"}}, true, "ResRobot", ""},
+		{"no nationwide planner", Request{Origin: "Uppsala", Destination: "Slussen"}, false, "SL", ""},
+	}
+	for _, tt := range tests {
+		r := &Router{Local: &fakePlanner{name: "SL", covers: stockholm, maxVias: 1}}
+		if tt.nationwide {
+			r.Nationwide = &fakePlanner{name: "ResRobot", maxVias: 5}
+		}
+		first, next := r.Choose(context.Background(), tt.req)
+		nextName := ""
+		if next != nil {
+			nextName = next.Name()
+		}
+		if first.Name() != tt.first || nextName != tt.next {
+			t.Errorf("%s: Choose() = %s, %q; want %s, %q", tt.name, first.Name(), nextName, tt.first, tt.next)
+		}
+	}
+}
+
+func TestRouterPlan(t *testing.T) {
+	upstream := &errs.UpstreamError{Service: "SL", Message: "503 Service Unavailable"}
+	inside := Request{Origin: "Slussen", Destination: "Odenplan"}
+	tests := []struct {
+		name                string
+		localErr, remoteErr error
+		want                string // planner of the page, "" for an error
+		wantErr             error
+		fallback            bool
+	}{
+		{"local answers", nil, nil, "SL", nil, false},
+		{"falls back", upstream, nil, "ResRobot", nil, true},
+		{"both fail", upstream, errors.New("timeout"), "", errs.ErrUpstream, true},
+		{"not found locally", &errs.NotFoundError{What: "stops", Query: "Slussen"}, nil, "ResRobot", nil, true},
+		{"not retried when ambiguous", errs.ErrAmbiguous, nil, "", errs.ErrAmbiguous, false},
+		{"not retried when invalid", errs.Invalidf("bad time"), nil, "", errs.ErrInvalidInput, false},
+		{"not retried without an API key", &errs.MissingAPIKeyError{EnvVar: "SL_API_KEY"}, nil, "", errs.ErrMissingAPIKey, false},
+	}
+	for _, tt := range tests {
+		local := &fakePlanner{name: "SL", covers: []string{"Slussen", "Odenplan"}, err: tt.localErr}
+		remote := &fakePlanner{name: "ResRobot", err: tt.remoteErr}
+		fellBack := false
+		r := &Router{Local: local, Nationwide: remote, OnFallback: func(failed, next Planner, err error) {
+			fellBack = failed == local && next == remote && err == tt.localErr
+		}}
+
+		page, err := r.Plan(context.Background(), inside)
+		got := ""
+		if page != nil {
+			got = page.Planner
+		}
+		if got != tt.want || (tt.wantErr == nil) != (err == nil) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+			t.Errorf("%s: Plan() = %q, %v; want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+		if fellBack != tt.fallback {
+			t.Errorf("%s: fell back = %v, want %v", tt.name, fellBack, tt.fallback)
+		}
+	}
+}
+
+func TestRouterPlanCanceled(t *testing.T) {
+	local := &fakePlanner{name: "SL", covers: []string{"Slussen", "Odenplan"}, err: context.Canceled}
+	remote := &fakePlanner{name: "ResRobot"}
+	r := &Router{Local: local, Nationwide: remote}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.Plan(ctx, Request{Origin: "Slussen", Destination: "Odenplan"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Plan() error = %v, want context.Canceled", err)
+	}
+	if remote.plans != 0 {
+		t.Errorf("a cancelled search was tried with %s", remote.name)
+	}
+}
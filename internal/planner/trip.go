@@ -0,0 +1,266 @@
+package planner
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"transport/internal/geo"
+	"transport/internal/rank"
+)
+
+// Trip is one way of travelling from the origin to the destination
+type Trip struct {
+	Legs             []Leg
+	Duration         time.Duration // planned
+	RealtimeDuration time.Duration // from real-time data, the planned duration without it
+	Changes          int
+	Cancelled        bool
+	Status           string // StatusCancelled, StatusPartiallyCancelled, another planner status, or "" if normal
+
+	// Metrics describes the trip for ranking. The planners know best
+	// where a change involves a walk, so they fill it in.
+	Metrics rank.Metrics
+}
+
+// Trip statuses that have a meaning of their own
+const (
+	StatusCancelled          = "cancelled"
+	StatusPartiallyCancelled = "partially_cancelled"
+)
+
+// Leg is one ride or walk of a trip
+type Leg struct {
+	Mode      string // metro, bus, train, tram, ship, walk or unknown
+	Line      string
+	Direction string
+	Operator  string
+	From      Stop
+	To        Stop
+
+	Departure         time.Time // planned
+	Arrival           time.Time // planned
+	ExpectedDeparture time.Time // real-time, zero without real-time data
+	ExpectedArrival   time.Time // real-time, zero without real-time data
+	Duration          time.Duration
+	Distance          int // meters, for walks
+	Cancelled         bool
+
+	Stops               []StopTime  // stops passed between From and To, if asked for
+	Notices             []Notice    // disruptions and other messages, most important first
+	ReservationRequired bool        // a seat must be booked
+	Attributes          []Attribute // facilities and ticket rules
+	Geometry            []geo.Point // from From to To
+}
+
+// Stop is where a leg starts or ends
+type Stop struct {
+	Name     string
+	Platform string
+	Coord    geo.Point
+	Via      bool // the trip passes a requested via stop here
+}
+
+// StopTime is a stop a leg passes between its origin and destination
+type StopTime struct {
+	Name              string
+	Coord             geo.Point
+	Arrival           time.Time // planned, zero at the first stop of a line
+	Departure         time.Time // planned, zero at the last stop of a line
+	ExpectedArrival   time.Time // real-time, zero without real-time data
+	ExpectedDeparture time.Time // real-time, zero without real-time data
+}
+
+// Notice is a message about a leg, such as track works
+type Notice struct {
+	Importance int // higher is more important
+	Title      string
+	Message    string
+	URL        string
+	Info       bool // general information rather than a disruption
+}
+
+// Attribute describes the vehicle or the ticket of a leg
+type Attribute struct {
+	Kind string // one of the Attribute kinds, or "attribute" for any other
+	Code string // the planner's code for the attribute
+	Text string
+}
+
+// Kinds of attribute travellers need to know about
+const (
+	AttributeReservationRequired = "reservation_required"
+	AttributeBikeAllowed         = "bike_allowed"
+	AttributeWheelchair          = "wheelchair"
+)
+
+// ModeIcon returns the icon for a mode such as "bus" or "METRO", shared
+// by trips, departure boards and nearby stops
+func ModeIcon(mode string) string {
+	switch strings.ToLower(mode) {
+	case "bus":
+		return "🚌"
+	case "metro":
+		return "🚇"
+	case "train":
+		return "🚂"
+	case "tram":
+		return "🚊"
+	case "ship":
+		return "⛴️"
+	default:
+		return "🚍"
+	}
+}
+
+// IsWalk reports whether the leg is a walk
+func (l *Leg) IsWalk() bool {
+	return l.Mode == "walk"
+}
+
+// DepartureDelay returns how much later than planned the leg leaves
+// (negative if early, zero without real-time data)
+func (l *Leg) DepartureDelay() time.Duration {
+	return delay(l.Departure, l.ExpectedDeparture)
+}
+
+// ArrivalDelay returns how much later than planned the leg arrives
+func (l *Leg) ArrivalDelay() time.Duration {
+	return delay(l.Arrival, l.ExpectedArrival)
+}
+
+// HasAttribute reports whether the leg has an attribute of the given kind
+func (l *Leg) HasAttribute(kind string) bool {
+	for _, attr := range l.Attributes {
+		if attr.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// DepartureDelay returns how much later than planned the vehicle leaves
+// the stop
+func (s *StopTime) DepartureDelay() time.Duration {
+	return delay(s.Departure, s.ExpectedDeparture)
+}
+
+// ArrivalDelay returns how much later than planned the vehicle reaches
+// the stop
+func (s *StopTime) ArrivalDelay() time.Duration {
+	return delay(s.Arrival, s.ExpectedArrival)
+}
+
+// delay returns expected minus planned, or zero if either is unknown
+func delay(planned, expected time.Time) time.Duration {
+	if planned.IsZero() || expected.IsZero() {
+		return 0
+	}
+	return expected.Sub(planned)
+}
+
+// Departure returns the planned departure from the origin
+func (t *Trip) Departure() time.Time {
+	if len(t.Legs) == 0 {
+		return time.Time{}
+	}
+	return t.Legs[0].Departure
+}
+
+// Arrival returns the planned arrival at the destination
+func (t *Trip) Arrival() time.Time {
+	if len(t.Legs) == 0 {
+		return time.Time{}
+	}
+	return t.Legs[len(t.Legs)-1].Arrival
+}
+
+// ExpectedArrival returns the arrival at the destination from real-time
+// data, or the planned arrival without it
+func (t *Trip) ExpectedArrival() time.Time {
+	if len(t.Legs) > 0 {
+		if expected := t.Legs[len(t.Legs)-1].ExpectedArrival; !expected.IsZero() {
+			return expected
+		}
+	}
+	return t.Arrival()
+}
+
+// Transfers returns the changes between rides
+func (t *Trip) Transfers() []rank.Transfer {
+	return t.Metrics.Transfers
+}
+
+// Score returns the trip's ranking score (see rank.Metrics.Score)
+func (t *Trip) Score() int {
+	return t.Metrics.Score()
+}
+
+// SaferAlternatives returns, for each trip with a transfer at risk, the
+// index of a safer trip in the same list, or -1 (see rank.SaferAlternative)
+func SaferAlternatives(trips []Trip) []int {
+	metrics := make([]rank.Metrics, len(trips))
+	for i := range trips {
+		metrics[i] = trips[i].Metrics
+	}
+	alternatives := make([]int, len(trips))
+	for i := range trips {
+		alternatives[i] = rank.SaferAlternative(metrics, i)
+	}
+	return alternatives
+}
+
+// MapsURL returns a Google Maps link to the trip by public transport,
+// through the stops where it changes
+func (t *Trip) MapsURL() string {
+	if len(t.Legs) == 0 {
+		return ""
+	}
+
+	params := url.Values{}
+	params.Set("api", "1")
+	params.Set("origin", mapsPlace(t.Legs[0].From))
+	params.Set("destination", mapsPlace(t.Legs[len(t.Legs)-1].To))
+	params.Set("travelmode", "transit")
+
+	var waypoints []string
+	for _, leg := range t.Legs[:len(t.Legs)-1] {
+		waypoints = append(waypoints, mapsPlace(leg.To))
+	}
+	if len(waypoints) > 0 {
+		params.Set("waypoints", strings.Join(waypoints, "|"))
+	}
+
+	return "https://www.google.com/maps/dir/?" + params.Encode()
+}
+
+// mapsPlace returns a stop as Google Maps takes it: its coordinates if
+// known, otherwise its name
+func mapsPlace(stop Stop) string {
+	if stop.Coord.IsZero() {
+		return stop.Name
+	}
+	return fmt.Sprintf("%f,%f", stop.Coord.Lat, stop.Coord.Lon)
+}
+
+// Route returns the geometry of the trip for GeoJSON or GPX export
+func (t *Trip) Route(name string) geo.Route {
+	route := geo.Route{Name: name}
+	for _, l := range t.Legs {
+		leg := geo.Leg{
+			Mode:      l.Mode,
+			From:      l.From.Name,
+			To:        l.To.Name,
+			Departure: l.Departure,
+			Arrival:   l.Arrival,
+			Points:    l.Geometry,
+		}
+		if !l.IsWalk() {
+			leg.Line = l.Line
+			leg.Direction = l.Direction
+		}
+		route.Legs = append(route.Legs, leg)
+	}
+	return route
+}
@@ -0,0 +1,159 @@
+// Package rank scores and sorts journeys from any trip planner.
+//
+// The planners describe journeys differently, so each one converts its
+// journeys to Metrics and the ranking works on those. Scores are a
+// "generalised travel time" in minutes: the time the journey takes, plus
+// penalties for changing, walking and transfers that are easy to miss.
+// Lower scores are better.
+package rank
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Strategy is a way of ordering journeys
+type Strategy string
+
+// Sort strategies understood by ParseStrategy
+const (
+	Fastest         Strategy = "fastest"
+	EarliestArrival Strategy = "earliest-arrival"
+	FewestChanges   Strategy = "fewest-changes"
+	LeastWalking    Strategy = "least-walking"
+	MostReliable    Strategy = "most-reliable"
+)
+
+// Strategies lists the sort strategies in the order they are documented
+var Strategies = []Strategy{Fastest, EarliestArrival, FewestChanges, LeastWalking, MostReliable}
+
+// Score weights, in minutes
+const (
+	changePenalty    = 5    // each change
+	walkWeight       = 2    // a minute of walking counts as two
+	cancelledPenalty = 1000 // cancelled journeys always rank last
+)
+
+// Metrics describes a journey for ranking
+type Metrics struct {
+	Departure time.Time
+	Arrival   time.Time
+	Duration  time.Duration
+	Changes   int
+	Walk      time.Duration // total time spent walking
+	Transfers []Transfer
+	Delay     time.Duration // real-time delay at the destination
+	Cancelled bool
+}
+
+// ParseStrategy parses a strategy name, accepting a few Swedish aliases
+func ParseStrategy(name string) (Strategy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "fastest", "snabbast":
+		return Fastest, nil
+	case "earliest-arrival", "arrival", "tidigast", "framme":
+		return EarliestArrival, nil
+	case "fewest-changes", "changes", "byten", "färrest-byten":
+		return FewestChanges, nil
+	case "least-walking", "walking", "gång", "minst-gång":
+		return LeastWalking, nil
+	case "most-reliable", "reliable", "säkrast", "pålitligast":
+		return MostReliable, nil
+	}
+
+	names := make([]string, len(Strategies))
+	for i, s := range Strategies {
+		names[i] = string(s)
+	}
+	return "", fmt.Errorf("invalid sort '%s' (use %s)", name, strings.Join(names, ", "))
+}
+
+// Score returns the generalised travel time of the journey in minutes.
+// Lower is better.
+func (m Metrics) Score() int {
+	score := m.Duration.Minutes() +
+		float64(m.Changes*changePenalty) +
+		m.Walk.Minutes()*(walkWeight-1) +
+		m.risk()
+	return int(score + 0.5)
+}
+
+// risk estimates how likely the journey is to go wrong, in minutes: tight
+// transfers, delays already reported and cancellations
+func (m Metrics) risk() float64 {
+	if m.Cancelled {
+		return cancelledPenalty
+	}
+
+	var risk float64
+	if slack, ok := minSlack(m.Transfers); ok {
+		switch {
+		case slack < RiskThreshold:
+			risk += 30
+		case slack < 5*time.Minute:
+			risk += 10
+		case slack < 8*time.Minute:
+			risk += 3
+		}
+	}
+	if m.Delay > 0 {
+		risk += m.Delay.Minutes()
+	}
+	return risk
+}
+
+// Order returns the indices of metrics sorted by strategy. Ties are broken
+// by score, then by the original order.
+func Order(strategy Strategy, metrics []Metrics) []int {
+	order := make([]int, len(metrics))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := metrics[order[i]], metrics[order[j]]
+		if a.Cancelled != b.Cancelled {
+			return b.Cancelled
+		}
+		if c := compare(strategy, a, b); c != 0 {
+			return c < 0
+		}
+		return a.Score() < b.Score()
+	})
+	return order
+}
+
+// compare orders two journeys by the strategy's main criterion
+func compare(strategy Strategy, a, b Metrics) int {
+	switch strategy {
+	case Fastest:
+		return compareDurations(a.Duration, b.Duration)
+	case EarliestArrival:
+		return a.Arrival.Compare(b.Arrival)
+	case FewestChanges:
+		return a.Changes - b.Changes
+	case LeastWalking:
+		return compareDurations(a.Walk, b.Walk)
+	case MostReliable:
+		ra, rb := a.risk(), b.risk()
+		switch {
+		case ra < rb:
+			return -1
+		case ra > rb:
+			return 1
+		}
+	}
+	return 0
+}
+
+func compareDurations(a, b time.Duration) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package rank
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+// at returns base plus m minutes
+func at(m int) time.Time {
+	return base.Add(time.Duration(m) * time.Minute)
+}
+
+// change returns a transfer with slack minutes to spare after walk minutes
+func change(slack, walk int) Transfer {
+	return Transfer{
+		Stop:      "Odenplan",
+		Arrival:   at(10),
+		Departure: at(10 + walk + slack),
+		Walk:      time.Duration(walk) * time.Minute,
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Strategy
+		wantErr bool
+	}{
+		{"fastest", Fastest, false},
+		{" Snabbast ", Fastest, false},
+		{"framme", EarliestArrival, false},
+		{"byten", FewestChanges, false},
+		{"minst-gång", LeastWalking, false},
+		{"säkrast", MostReliable, false},
+		{"cheapest", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStrategy(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseStrategy(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics Metrics
+		want    int
+	}{
+		{"direct", Metrics{Duration: 30 * time.Minute}, 30},
+		{"one change", Metrics{Duration: 30 * time.Minute, Changes: 1, Transfers: []Transfer{change(10, 2)}}, 35},
+		{"walking counts twice", Metrics{Duration: 30 * time.Minute, Walk: 6 * time.Minute}, 36},
+		{"tight change", Metrics{Duration: 30 * time.Minute, Changes: 1, Transfers: []Transfer{change(1, 2)}}, 65},
+		{"short change", Metrics{Duration: 30 * time.Minute, Changes: 1, Transfers: []Transfer{change(4, 2)}}, 45},
+		{"reported delay", Metrics{Duration: 30 * time.Minute, Delay: 4 * time.Minute}, 34},
+		{"cancelled", Metrics{Duration: 30 * time.Minute, Cancelled: true}, 1030},
+	}
+	for _, tt := range tests {
+		if got := tt.metrics.Score(); got != tt.want {
+			t.Errorf("%s: Score() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	metrics := []Metrics{
+		{Arrival: at(50), Duration: 40 * time.Minute, Changes: 2, Walk: 2 * time.Minute, Transfers: []Transfer{change(1, 1), change(6, 1)}},
+		{Arrival: at(55), Duration: 35 * time.Minute, Changes: 0, Walk: 8 * time.Minute},
+		{Arrival: at(45), Duration: 45 * time.Minute, Changes: 1, Walk: 0, Transfers: []Transfer{change(9, 0)}},
+		{Arrival: at(30), Duration: 20 * time.Minute, Cancelled: true},
+	}
+	tests := []struct {
+		strategy Strategy
+		want     []int
+	}{
+		{Fastest, []int{1, 0, 2, 3}},
+		{EarliestArrival, []int{2, 0, 1, 3}},
+		{FewestChanges, []int{1, 2, 0, 3}},
+		{LeastWalking, []int{2, 0, 1, 3}},
+		{MostReliable, []int{1, 2, 0, 3}},
+	}
+	for _, tt := range tests {
+		if got := Order(tt.strategy, metrics); !slices.Equal(got, tt.want) {
+			t.Errorf("Order(%s) = %v, want %v", tt.strategy, got, tt.want)
+		}
+	}
+}
+
+func TestTransferSlack(t *testing.T) {
+	tests := []struct {
+		name     string
+		transfer Transfer
+		slack    time.Duration
+		atRisk   bool
+	}{
+		{"plenty of time", change(8, 2), 8 * time.Minute, false},
+		{"at the threshold", change(3, 2), 3 * time.Minute, false},
+		{"tight", change(2, 2), 2 * time.Minute, true},
+		{"walk longer than the gap", change(-1, 4), -time.Minute, true},
+	}
+	for _, tt := range tests {
+		if got := tt.transfer.Slack(); got != tt.slack {
+			t.Errorf("%s: Slack() = %v, want %v", tt.name, got, tt.slack)
+		}
+		if got := tt.transfer.AtRisk(); got != tt.atRisk {
+			t.Errorf("%s: AtRisk() = %v, want %v", tt.name, got, tt.atRisk)
+		}
+	}
+}
+
+func TestSaferAlternative(t *testing.T) {
+	risky := Metrics{Arrival: at(40), Transfers: []Transfer{change(1, 2)}}
+	safeLate := Metrics{Arrival: at(60)}
+	safeEarly := Metrics{Arrival: at(50), Transfers: []Transfer{change(5, 2)}}
+	cancelled := Metrics{Arrival: at(45), Cancelled: true}
+
+	tests := []struct {
+		name    string
+		metrics []Metrics
+		i       int
+		want    int
+	}{
+		{"earliest safe trip", []Metrics{risky, safeLate, safeEarly}, 0, 2},
+		{"skips cancelled trips", []Metrics{risky, cancelled, safeLate}, 0, 2},
+		{"safe trips need none", []Metrics{risky, safeLate}, 1, -1},
+		{"nothing better", []Metrics{risky, risky, cancelled}, 0, -1},
+	}
+	for _, tt := range tests {
+		if got := SaferAlternative(tt.metrics, tt.i); got != tt.want {
+			t.Errorf("%s: SaferAlternative(%d) = %d, want %d", tt.name, tt.i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package rank
+
+import "time"
+
+// RiskThreshold is the slack below which a transfer is at risk of being missed
+const RiskThreshold = 3 * time.Minute
+
+// Transfer is a change from one ride to the next
+type Transfer struct {
+	Stop       string        // where the change happens
+	Arrival    time.Time     // arrival of the incoming ride, estimated if known
+	Departure  time.Time     // departure of the outgoing ride, estimated if known
+	Walk       time.Duration // time needed to walk between the platforms
+	WalkMeters int           // distance between the platforms, 0 if unknown
+}
+
+// Slack returns the time to spare once the walk between platforms is done
+func (t Transfer) Slack() time.Duration {
+	return t.Departure.Sub(t.Arrival) - t.Walk
+}
+
+// AtRisk reports whether the transfer is likely to be missed
+func (t Transfer) AtRisk() bool {
+	return t.Slack() < RiskThreshold
+}
+
+// RiskyTransfers returns the transfers that are at risk
+func RiskyTransfers(transfers []Transfer) []Transfer {
+	var risky []Transfer
+	for _, t := range transfers {
+		if t.AtRisk() {
+			risky = append(risky, t)
+		}
+	}
+	return risky
+}
+
+// minSlack returns the smallest slack of any transfer. ok is false
+// without transfers.
+func minSlack(transfers []Transfer) (slack time.Duration, ok bool) {
+	for _, t := range transfers {
+		if s := t.Slack(); !ok || s < slack {
+			slack, ok = s, true
+		}
+	}
+	return slack, ok
+}
+
+// SaferAlternative suggests a journey to take instead of journey i when it
+// has a transfer at risk: the earliest arriving journey in the same result
+// set that isn't cancelled and has no transfer at risk. Returns -1 if
+// journey i is safe or there is nothing better.
+func SaferAlternative(metrics []Metrics, i int) int {
+	if len(RiskyTransfers(metrics[i].Transfers)) == 0 {
+		return -1
+	}
+
+	best := -1
+	for j, m := range metrics {
+		if j == i || m.Cancelled || len(RiskyTransfers(m.Transfers)) > 0 {
+			continue
+		}
+		if best < 0 || m.Arrival.Before(metrics[best].Arrival) {
+			best = j
+		}
+	}
+	return best
+}
@@ -0,0 +1,152 @@
+package resrobot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"transport/internal/errs"
+)
+
+// BoardOptions selects the departures or arrivals on a board
+type BoardOptions struct {
+	Time       time.Time     // start of the board; zero for now
+	Duration   time.Duration // how far ahead to look; 0 for the API default of an hour
+	MaxResults int           // 0 for no limit
+	Products   Products      // allowed product classes, see ProductsForModes; 0 for all
+	Direction  string        // stop ID the vehicles go towards (or come from, for arrivals)
+}
+
+// BoardEntry is a departure or an arrival on a board
+type BoardEntry struct {
+	Stop      string
+	StopID    string
+	Line      string
+	Category  string // PEN, MET, BUS, REG, SJ, etc.
+	Operator  string
+	Direction string // final destination, or the origin for arrivals
+	Time      time.Time
+	RtTime    *time.Time // Real-time departure or arrival
+	Track     string
+	Cancelled bool
+}
+
+// ExpectedTime returns the real-time departure or arrival if known, and the
+// timetable time otherwise
+func (e *BoardEntry) ExpectedTime() time.Time {
+	if e.RtTime != nil {
+		return *e.RtTime
+	}
+	return e.Time
+}
+
+// GetDepartures returns the departures from a stop
+func (c *Client) GetDepartures(ctx context.Context, stopID string, opts BoardOptions) ([]BoardEntry, error) {
+	var result DepartureBoardResponse
+	if err := c.getBoard(ctx, "departureBoard", stopID, opts, &result); err != nil {
+		return nil, fmt.Errorf("failed to get departures: %w", err)
+	}
+	return parseBoard(result.Departure), nil
+}
+
+// GetArrivals returns the arrivals at a stop. The Direction of each entry
+// is where the vehicle comes from.
+func (c *Client) GetArrivals(ctx context.Context, stopID string, opts BoardOptions) ([]BoardEntry, error) {
+	var result ArrivalBoardResponse
+	if err := c.getBoard(ctx, "arrivalBoard", stopID, opts, &result); err != nil {
+		return nil, fmt.Errorf("failed to get arrivals: %w", err)
+	}
+	return parseBoard(result.Arrival), nil
+}
+
+// getBoard requests a departure or arrival board and decodes it into result
+func (c *Client) getBoard(ctx context.Context, endpoint, stopID string, opts BoardOptions, result any) error {
+	if c.apiKey == "" {
+		return ErrNoAPIKey
+	}
+
+	params := url.Values{}
+	params.Set("id", stopID)
+	params.Set("format", "json")
+	params.Set("accessId", c.apiKey)
+	params.Set("passlist", "0")
+
+	start := opts.Time
+	if start.IsZero() {
+		start = c.now()
+	}
+	params.Set("date", start.Format("2006-01-02"))
+	params.Set("time", start.Format("15:04"))
+	if opts.Duration > 0 {
+		params.Set("duration", strconv.Itoa(int(opts.Duration.Minutes())))
+	}
+	if opts.MaxResults > 0 {
+		params.Set("maxJourneys", strconv.Itoa(opts.MaxResults))
+	}
+	if opts.Products > 0 {
+		params.Set("products", opts.Products.Param())
+	}
+	if opts.Direction != "" {
+		params.Set("direction", opts.Direction)
+	}
+
+	reqURL := fmt.Sprintf("%s/%s?%s", c.baseURL, endpoint, params.Encode())
+
+	resp, err := c.get(ctx, reqURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errs.FromResponse("ResRobot", resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return errs.Unavailable("ResRobot", fmt.Errorf("failed to decode response: %w", err))
+	}
+	return nil
+}
+
+// parseBoard converts API board items to our parsed format
+func parseBoard(items []DepartureBoardItem) []BoardEntry {
+	entries := make([]BoardEntry, 0, len(items))
+	for _, item := range items {
+		entry := BoardEntry{
+			Stop:      cleanStopName(item.Stop),
+			StopID:    item.StopExtID,
+			Line:      item.Product.Line,
+			Category:  item.Product.CatOut,
+			Operator:  item.Product.Operator,
+			Direction: cleanStopName(item.Direction),
+			Time:      parseDateTime(item.Date, item.Time),
+			Track:     item.Track,
+			Cancelled: item.Cancelled,
+		}
+		if entry.Line == "" {
+			entry.Line = item.Product.Num
+		}
+		if item.Origin != "" {
+			entry.Direction = cleanStopName(item.Origin)
+		}
+		if item.RtTime != "" {
+			rt := parseDateTime(item.RtDate, item.RtTime)
+			entry.RtTime = &rt
+		}
+		if item.RtTrack != "" {
+			entry.Track = item.RtTrack
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// ModeForCategory returns the mode name shared with the SL client (bus,
+// metro, train, tram, ship) for a category, or "" if it is unknown
+func ModeForCategory(cat string) string {
+	return CategoryProducts(cat).Mode()
+}
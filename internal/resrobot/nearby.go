@@ -0,0 +1,171 @@
+package resrobot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"transport/internal/errs"
+	"transport/internal/planner"
+)
+
+// DefaultNearbyRadius is how far NearbyStops looks by default, in meters:
+// about a quarter of an hour's walk
+const DefaultNearbyRadius = 1000
+
+// coordIDPrefix marks the IDs of stops made by CoordStop
+const coordIDPrefix = "coord:"
+
+// NearbyStops finds the stops within radius meters of a coordinate, closest
+// first, with their distance in Dist. max limits the number of stops; 0
+// leaves it to the API.
+func (c *Client) NearbyStops(ctx context.Context, lat, lon float64, radius, max int) ([]StopLocationData, error) {
+	if c.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	if radius <= 0 {
+		radius = DefaultNearbyRadius
+	}
+
+	params := url.Values{}
+	params.Set("originCoordLat", strconv.FormatFloat(lat, 'f', 6, 64))
+	params.Set("originCoordLong", strconv.FormatFloat(lon, 'f', 6, 64))
+	params.Set("r", strconv.Itoa(radius))
+	params.Set("format", "json")
+	params.Set("accessId", c.apiKey)
+	if max > 0 {
+		params.Set("maxNo", strconv.Itoa(max))
+	}
+
+	reqURL := fmt.Sprintf("%s/location.nearbystops?%s", c.baseURL, params.Encode())
+
+	resp, err := c.get(ctx, reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find nearby stops: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errs.FromResponse("ResRobot", resp)
+	}
+
+	var result LocationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, errs.Unavailable("ResRobot", fmt.Errorf("failed to decode response: %w", err))
+	}
+
+	var stops []StopLocationData
+	for _, item := range result.StopLocationOrCoordLocation {
+		if item.StopLocation != nil {
+			stops = append(stops, *item.StopLocation)
+		}
+	}
+	return stops, nil
+}
+
+// NearestStop returns the stop closest to a coordinate, within radius meters
+func (c *Client) NearestStop(ctx context.Context, lat, lon float64, radius int) (*StopLocationData, error) {
+	stops, err := c.NearbyStops(ctx, lat, lon, radius, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(stops) == 0 {
+		return nil, &errs.NotFoundError{What: "stops", Query: fmt.Sprintf("%.5f,%.5f", lat, lon)}
+	}
+	return &stops[0], nil
+}
+
+// CoordStop returns a stop for a coordinate, such as an address. Its ID can
+// be given to PlanTrip as the origin or destination; the trip then walks
+// to or from the coordinate.
+func CoordStop(lat, lon float64) StopLocationData {
+	coord := strconv.FormatFloat(lat, 'f', 6, 64) + "," + strconv.FormatFloat(lon, 'f', 6, 64)
+	return StopLocationData{
+		ID:   coordIDPrefix + coord,
+		Name: coord,
+		Lat:  lat,
+		Lon:  lon,
+	}
+}
+
+// IsCoord reports whether the stop is a coordinate from CoordStop
+func (s *StopLocationData) IsCoord() bool {
+	return strings.HasPrefix(s.ID, coordIDPrefix)
+}
+
+// setTripEndpoint sets the origin or destination ("dest") of a trip: a
+// stop ID, or the coordinate of a stop from CoordStop
+func setTripEndpoint(params url.Values, role, id string) {
+	coord, ok := strings.CutPrefix(id, coordIDPrefix)
+	if !ok {
+		params.Set(role+"Id", id)
+		return
+	}
+	lat, lon, _ := strings.Cut(coord, ",")
+	params.Set(role+"CoordLat", lat)
+	params.Set(role+"CoordLong", lon)
+}
+
+// FormatNearbyStops formats the stops found by NearbyStops
+func FormatNearbyStops(place string, radius int, stops []StopLocationData) string {
+	var sb strings.Builder
+
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf(" 🚏 Hållplatser inom %d m från %s\n", radius, place))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	if len(stops) == 0 {
+		sb.WriteString("  Inga hållplatser hittades inom angivet avstånd.\n")
+	}
+	for _, stop := range stops {
+		sb.WriteString(fmt.Sprintf("  %-45s %6d m  %s\n",
+			truncateName(stop.DisplayName(), 45),
+			stop.Dist,
+			walkTime(stop.Dist)))
+		var details []string
+		if municipality := stop.Municipality(); municipality != "" {
+			details = append(details, municipality)
+		}
+		if icons := productIcons(stop.Products); icons != "" {
+			details = append(details, icons)
+		}
+		if len(details) > 0 {
+			sb.WriteString(fmt.Sprintf("    %s\n", strings.Join(details, " · ")))
+		}
+	}
+
+	sb.WriteString("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	return sb.String()
+}
+
+// productIcons lists the product classes of a stop with their icons, as
+// "🚂 Regionaltåg  🚌 Buss"
+func productIcons(products Products) string {
+	var parts []string
+	for _, class := range products.Classes() {
+		parts = append(parts, planner.ModeIcon(class.Mode())+" "+class.Name())
+	}
+	return strings.Join(parts, "  ")
+}
+
+// walkTime estimates the time to walk a distance, at about 5 km/h
+func walkTime(meters int) string {
+	minutes := (meters + 40) / 80
+	if minutes < 1 {
+		return "🚶 <1 min"
+	}
+	return fmt.Sprintf("🚶 %d min", minutes)
+}
+
+// truncateName shortens a name to at most n characters
+func truncateName(name string, n int) string {
+	runes := []rune(name)
+	if len(runes) <= n {
+		return name
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,163 @@
+package resrobot
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NoteKind classifies a note on a leg
+type NoteKind string
+
+// Kinds of leg notes. Attributes describe the vehicle or the ticket; the
+// others are messages about the journey.
+const (
+	NoteReservationRequired NoteKind = "reservation_required"
+	NoteBikeAllowed         NoteKind = "bike_allowed"
+	NoteWheelchair          NoteKind = "wheelchair"
+	NoteAttribute           NoteKind = "attribute" // any other attribute, e.g. Bistro
+	NoteDisruption          NoteKind = "disruption"
+	NoteInfo                NoteKind = "info"
+)
+
+// ParsedNote is a note on a leg, classified by kind
+type ParsedNote struct {
+	Kind     NoteKind
+	Key      string // ResRobot's code for the note
+	Text     string
+	Priority int
+}
+
+// IsAttribute reports whether the note describes the vehicle or the
+// ticket rather than being a message
+func (n *ParsedNote) IsAttribute() bool {
+	return n.Kind != NoteDisruption && n.Kind != NoteInfo
+}
+
+// parseNotes classifies the notes of a leg. Attribute codes differ between
+// operators, so attributes are recognised by their text.
+func parseNotes(notes *Notes) []ParsedNote {
+	if notes == nil {
+		return nil
+	}
+	var parsed []ParsedNote
+	for _, note := range notes.Note {
+		text := strings.TrimSpace(note.Value)
+		if text == "" {
+			continue
+		}
+		parsed = append(parsed, ParsedNote{
+			Kind:     noteKind(note.Type, text),
+			Key:      note.Key,
+			Text:     text,
+			Priority: note.Priority,
+		})
+	}
+	return parsed
+}
+
+// noteKind classifies a note by its type: "A" for attributes, "H", "M" and
+// "R" for disruption and real-time messages, anything else is information
+func noteKind(noteType, text string) NoteKind {
+	switch noteType {
+	case "A":
+		return attributeKind(text)
+	case "H", "M", "R":
+		return NoteDisruption
+	default:
+		return NoteInfo
+	}
+}
+
+// attributeKind recognises the attributes travellers need to know about,
+// in Swedish or English. Negated attributes, such as "Ingen platsbokning"
+// or "No bikes", are other attributes.
+func attributeKind(text string) NoteKind {
+	lower := strings.ToLower(text)
+	if negated(lower) {
+		return NoteAttribute
+	}
+
+	switch {
+	case (strings.Contains(lower, "bokning") && containsAny(lower, "obligatorisk", "krävs", "förbokning")) ||
+		containsAny(lower, "reservation required", "compulsory reservation", "mandatory reservation"):
+		return NoteReservationRequired
+	case containsAny(lower, "cykel", "cyklar", "bicycle", "bike"):
+		return NoteBikeAllowed
+	case containsAny(lower, "rullstol", "wheelchair", "tillgänglighetsanpassad"):
+		return NoteWheelchair
+	default:
+		return NoteAttribute
+	}
+}
+
+// negations are the words that turn an attribute around, e.g. in
+// "Platsbokning krävs ej" or "Reservation not required"
+var negations = map[string]bool{
+	"ej": true, "inte": true, "icke": true, "ingen": true, "inga": true, "utan": true,
+	"no": true, "not": true, "non": true, "without": true,
+}
+
+// negated reports whether the lower-case text has a negating word
+func negated(lower string) bool {
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		if negations[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether the leg has a note of the given kind
+func (l *ParsedLeg) Has(kind NoteKind) bool {
+	for _, note := range l.Notes {
+		if note.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresReservation reports whether a seat must be booked for the leg,
+// as on SJ and Norrtåg trains
+func (l *ParsedLeg) RequiresReservation() bool {
+	return l.Has(NoteReservationRequired)
+}
+
+// TripStatus classifies the status ResRobot gives a trip
+type TripStatus string
+
+// Trip statuses. Values ResRobot sends that aren't recognised are kept,
+// in lower case.
+const (
+	TripNormal             TripStatus = ""
+	TripCancelled          TripStatus = "cancelled"
+	TripPartiallyCancelled TripStatus = "partially_cancelled"
+)
+
+// parseTripStatus classifies the tripStatus of a trip
+func parseTripStatus(status string) TripStatus {
+	upper := strings.ToUpper(strings.TrimSpace(status))
+	switch {
+	case upper == "", upper == "OK", upper == "NORMAL", upper == "PLANNED":
+		return TripNormal
+	case strings.Contains(upper, "PARTIAL") && strings.Contains(upper, "CANCEL"):
+		return TripPartiallyCancelled
+	case strings.Contains(upper, "CANCEL"):
+		return TripCancelled
+	default:
+		return TripStatus(strings.ToLower(status))
+	}
+}
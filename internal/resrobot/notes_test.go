@@ -0,0 +1,76 @@
+package resrobot
+
+import "testing"
+
+func TestNoteKind(t *testing.T) {
+	tests := []struct {
+		noteType, text string
+		want           NoteKind
+	}{
+		{"A", "Obligatorisk platsbokning", NoteReservationRequired},
+		{"A", "Platsbokning krävs", NoteReservationRequired},
+		{"A", "Förbokning krävs, ring 0771-xx xx xx", NoteReservationRequired},
+		{"A", "Reservation required", NoteReservationRequired},
+		{"A", "Ingen platsbokning", NoteAttribute},
+		{"A", "Platsbokning krävs ej", NoteAttribute},
+		{"A", "Platsbokning inte obligatorisk", NoteAttribute},
+		{"A", "No reservation required", NoteAttribute},
+		{"A", "Reservation not required", NoteAttribute},
+		{"A", "Cykel kan medtas", NoteBikeAllowed},
+		{"A", "Bicycles allowed", NoteBikeAllowed},
+		{"A", "Ej cykel", NoteAttribute},
+		{"A", "No bikes", NoteAttribute},
+		{"A", "Rullstolsplats", NoteWheelchair},
+		{"A", "Tillgänglighetsanpassad", NoteWheelchair},
+		{"A", "Inte tillgänglighetsanpassad", NoteAttribute},
+		{"A", "Bistro", NoteAttribute},
+		{"A", "Nordic", NoteAttribute}, // "no" only counts as a word
+		{"H", "Banarbete mellan Södertälje och Stockholm", NoteDisruption},
+		{"M", "Inställd", NoteDisruption},
+		{"R", "Försenad", NoteDisruption},
+		{"I", "Ingen platsbokning", NoteInfo},
+	}
+	for _, tt := range tests {
+		if got := noteKind(tt.noteType, tt.text); got != tt.want {
+			t.Errorf("noteKind(%q, %q) = %q, want %q", tt.noteType, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseNotes(t *testing.T) {
+	notes := &Notes{Note: []Note{
+		{Value: " Obligatorisk platsbokning ", Key: "RE", Type: "A", Priority: 100},
+		{Value: "  ", Key: "XX", Type: "A"},
+		{Value: "Banarbete", Key: "HIM", Type: "H", Priority: 50},
+	}}
+	parsed := parseNotes(notes)
+	if len(parsed) != 2 {
+		t.Fatalf("parseNotes() = %d notes, want 2 (blank notes dropped)", len(parsed))
+	}
+	if got := parsed[0]; got.Kind != NoteReservationRequired || got.Text != "Obligatorisk platsbokning" || !got.IsAttribute() {
+		t.Errorf("first note = %+v, want a trimmed reservation attribute", got)
+	}
+	if got := parsed[1]; got.Kind != NoteDisruption || got.IsAttribute() {
+		t.Errorf("second note = %+v, want a disruption message", got)
+	}
+	if parseNotes(nil) != nil {
+		t.Errorf("parseNotes(nil) is not nil")
+	}
+}
+
+func TestParseTripStatus(t *testing.T) {
+	tests := map[string]TripStatus{
+		"":                    TripNormal,
+		"OK":                  TripNormal,
+		"planned":             TripNormal,
+		"CANCELLED":           TripCancelled,
+		"PARTIALLY_CANCELLED": TripPartiallyCancelled,
+		"partialCancellation": TripPartiallyCancelled,
+		"ALTERNATIVE":         "alternative",
+	}
+	for status, want := range tests {
+		if got := parseTripStatus(status); got != want {
+			t.Errorf("parseTripStatus(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package resrobot
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBaseURL points the client at another ResRobot API, e.g. a fake from
+// transporttest
+func WithBaseURL(url string) Option {
+	return func(c *Client) {
+		c.baseURL = url
+	}
+}
+
+// WithHTTPClient sends requests with hc instead of the shared client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithAPIKey uses key instead of the one from RESROBOT_API_KEY
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithClock makes the client read the current time from now, which decides
+// when trips and boards start unless the options give a time
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		c.now = now
+	}
+}
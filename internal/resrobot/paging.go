@@ -0,0 +1,205 @@
+package resrobot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"transport/internal/errs"
+	"transport/internal/pagetoken"
+	"transport/internal/tz"
+)
+
+// maxPageRequests bounds the number of requests PlanTripSpan makes
+const maxPageRequests = 10
+
+// pageTokenPrefix marks page tokens made by this client
+const pageTokenPrefix = "rr."
+
+// TripPage is a list of trips sorted by departure, with tokens for
+// continuing before or after them
+type TripPage struct {
+	Trips   []ParsedTrip
+	Earlier string // token for trips before these, "" if unknown
+	Later   string // token for trips after these, "" if unknown
+}
+
+// IsPageToken reports whether token was made by this client, as opposed
+// to an SL token
+func IsPageToken(token string) bool {
+	return strings.HasPrefix(token, pageTokenPrefix)
+}
+
+// Kinds of page token payload: a scroll context from the trip planner,
+// or a time to search from when there is no context to continue from
+const (
+	tokenScroll  = "ctx"
+	tokenEarlier = "earlier"
+	tokenLater   = "later"
+)
+
+// pageSearch identifies the search a page token belongs to: the stops
+// and the options that decide which trips are found
+func pageSearch(originID, destID string, opts TripOptions) string {
+	return pagetoken.Search(originID, destID, viaParam(opts.Via), opts.Products,
+		opts.MaxWalkMeters, opts.WalkSpeed, opts.MinChangeMinutes)
+}
+
+// newPageToken wraps a scroll context from the trip planner
+func newPageToken(search, scroll string) string {
+	if scroll == "" {
+		return ""
+	}
+	return pagetoken.Encode(pageTokenPrefix, search, tokenScroll, scroll)
+}
+
+// newTimeToken makes a token for trips arriving before t (earlier) or
+// departing from t (later)
+func newTimeToken(search, kind string, t time.Time) string {
+	return pagetoken.Encode(pageTokenPrefix, search, kind, t.Format(time.RFC3339))
+}
+
+// applyPageToken sets the scroll context or search time from a token made
+// for the same search
+func applyPageToken(token, search string, params url.Values) error {
+	kind, value, err := pagetoken.Decode(token, pageTokenPrefix, search)
+	if err != nil {
+		return err
+	}
+
+	invalid := errs.Invalidf("invalid page token '%s'", token)
+	switch kind {
+	case tokenScroll:
+		params.Set("context", value)
+	case tokenEarlier, tokenLater:
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return invalid
+		}
+		t = t.In(tz.Stockholm)
+		params.Set("date", t.Format("2006-01-02"))
+		params.Set("time", t.Format("15:04"))
+		params.Del("searchForArrival")
+		if kind == tokenEarlier {
+			params.Set("searchForArrival", "1")
+		}
+	default:
+		return invalid
+	}
+	return nil
+}
+
+// Key identifies a trip across overlapping result pages
+func (t *ParsedTrip) Key() string {
+	parts := make([]string, len(t.Legs))
+	for i, leg := range t.Legs {
+		parts[i] = fmt.Sprintf("%s@%s", leg.Line, leg.DepartureTime.Format(time.RFC3339))
+	}
+	return strings.Join(parts, "|")
+}
+
+// PlanTripSpan collects trips departing within span of opts.Time, or
+// arriving within span before it with opts.ArriveBy, by requesting page
+// after page. Trips returned on several pages are only listed once.
+func (c *Client) PlanTripSpan(ctx context.Context, originID, destID string, opts TripOptions, span time.Duration) (*TripPage, error) {
+	first, err := c.PlanTripPage(ctx, originID, destID, opts, "")
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := opts.Time, opts.Time.Add(span)
+	if opts.ArriveBy {
+		start, end = opts.Time.Add(-span), opts.Time
+	}
+
+	trips := mergeTrips(nil, first.Trips)
+	page := first
+	for i := 1; i < maxPageRequests && !spanCovered(trips, start, end, opts.ArriveBy); i++ {
+		token := page.Later
+		if opts.ArriveBy {
+			token = page.Earlier
+		}
+		if token == "" {
+			break
+		}
+
+		page, err = c.PlanTripPage(ctx, originID, destID, opts, token)
+		if err != nil {
+			return nil, err
+		}
+		before := len(trips)
+		trips = mergeTrips(trips, page.Trips)
+		if len(trips) == before {
+			break // nothing new, the planner has run out of trips
+		}
+	}
+
+	// Keep only trips inside the span
+	var inSpan []ParsedTrip
+	for _, trip := range trips {
+		t := trip.Legs[0].DepartureTime
+		if opts.ArriveBy {
+			t = trip.Legs[len(trip.Legs)-1].ArrivalTime
+		}
+		if !t.Before(start) && !t.After(end) {
+			inSpan = append(inSpan, trip)
+		}
+	}
+
+	// Trips outside the span were dropped, so the tokens continue from
+	// times rather than from the planner's scroll contexts
+	result := &TripPage{Trips: inSpan}
+	if len(inSpan) > 0 {
+		search := pageSearch(originID, destID, opts)
+		last := inSpan[len(inSpan)-1].Legs[0].DepartureTime
+		result.Later = newTimeToken(search, tokenLater, last.Add(time.Minute))
+
+		earliest := inSpan[0].Legs[len(inSpan[0].Legs)-1].ArrivalTime
+		for _, trip := range inSpan[1:] {
+			if arrival := trip.Legs[len(trip.Legs)-1].ArrivalTime; arrival.Before(earliest) {
+				earliest = arrival
+			}
+		}
+		result.Earlier = newTimeToken(search, tokenEarlier, earliest.Add(-time.Minute))
+	}
+	return result, nil
+}
+
+// spanCovered reports whether trips reach the far end of the span
+func spanCovered(trips []ParsedTrip, start, end time.Time, arriveBy bool) bool {
+	if len(trips) == 0 {
+		return false
+	}
+	if arriveBy {
+		first := trips[0].Legs[len(trips[0].Legs)-1].ArrivalTime
+		return !first.After(start)
+	}
+	last := trips[len(trips)-1].Legs[0].DepartureTime
+	return !last.Before(end)
+}
+
+// mergeTrips adds the trips in more that aren't already in trips and
+// sorts the result by departure
+func mergeTrips(trips, more []ParsedTrip) []ParsedTrip {
+	seen := make(map[string]bool, len(trips))
+	for i := range trips {
+		seen[trips[i].Key()] = true
+	}
+	for _, trip := range more {
+		if len(trip.Legs) == 0 {
+			continue
+		}
+		if key := trip.Key(); !seen[key] {
+			seen[key] = true
+			trips = append(trips, trip)
+		}
+	}
+
+	sort.SliceStable(trips, func(i, j int) bool {
+		return trips[i].Legs[0].DepartureTime.Before(trips[j].Legs[0].DepartureTime)
+	})
+	return trips
+}
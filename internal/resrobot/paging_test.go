@@ -0,0 +1,80 @@
+package resrobot
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"transport/internal/errs"
+)
+
+func TestApplyPageToken(t *testing.T) {
+	opts := TripOptions{Products: ProductRegionalTrain | ProductLongDistanceTrain}
+	search := pageSearch("740000001", "740000002", opts)
+	at := time.Date(2026, 3, 2, 7, 12, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		token string
+		want  url.Values
+	}{
+		{"scroll", newPageToken(search, "3|OF|MT#14#3"), url.Values{"context": {"3|OF|MT#14#3"}}},
+		{"later", newTimeToken(search, tokenLater, at), url.Values{"date": {"2026-03-02"}, "time": {"08:12"}}},
+		{"earlier", newTimeToken(search, tokenEarlier, at), url.Values{"date": {"2026-03-02"}, "time": {"08:12"}, "searchForArrival": {"1"}}},
+	}
+	for _, tt := range tests {
+		params := url.Values{"searchForArrival": {"1"}}
+		if tt.name == "scroll" {
+			params = url.Values{}
+		}
+		if err := applyPageToken(tt.token, search, params); err != nil {
+			t.Errorf("%s: applyPageToken() error = %v", tt.name, err)
+			continue
+		}
+		if params.Encode() != tt.want.Encode() {
+			t.Errorf("%s: params = %s, want %s", tt.name, params.Encode(), tt.want.Encode())
+		}
+	}
+
+	if got := newPageToken(search, ""); got != "" {
+		t.Errorf("newPageToken() without a context = %q, want none", got)
+	}
+}
+
+func TestApplyPageTokenRejects(t *testing.T) {
+	opts := TripOptions{MinChangeMinutes: 5}
+	search := pageSearch("740000001", "740000002", opts)
+	token := newPageToken(search, "3|OF|MT#14#3")
+
+	moreWalk := opts
+	moreWalk.MaxWalkMeters = 2000
+	tests := []struct {
+		name, token, search string
+	}{
+		{"other destination", token, pageSearch("740000001", "740000003", opts)},
+		{"reversed", token, pageSearch("740000002", "740000001", opts)},
+		{"other options", token, pageSearch("740000001", "740000002", moreWalk)},
+		{"SL token", "sl.bGF0ZXJAMjAyNi0wMy0wMlQwODowMDowMCswMTowMA", search},
+		{"garbage", "rr.!!!", search},
+	}
+	for _, tt := range tests {
+		err := applyPageToken(tt.token, tt.search, url.Values{})
+		if !errors.Is(err, errs.ErrInvalidInput) {
+			t.Errorf("%s: applyPageToken() error = %v, want invalid input", tt.name, err)
+		}
+	}
+}
+
+func TestTimeTokenInStockholmTime(t *testing.T) {
+	// Summer time: 06:00 UTC is 08:00 in Stockholm
+	at := time.Date(2026, 7, 1, 6, 0, 0, 0, time.UTC)
+	search := pageSearch("a", "b", TripOptions{})
+	params := url.Values{}
+	if err := applyPageToken(newTimeToken(search, tokenLater, at), search, params); err != nil {
+		t.Fatal(err)
+	}
+	if got := params.Get("time"); got != "08:00" {
+		t.Errorf("time = %s, want 08:00", got)
+	}
+}
@@ -0,0 +1,165 @@
+package resrobot
+
+import (
+	"transport/internal/geo"
+	"transport/internal/planner"
+)
+
+// PlannerName is the name of the ResRobot journey planner in planner.Page
+const PlannerName = "ResRobot"
+
+// Place converts a resolved stop to the planner's model
+func (s *StopLocationData) Place() planner.Place {
+	place := planner.Place{
+		Name:  s.DisplayName(),
+		Kind:  planner.KindStop,
+		Coord: geo.Point{Lat: s.Lat, Lon: s.Lon},
+	}
+	if s.IsCoord() {
+		place.Kind = planner.KindCoord
+	}
+	return place
+}
+
+// PlannerPage converts a page of trips to the planner's model, marking
+// where each trip passes the via stops
+func (p *TripPage) PlannerPage(origin, dest *StopLocationData, vias []StopLocationData) *planner.Page {
+	page := &planner.Page{
+		Planner:     PlannerName,
+		Origin:      origin.Place(),
+		Destination: dest.Place(),
+		Earlier:     p.Earlier,
+		Later:       p.Later,
+	}
+	for i := range vias {
+		page.Via = append(page.Via, vias[i].Place())
+	}
+	for i := range p.Trips {
+		page.Trips = append(page.Trips, p.Trips[i].Trip(vias))
+	}
+	return page
+}
+
+// Trip converts the trip to the planner's model, marking where it passes
+// the via stops
+func (t *ParsedTrip) Trip(vias []StopLocationData) planner.Trip {
+	trip := planner.Trip{
+		Duration:         t.Duration,
+		RealtimeDuration: t.Duration,
+		Changes:          t.Interchanges,
+		Cancelled:        t.Status == TripCancelled,
+		Status:           string(t.Status),
+		Metrics:          t.Metrics(),
+	}
+	if len(t.Legs) > 0 {
+		// Real-time data only shifts the ends of the trip
+		first, last := t.Legs[0], t.Legs[len(t.Legs)-1]
+		if first.RtDeparture != nil {
+			trip.RealtimeDuration -= first.RtDeparture.Sub(first.DepartureTime)
+		}
+		if last.RtArrival != nil {
+			trip.RealtimeDuration += last.RtArrival.Sub(last.ArrivalTime)
+		}
+	}
+
+	viaLegs := make(map[int]bool)
+	for i := range vias {
+		viaLegs[t.ViaLeg(&vias[i])] = true
+	}
+	for i := range t.Legs {
+		leg := t.Legs[i].plannerLeg()
+		leg.From.Via = viaLegs[i]
+		trip.Legs = append(trip.Legs, leg)
+	}
+	return trip
+}
+
+// plannerLeg converts the leg to the planner's model
+func (l *ParsedLeg) plannerLeg() planner.Leg {
+	leg := planner.Leg{
+		Mode:      "walk",
+		Direction: l.Direction,
+		From: planner.Stop{
+			Name:     l.Origin,
+			Platform: l.OriginTrack,
+			Coord:    l.OriginCoord,
+		},
+		To: planner.Stop{
+			Name:     l.Destination,
+			Platform: l.DestTrack,
+			Coord:    l.DestCoord,
+		},
+		Departure: l.DepartureTime,
+		Arrival:   l.ArrivalTime,
+		Duration:  l.ArrivalTime.Sub(l.DepartureTime),
+		Distance:  l.Distance,
+		Geometry:  l.Geometry(),
+
+		ReservationRequired: l.RequiresReservation(),
+	}
+	if !l.IsWalk {
+		leg.Mode = CategoryProducts(l.Category).Mode()
+		if leg.Mode == "" {
+			leg.Mode = "unknown"
+		}
+		leg.Line = l.Line
+		if leg.Line == "" {
+			leg.Line = GetCategoryName(l.Category)
+		}
+		leg.Operator = l.Operator
+	}
+	if l.RtDeparture != nil {
+		leg.ExpectedDeparture = *l.RtDeparture
+	}
+	if l.RtArrival != nil {
+		leg.ExpectedArrival = *l.RtArrival
+	}
+
+	for _, s := range l.Stops {
+		stop := planner.StopTime{
+			Name:      s.Name,
+			Coord:     geo.Point{Lat: s.Lat, Lon: s.Lon},
+			Arrival:   s.Arrival,
+			Departure: s.Departure,
+		}
+		if s.RtArrival != nil {
+			stop.ExpectedArrival = *s.RtArrival
+		}
+		if s.RtDeparture != nil {
+			stop.ExpectedDeparture = *s.RtDeparture
+		}
+		leg.Stops = append(leg.Stops, stop)
+	}
+
+	// Disruptions first, as SL sorts its messages
+	for _, kind := range []NoteKind{NoteDisruption, NoteInfo} {
+		for _, note := range l.Notes {
+			if note.Kind == kind {
+				leg.Notices = append(leg.Notices, planner.Notice{
+					Importance: noticeImportance(kind),
+					Message:    note.Text,
+					Info:       kind == NoteInfo,
+				})
+			}
+		}
+	}
+	for _, note := range l.Notes {
+		if note.IsAttribute() {
+			leg.Attributes = append(leg.Attributes, planner.Attribute{
+				Kind: string(note.Kind),
+				Code: note.Key,
+				Text: note.Text,
+			})
+		}
+	}
+	return leg
+}
+
+// noticeImportance ranks a message note on the scale of SL's service
+// messages: disruptions are high, other information normal
+func noticeImportance(kind NoteKind) int {
+	if kind == NoteDisruption {
+		return 3
+	}
+	return 2
+}
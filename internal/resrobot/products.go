@@ -0,0 +1,272 @@
+package resrobot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Products is a set of product classes, encoded as the bitmask ResRobot
+// uses for the products of a stop and the "products" parameter of trips
+// and boards. The zero value means no restriction when used as a filter.
+type Products int
+
+// Product classes. Each is a single bit of Products.
+const (
+	ProductLongDistanceTrain Products = 2   // Snabbtåg, SJ, Intercity, nattåg
+	ProductRegionalTrain     Products = 4   // Regionaltåg, Norrtåg
+	ProductExpressBus        Products = 8   // Expressbuss, Flygbuss
+	ProductLocalTrain        Products = 16  // Pendeltåg
+	ProductMetro             Products = 32  // Tunnelbana
+	ProductTram              Products = 64  // Spårvagn
+	ProductBus               Products = 128 // Local and night buses
+	ProductFerry             Products = 256 // Färja, båt
+	ProductTaxi              Products = 512 // Anropsstyrd trafik and taxi
+
+	AllProducts = ProductLongDistanceTrain | ProductRegionalTrain | ProductExpressBus |
+		ProductLocalTrain | ProductMetro | ProductTram | ProductBus | ProductFerry | ProductTaxi
+)
+
+// productClasses lists the product classes in the order they are shown
+var productClasses = []Products{
+	ProductLongDistanceTrain,
+	ProductRegionalTrain,
+	ProductLocalTrain,
+	ProductMetro,
+	ProductTram,
+	ProductExpressBus,
+	ProductBus,
+	ProductFerry,
+	ProductTaxi,
+}
+
+// productNames maps the names accepted by ParseProducts, in Swedish and
+// English, to product classes
+var productNames = map[string]Products{
+	"fjärrtåg": ProductLongDistanceTrain, "fjarrtag": ProductLongDistanceTrain,
+	"snabbtåg": ProductLongDistanceTrain, "snabbtag": ProductLongDistanceTrain,
+	"long_distance_train": ProductLongDistanceTrain, "long-distance": ProductLongDistanceTrain,
+
+	"regionaltåg": ProductRegionalTrain, "regionaltag": ProductRegionalTrain,
+	"regional_train": ProductRegionalTrain, "regional": ProductRegionalTrain,
+
+	"pendeltåg": ProductLocalTrain, "pendeltag": ProductLocalTrain,
+	"local_train": ProductLocalTrain, "commuter": ProductLocalTrain,
+
+	"tunnelbana": ProductMetro, "t-bana": ProductMetro, "metro": ProductMetro,
+
+	"spårvagn": ProductTram, "sparvagn": ProductTram, "tram": ProductTram,
+
+	"expressbuss": ProductExpressBus, "flygbuss": ProductExpressBus,
+	"express_bus": ProductExpressBus, "express": ProductExpressBus,
+
+	"buss": ProductBus, "bus": ProductBus, "local_bus": ProductBus,
+
+	"färja": ProductFerry, "farja": ProductFerry, "båt": ProductFerry, "bat": ProductFerry,
+	"ferry": ProductFerry,
+
+	"taxi": ProductTaxi,
+}
+
+// ParseProducts parses a comma-separated list of product classes, such as
+// "regionaltåg,buss" or "long_distance_train,ferry". Returns 0 for an
+// empty list.
+func ParseProducts(value string) (Products, error) {
+	var products Products
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		class, ok := productNames[item]
+		if !ok {
+			return 0, fmt.Errorf("invalid product class '%s'", item)
+		}
+		products |= class
+	}
+	return products, nil
+}
+
+// Has reports whether the set includes every class in c
+func (p Products) Has(c Products) bool {
+	return c != 0 && p&c == c
+}
+
+// Classes returns the single product classes in the set, trains first
+func (p Products) Classes() []Products {
+	var classes []Products
+	for _, class := range productClasses {
+		if p.Has(class) {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// Param returns the set as the value of the "products" parameter
+func (p Products) Param() string {
+	return strconv.Itoa(int(p))
+}
+
+// ID returns a stable English identifier for a product class, for JSON
+func (p Products) ID() string {
+	switch p {
+	case ProductLongDistanceTrain:
+		return "long_distance_train"
+	case ProductRegionalTrain:
+		return "regional_train"
+	case ProductExpressBus:
+		return "express_bus"
+	case ProductLocalTrain:
+		return "local_train"
+	case ProductMetro:
+		return "metro"
+	case ProductTram:
+		return "tram"
+	case ProductBus:
+		return "bus"
+	case ProductFerry:
+		return "ferry"
+	case ProductTaxi:
+		return "taxi"
+	default:
+		return ""
+	}
+}
+
+// Name returns the Swedish name of a product class
+func (p Products) Name() string {
+	switch p {
+	case ProductLongDistanceTrain:
+		return "Fjärrtåg"
+	case ProductRegionalTrain:
+		return "Regionaltåg"
+	case ProductExpressBus:
+		return "Expressbuss"
+	case ProductLocalTrain:
+		return "Pendeltåg"
+	case ProductMetro:
+		return "Tunnelbana"
+	case ProductTram:
+		return "Spårvagn"
+	case ProductBus:
+		return "Buss"
+	case ProductFerry:
+		return "Färja"
+	case ProductTaxi:
+		return "Taxi"
+	default:
+		return ""
+	}
+}
+
+// Mode returns the mode name shared with the SL client (bus, metro, train,
+// tram, ship) for a product class, or "" if SL has none
+func (p Products) Mode() string {
+	switch p {
+	case ProductLongDistanceTrain, ProductRegionalTrain, ProductLocalTrain:
+		return "train"
+	case ProductMetro:
+		return "metro"
+	case ProductTram:
+		return "tram"
+	case ProductExpressBus, ProductBus:
+		return "bus"
+	case ProductFerry:
+		return "ship"
+	default:
+		return ""
+	}
+}
+
+// Names returns the Swedish names of the classes in the set
+func (p Products) Names() []string {
+	var names []string
+	for _, class := range p.Classes() {
+		names = append(names, class.Name())
+	}
+	return names
+}
+
+// IDs returns the identifiers of the classes in the set
+func (p Products) IDs() []string {
+	var ids []string
+	for _, class := range p.Classes() {
+		ids = append(ids, class.ID())
+	}
+	return ids
+}
+
+// Modes returns the SL mode names covered by the set, without duplicates
+func (p Products) Modes() []string {
+	var modes []string
+	seen := make(map[string]bool)
+	for _, class := range p.Classes() {
+		if mode := class.Mode(); mode != "" && !seen[mode] {
+			seen[mode] = true
+			modes = append(modes, mode)
+		}
+	}
+	return modes
+}
+
+// String returns the set as "Regionaltåg, Buss"
+func (p Products) String() string {
+	return strings.Join(p.Names(), ", ")
+}
+
+// CategoryProducts returns the product class of a category (PEN, BUS, SJ,
+// etc.), or 0 if it is unknown
+func CategoryProducts(cat string) Products {
+	switch cat {
+	case CatSJ, CatSnabbtag:
+		return ProductLongDistanceTrain
+	case CatRegionaltag, CatNorrtag:
+		return ProductRegionalTrain
+	case CatPendeltag:
+		return ProductLocalTrain
+	case CatMetro:
+		return ProductMetro
+	case CatSparvagn:
+		return ProductTram
+	case CatFlygbuss:
+		return ProductExpressBus
+	case CatBus, CatNattbus:
+		return ProductBus
+	case CatFerry:
+		return ProductFerry
+	default:
+		return 0
+	}
+}
+
+// ProductsForModes converts included and excluded mode names (bus, metro,
+// train, tram, ship) to a set of products. Returns 0 if neither is given.
+func ProductsForModes(modes, exclude []string) Products {
+	if len(modes) == 0 && len(exclude) == 0 {
+		return 0
+	}
+
+	products := AllProducts
+	if len(modes) > 0 {
+		products = 0
+		for _, mode := range modes {
+			products |= modeProducts(mode)
+		}
+	}
+	for _, mode := range exclude {
+		products &^= modeProducts(mode)
+	}
+	return products
+}
+
+// modeProducts returns the product classes of an SL mode name
+func modeProducts(mode string) Products {
+	var products Products
+	for _, class := range productClasses {
+		if class.Mode() == mode {
+			products |= class
+		}
+	}
+	return products
+}
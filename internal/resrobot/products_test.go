@@ -0,0 +1,124 @@
+package resrobot
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestDecodeStopProducts(t *testing.T) {
+	tests := []struct {
+		json    string
+		classes []Products
+		ids     []string
+		modes   []string
+	}{
+		{`{"products": 0}`, nil, nil, nil},
+		{`{"products": 128}`, []Products{ProductBus}, []string{"bus"}, []string{"bus"}},
+		{
+			`{"products": 148}`, // Uppsala C: regional and commuter trains, buses
+			[]Products{ProductRegionalTrain, ProductLocalTrain, ProductBus},
+			[]string{"regional_train", "local_train", "bus"},
+			[]string{"train", "bus"},
+		},
+		{
+			`{"products": 958}`, // every class but trams
+			[]Products{ProductLongDistanceTrain, ProductRegionalTrain, ProductLocalTrain, ProductMetro,
+				ProductExpressBus, ProductBus, ProductFerry, ProductTaxi},
+			[]string{"long_distance_train", "regional_train", "local_train", "metro",
+				"express_bus", "bus", "ferry", "taxi"},
+			[]string{"train", "metro", "bus", "ship"},
+		},
+	}
+	for _, tt := range tests {
+		var stop StopLocationData
+		if err := json.Unmarshal([]byte(tt.json), &stop); err != nil {
+			t.Fatalf("%s: %v", tt.json, err)
+		}
+		p := stop.Products
+		if got := p.Classes(); !slices.Equal(got, tt.classes) {
+			t.Errorf("%s: Classes() = %v, want %v", tt.json, got, tt.classes)
+		}
+		if got := p.IDs(); !slices.Equal(got, tt.ids) {
+			t.Errorf("%s: IDs() = %q, want %q", tt.json, got, tt.ids)
+		}
+		if got := p.Modes(); !slices.Equal(got, tt.modes) {
+			t.Errorf("%s: Modes() = %q, want %q", tt.json, got, tt.modes)
+		}
+	}
+}
+
+func TestParseProducts(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Products
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"regionaltåg", ProductRegionalTrain, false},
+		{"Fjärrtåg, buss", ProductLongDistanceTrain | ProductBus, false},
+		{"long_distance_train,ferry", ProductLongDistanceTrain | ProductFerry, false},
+		{"pendeltag,,t-bana", ProductLocalTrain | ProductMetro, false},
+		{"tåg", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseProducts(tt.value)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseProducts(%q) = %d, %v; want %d, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCategoryProducts(t *testing.T) {
+	tests := map[string]Products{
+		CatSJ:          ProductLongDistanceTrain,
+		CatNorrtag:     ProductRegionalTrain,
+		CatPendeltag:   ProductLocalTrain,
+		CatMetro:       ProductMetro,
+		CatSparvagn:    ProductTram,
+		CatFlygbuss:    ProductExpressBus,
+		CatNattbus:     ProductBus,
+		CatFerry:       ProductFerry,
+		"UNKNOWN":      0,
+		CatRegionaltag: ProductRegionalTrain,
+	}
+	for cat, want := range tests {
+		if got := CategoryProducts(cat); got != want {
+			t.Errorf("CategoryProducts(%q) = %d, want %d", cat, got, want)
+		}
+	}
+}
+
+func TestGetCategoryEmoji(t *testing.T) {
+	tests := map[string]string{
+		CatPendeltag: "🚂",
+		CatMetro:     "🚇",
+		CatFlygbuss:  "🚌",
+		CatFerry:     "⛴️",
+		"UNKNOWN":    "🚍",
+	}
+	for cat, want := range tests {
+		if got := GetCategoryEmoji(cat); got != want {
+			t.Errorf("GetCategoryEmoji(%q) = %q, want %q", cat, got, want)
+		}
+	}
+}
+
+func TestProductsForModes(t *testing.T) {
+	trains := ProductLongDistanceTrain | ProductRegionalTrain | ProductLocalTrain
+	tests := []struct {
+		modes, exclude []string
+		want           Products
+	}{
+		{nil, nil, 0},
+		{[]string{"train"}, nil, trains},
+		{[]string{"bus", "ship"}, nil, ProductExpressBus | ProductBus | ProductFerry},
+		{nil, []string{"bus"}, AllProducts &^ (ProductExpressBus | ProductBus)},
+		{[]string{"train", "metro"}, []string{"metro"}, trains},
+	}
+	for _, tt := range tests {
+		if got := ProductsForModes(tt.modes, tt.exclude); got != tt.want {
+			t.Errorf("ProductsForModes(%q, %q) = %d, want %d", tt.modes, tt.exclude, got, tt.want)
+		}
+	}
+}
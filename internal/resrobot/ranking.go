@@ -0,0 +1,97 @@
+package resrobot
+
+import (
+	"time"
+
+	"transport/internal/rank"
+)
+
+// Transfers returns the changes between rides, using real-time times when
+// available. Walks between the rides count towards the time needed.
+func (t *ParsedTrip) Transfers() []rank.Transfer {
+	var transfers []rank.Transfer
+	var prev *ParsedLeg
+	var walk time.Duration
+	var meters int
+
+	for i := range t.Legs {
+		leg := &t.Legs[i]
+		if leg.IsWalk {
+			walk += leg.ArrivalTime.Sub(leg.DepartureTime)
+			meters += leg.Distance
+			continue
+		}
+
+		if prev != nil {
+			transfer := rank.Transfer{
+				Stop:       leg.Origin,
+				Arrival:    prev.ArrivalTime,
+				Departure:  leg.DepartureTime,
+				Walk:       walk,
+				WalkMeters: meters,
+			}
+			if prev.RtArrival != nil {
+				transfer.Arrival = *prev.RtArrival
+			}
+			if leg.RtDeparture != nil {
+				transfer.Departure = *leg.RtDeparture
+			}
+			transfers = append(transfers, transfer)
+		}
+		prev, walk, meters = leg, 0, 0
+	}
+	return transfers
+}
+
+// Metrics describes the trip for ranking
+func (t *ParsedTrip) Metrics() rank.Metrics {
+	m := rank.Metrics{
+		Duration:  t.Duration,
+		Changes:   t.Interchanges,
+		Transfers: t.Transfers(),
+	}
+	if len(t.Legs) == 0 {
+		return m
+	}
+
+	first, last := t.Legs[0], t.Legs[len(t.Legs)-1]
+	m.Departure = first.DepartureTime
+	if first.RtDeparture != nil {
+		m.Departure = *first.RtDeparture
+	}
+	m.Arrival = last.ArrivalTime
+	if last.RtArrival != nil {
+		m.Arrival = *last.RtArrival
+		m.Delay = last.RtArrival.Sub(last.ArrivalTime)
+	}
+
+	for _, leg := range t.Legs {
+		if leg.IsWalk {
+			m.Walk += leg.ArrivalTime.Sub(leg.DepartureTime)
+		}
+	}
+	return m
+}
+
+// Score returns the trip's ranking score (see rank.Metrics.Score)
+func (t *ParsedTrip) Score() int {
+	return t.Metrics().Score()
+}
+
+// tripMetrics returns the metrics of each trip
+func tripMetrics(trips []ParsedTrip) []rank.Metrics {
+	metrics := make([]rank.Metrics, len(trips))
+	for i := range trips {
+		metrics[i] = trips[i].Metrics()
+	}
+	return metrics
+}
+
+// SortTrips sorts trips in place by strategy
+func SortTrips(trips []ParsedTrip, strategy rank.Strategy) {
+	sorted := make([]ParsedTrip, len(trips))
+	for i, idx := range rank.Order(strategy, tripMetrics(trips)) {
+		sorted[i] = trips[idx]
+	}
+	copy(trips, sorted)
+}
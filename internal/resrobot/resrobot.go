@@ -1,9 +1,10 @@
 package resrobot
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -12,6 +13,10 @@ import (
 	"strings"
 	"time"
 
+	"transport/internal/errs"
+	"transport/internal/geo"
+	"transport/internal/httpx"
+	"transport/internal/match"
 	"transport/internal/tz"
 )
 
@@ -20,26 +25,39 @@ const (
 	defaultTimeout = 15 * time.Second
 )
 
+// ErrNoAPIKey is returned by requests made without an API key
+var ErrNoAPIKey = &errs.MissingAPIKeyError{
+	EnvVar:    "RESROBOT_API_KEY",
+	SignupURL: "https://www.trafiklab.se/api/trafiklab-apis/resrobot-v21/",
+}
+
 // Client handles communication with the ResRobot API
 type Client struct {
 	httpClient *http.Client
+	baseURL    string
 	apiKey     string
+	now        func() time.Time
 }
 
-// NewClient creates a new ResRobot API client
-func NewClient() *Client {
+// NewClient creates a new ResRobot API client. The API key is read from
+// RESROBOT_API_KEY unless given with WithAPIKey.
+func NewClient(opts ...Option) *Client {
 	apiKey := os.Getenv("RESROBOT_API_KEY")
 	if apiKey == "" {
 		// Try alternate env var name
 		apiKey = os.Getenv("TRAFIKLAB_RESROBOT_KEY")
 	}
 
-	return &Client{
-		httpClient: &http.Client{
-			Timeout: defaultTimeout,
-		},
-		apiKey: apiKey,
+	c := &Client{
+		httpClient: httpx.NewClient(defaultTimeout),
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		now:        tz.Now,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // HasAPIKey returns true if an API key is configured
@@ -47,10 +65,23 @@ func (c *Client) HasAPIKey() bool {
 	return c.apiKey != ""
 }
 
+// get sends a GET request that is abandoned when ctx is done
+func (c *Client) get(ctx context.Context, reqURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errs.Unavailable("ResRobot", err)
+	}
+	return resp, nil
+}
+
 // SearchStops finds stops matching the given query
-func (c *Client) SearchStops(query string) ([]StopLocationData, error) {
+func (c *Client) SearchStops(ctx context.Context, query string) ([]StopLocationData, error) {
 	if c.apiKey == "" {
-		return nil, fmt.Errorf("RESROBOT_API_KEY not set. Get a free key at https://www.trafiklab.se/api/trafiklab-apis/resrobot-v21/")
+		return nil, ErrNoAPIKey
 	}
 
 	params := url.Values{}
@@ -58,22 +89,21 @@ func (c *Client) SearchStops(query string) ([]StopLocationData, error) {
 	params.Set("format", "json")
 	params.Set("accessId", c.apiKey)
 
-	reqURL := fmt.Sprintf("%s/location.name?%s", baseURL, params.Encode())
+	reqURL := fmt.Sprintf("%s/location.name?%s", c.baseURL, params.Encode())
 
-	resp, err := c.httpClient.Get(reqURL)
+	resp, err := c.get(ctx, reqURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search stops: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return nil, errs.FromResponse("ResRobot", resp)
 	}
 
 	var result LocationResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, errs.Unavailable("ResRobot", fmt.Errorf("failed to decode response: %w", err))
 	}
 
 	// Extract stop locations
@@ -92,31 +122,78 @@ type TripOptions struct {
 	Time       time.Time // Departure or arrival time
 	ArriveBy   bool      // If true, time is arrival time
 	NumResults int       // Number of results to return
-	Via        string    // Optional via station
+	Via        []ViaStop // Stops to pass through, in order (at most MaxVias)
+
+	IntermediateStops bool // Include the stops each leg passes
+
+	Products         Products // Allowed product classes, 0 for all (see ProductsForModes)
+	MaxWalkMeters    int      // Longest walk to or from a stop (0 for the planner's default)
+	WalkSpeed        int      // Walking speed in percent of normal (0 for normal)
+	MinChangeMinutes int      // Shortest time to change (0 for the planner's default)
+}
+
+// ViaStop is a stop the trip must pass through
+type ViaStop struct {
+	ID          string // Stop ID, as returned by SearchStops
+	WaitMinutes int    // Time to spend at the stop
 }
 
-// DefaultTripOptions returns default options
-func DefaultTripOptions() TripOptions {
+// MaxVias is the number of via stops the trip planner accepts
+const MaxVias = 5
+
+// viaParam formats via stops as the "via" parameter:
+// "id|wait" for each stop, separated by semicolons
+func viaParam(vias []ViaStop) string {
+	parts := make([]string, len(vias))
+	for i, via := range vias {
+		parts[i] = via.ID
+		if via.WaitMinutes > 0 {
+			parts[i] += "|" + strconv.Itoa(via.WaitMinutes)
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// DefaultTripOptions returns default options, leaving now by the client's
+// clock
+func (c *Client) DefaultTripOptions() TripOptions {
 	return TripOptions{
-		Time:       tz.Now(),
+		Time:       c.now(),
 		ArriveBy:   false,
 		NumResults: 5,
 	}
 }
 
 // PlanTrip finds journeys between origin and destination
-func (c *Client) PlanTrip(originID, destID string, opts TripOptions) ([]ParsedTrip, error) {
+func (c *Client) PlanTrip(ctx context.Context, originID, destID string, opts TripOptions) ([]ParsedTrip, error) {
+	page, err := c.PlanTripPage(ctx, originID, destID, opts, "")
+	if err != nil {
+		return nil, err
+	}
+	return page.Trips, nil
+}
+
+// PlanTripPage finds one page of journeys. With an empty token the page
+// starts at opts.Time; otherwise it continues from a token of an earlier
+// TripPage for the same trip.
+func (c *Client) PlanTripPage(ctx context.Context, originID, destID string, opts TripOptions, token string) (*TripPage, error) {
 	if c.apiKey == "" {
-		return nil, fmt.Errorf("RESROBOT_API_KEY not set. Get a free key at https://www.trafiklab.se/api/trafiklab-apis/resrobot-v21/")
+		return nil, ErrNoAPIKey
+	}
+	if len(opts.Via) > MaxVias {
+		return nil, errs.Invalidf("too many via stops: %d (at most %d)", len(opts.Via), MaxVias)
 	}
 
 	params := url.Values{}
-	params.Set("originId", originID)
-	params.Set("destId", destID)
+	setTripEndpoint(params, "origin", originID)
+	setTripEndpoint(params, "dest", destID)
 	params.Set("format", "json")
 	params.Set("accessId", c.apiKey)
 	params.Set("numF", fmt.Sprintf("%d", opts.NumResults))
 	params.Set("passlist", "0") // Don't include intermediate stops
+	if opts.IntermediateStops {
+		params.Set("passlist", "1")
+	}
 
 	if !opts.Time.IsZero() {
 		params.Set("date", opts.Time.Format("2006-01-02"))
@@ -127,22 +204,54 @@ func (c *Client) PlanTrip(originID, destID string, opts TripOptions) ([]ParsedTr
 		params.Set("searchForArrival", "1")
 	}
 
-	reqURL := fmt.Sprintf("%s/trip?%s", baseURL, params.Encode())
+	search := pageSearch(originID, destID, opts)
+	if token != "" {
+		if err := applyPageToken(token, search, params); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(opts.Via) > 0 {
+		params.Set("via", viaParam(opts.Via))
+	}
+
+	if opts.Products > 0 {
+		params.Set("products", opts.Products.Param())
+	}
+
+	if opts.MinChangeMinutes > 0 {
+		params.Set("minChangeTime", strconv.Itoa(opts.MinChangeMinutes))
+	}
+
+	if opts.MaxWalkMeters > 0 || opts.WalkSpeed > 0 {
+		// Format: enabled,min distance,max distance,speed in percent
+		maxWalk, speed := opts.MaxWalkMeters, opts.WalkSpeed
+		if maxWalk <= 0 {
+			maxWalk = 1000
+		}
+		if speed <= 0 {
+			speed = 100
+		}
+		walk := fmt.Sprintf("1,0,%d,%d", maxWalk, speed)
+		params.Set("originWalk", walk)
+		params.Set("destWalk", walk)
+	}
+
+	reqURL := fmt.Sprintf("%s/trip?%s", c.baseURL, params.Encode())
 
-	resp, err := c.httpClient.Get(reqURL)
+	resp, err := c.get(ctx, reqURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to plan trip: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return nil, errs.FromResponse("ResRobot", resp)
 	}
 
 	var result TripResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, errs.Unavailable("ResRobot", fmt.Errorf("failed to decode response: %w", err))
 	}
 
 	// Parse trips into our format
@@ -152,30 +261,114 @@ func (c *Client) PlanTrip(originID, destID string, opts TripOptions) ([]ParsedTr
 		trips = append(trips, parsed)
 	}
 
-	return trips, nil
+	return &TripPage{
+		Trips:   mergeTrips(nil, trips),
+		Earlier: newPageToken(search, result.ScrB),
+		Later:   newPageToken(search, result.ScrF),
+	}, nil
+}
+
+// maxCandidates is the number of alternatives offered for an ambiguous stop
+const maxCandidates = 8
+
+// ambiguityRatio decides when a stop name is ambiguous: if the runner-up
+// has at least this fraction of the best stop's weight, there is no
+// clear winner
+const ambiguityRatio = 0.5
+
+// AmbiguousStopError is returned when a query matches several stops and
+// none of them is a clear winner
+type AmbiguousStopError struct {
+	Query      string
+	Role       string // "origin" or "destination", if known
+	Candidates []StopLocationData
+}
+
+// Is makes errors.Is(err, errs.ErrAmbiguous) true
+func (e *AmbiguousStopError) Is(target error) bool {
+	return target == errs.ErrAmbiguous
+}
+
+func (e *AmbiguousStopError) Error() string {
+	names := make([]string, len(e.Candidates))
+	for i, stop := range e.Candidates {
+		names[i] = stop.Name
+	}
+	what := "stop"
+	if e.Role != "" {
+		what = e.Role
+	}
+	return fmt.Sprintf("ambiguous %s '%s'. Could be: %s", what, e.Query, strings.Join(names, ", "))
+}
+
+// ChooseStop picks the best of the stops returned by SearchStops. A unique
+// exact name match always wins; otherwise the first stop wins only if it
+// clearly outweighs the runner-up. Returns nil if there are no stops and
+// *AmbiguousStopError if there is no clear winner.
+func ChooseStop(query string, stops []StopLocationData) (*StopLocationData, error) {
+	stop, ambiguous := match.Choose(query, stops, stopCandidate, ambiguityRatio, maxCandidates)
+	if ambiguous != nil {
+		return nil, &AmbiguousStopError{Query: query, Candidates: ambiguous}
+	}
+	return stop, nil
+}
+
+// stopCandidate weighs stops by ResRobot's weight, a measure of how busy
+// they are
+var stopCandidate = match.Candidate[StopLocationData]{
+	Names:  func(s *StopLocationData) []string { return []string{s.Name, s.DisplayName()} },
+	Weight: func(s *StopLocationData) int { return s.Weight },
+}
+
+// ResolveStop searches for the query and picks the best stop
+func (c *Client) ResolveStop(ctx context.Context, query string) (*StopLocationData, error) {
+	stops, err := c.SearchStops(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return ChooseStop(query, stops)
 }
 
 // PlanTripByName resolves stop names and plans the trip
-func (c *Client) PlanTripByName(origin, dest string, opts TripOptions) ([]ParsedTrip, error) {
-	// Resolve origin
-	originStops, err := c.SearchStops(origin)
+func (c *Client) PlanTripByName(ctx context.Context, origin, dest string, opts TripOptions) ([]ParsedTrip, error) {
+	originStop, err := c.ResolveTripStop(ctx, origin, "origin")
 	if err != nil {
-		return nil, fmt.Errorf("failed to find origin '%s': %w", origin, err)
+		return nil, err
 	}
-	if len(originStops) == 0 {
-		return nil, fmt.Errorf("no stops found for origin '%s'", origin)
+	destStop, err := c.ResolveTripStop(ctx, dest, "destination")
+	if err != nil {
+		return nil, err
 	}
 
-	// Resolve destination
-	destStops, err := c.SearchStops(dest)
+	return c.PlanTrip(ctx, originStop.ID, destStop.ID, opts)
+}
+
+// ResolveTripStop resolves one stop of a trip. role ("origin",
+// "destination" or "via") is used in errors and set on ambiguity errors.
+// A "lat,lon" query is kept as a coordinate for the origin and destination,
+// and is the nearest stop for a via.
+func (c *Client) ResolveTripStop(ctx context.Context, query, role string) (*StopLocationData, error) {
+	if p, ok := geo.ParseLatLon(query); ok {
+		if role == "via" {
+			return c.NearestStop(ctx, p.Lat, p.Lon, DefaultNearbyRadius)
+		}
+		stop := CoordStop(p.Lat, p.Lon)
+		return &stop, nil
+	}
+
+	stop, err := c.ResolveStop(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find destination '%s': %w", dest, err)
+		var ambiguous *AmbiguousStopError
+		if errors.As(err, &ambiguous) {
+			ambiguous.Role = role
+			return nil, ambiguous
+		}
+		return nil, fmt.Errorf("failed to find %s '%s': %w", role, query, err)
 	}
-	if len(destStops) == 0 {
-		return nil, fmt.Errorf("no stops found for destination '%s'", dest)
+	if stop == nil {
+		return nil, &errs.NotFoundError{What: "stops", Query: query, Role: role}
 	}
-
-	return c.PlanTrip(originStops[0].ID, destStops[0].ID, opts)
+	return stop, nil
 }
 
 // parseTrip converts API trip to our parsed format
@@ -183,6 +376,7 @@ func parseTrip(trip Trip) ParsedTrip {
 	parsed := ParsedTrip{
 		Duration:     parseDuration(trip.Duration),
 		Interchanges: len(trip.LegList.Leg) - 1,
+		Status:       parseTripStatus(trip.TripStatus),
 	}
 
 	for _, leg := range trip.LegList.Leg {
@@ -205,16 +399,91 @@ func parseTrip(trip Trip) ParsedTrip {
 	return parsed
 }
 
+// parseStop converts an API stop in a leg's stop list to our format
+func parseStop(stop StopSequence) ParsedStop {
+	parsed := ParsedStop{
+		Name: cleanStopName(stop.Name),
+		Lat:  stop.Lat,
+		Lon:  stop.Lon,
+	}
+	if stop.ArrTime != "" {
+		parsed.Arrival = parseDateTime(stop.ArrDate, stop.ArrTime)
+	}
+	if stop.DepTime != "" {
+		parsed.Departure = parseDateTime(stop.DepDate, stop.DepTime)
+	}
+	if stop.RtArrTime != "" {
+		rt := parseDateTime(stop.RtArrDate, stop.RtArrTime)
+		parsed.RtArrival = &rt
+	}
+	if stop.RtDepTime != "" {
+		rt := parseDateTime(stop.RtDepDate, stop.RtDepTime)
+		parsed.RtDeparture = &rt
+	}
+	return parsed
+}
+
+// Geometry returns the path of the leg as a straight line through its
+// stops. ResRobot has no polylines, so the path only follows the route
+// closely with TripOptions.IntermediateStops.
+func (l *ParsedLeg) Geometry() []geo.Point {
+	points := []geo.Point{l.OriginCoord}
+	for _, stop := range l.Stops {
+		points = append(points, geo.Point{Lat: stop.Lat, Lon: stop.Lon})
+	}
+	points = append(points, l.DestCoord)
+
+	known := points[:0]
+	for _, p := range points {
+		if !p.IsZero() {
+			known = append(known, p)
+		}
+	}
+	return known
+}
+
+// ViaLeg returns the index of the leg that continues the trip after
+// passing stop, or -1 if the trip doesn't stop there between legs
+func (t *ParsedTrip) ViaLeg(stop *StopLocationData) int {
+	for i := 1; i < len(t.Legs); i++ {
+		prev, leg := t.Legs[i-1], t.Legs[i]
+		if isStop(prev.DestID, prev.Destination, stop) || isStop(leg.OriginID, leg.Origin, stop) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isStop reports whether a leg's stop is stop, by ID or by name
+func isStop(id, name string, stop *StopLocationData) bool {
+	if id != "" && (id == stop.ID || id == stop.ExtID) {
+		return true
+	}
+	return match.Equal(name, stop.DisplayName())
+}
+
 // parseLeg converts API leg to our parsed format
 func parseLeg(leg Leg) ParsedLeg {
 	parsed := ParsedLeg{
 		Origin:      cleanStopName(leg.Origin.Name),
+		OriginID:    leg.Origin.ExtID,
 		OriginTrack: leg.Origin.Track,
+		OriginCoord: geo.Point{Lat: leg.Origin.Lat, Lon: leg.Origin.Lon},
 		Destination: cleanStopName(leg.Destination.Name),
+		DestID:      leg.Destination.ExtID,
 		DestTrack:   leg.Destination.Track,
+		DestCoord:   geo.Point{Lat: leg.Destination.Lat, Lon: leg.Destination.Lon},
 		IsWalk:      leg.Type == "WALK" || leg.Type == "TRSF",
 		Distance:    leg.Dist,
 		Direction:   leg.Direction,
+		Notes:       parseNotes(leg.Notes),
+	}
+
+	// Intermediate stops; the list starts and ends with the leg's own stops
+	if leg.Stops != nil && len(leg.Stops.Stop) > 2 {
+		for _, stop := range leg.Stops.Stop[1 : len(leg.Stops.Stop)-1] {
+			parsed.Stops = append(parsed.Stops, parseStop(stop))
+		}
 	}
 
 	// Parse times
@@ -325,142 +594,3 @@ func cleanStopName(name string) string {
 	// Trim whitespace
 	return strings.TrimSpace(name)
 }
-
-// FormatTrips formats trips for display
-func FormatTrips(origin, dest string, trips []ParsedTrip) string {
-	var sb strings.Builder
-
-	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	sb.WriteString(fmt.Sprintf(" 🚆 %s → %s\n", origin, dest))
-	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
-
-	if len(trips) == 0 {
-		sb.WriteString("  Inga resor hittades.\n")
-		sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-		return sb.String()
-	}
-
-	for i, trip := range trips {
-		if i > 0 {
-			sb.WriteString("\n  ─────────────────────────────────────────────────────────────────\n\n")
-		}
-
-		// Trip header
-		firstLeg := trip.Legs[0]
-		lastLeg := trip.Legs[len(trip.Legs)-1]
-
-		depTime := firstLeg.DepartureTime.Format("15:04")
-		arrTime := lastLeg.ArrivalTime.Format("15:04")
-
-		// Check for delays
-		depStr := depTime
-		if firstLeg.RtDeparture != nil && !firstLeg.RtDeparture.Equal(firstLeg.DepartureTime) {
-			delay := firstLeg.RtDeparture.Sub(firstLeg.DepartureTime).Minutes()
-			if delay > 0 {
-				depStr = fmt.Sprintf("%s (+%.0f)", depTime, delay)
-			}
-		}
-
-		arrStr := arrTime
-		if lastLeg.RtArrival != nil && !lastLeg.RtArrival.Equal(lastLeg.ArrivalTime) {
-			delay := lastLeg.RtArrival.Sub(lastLeg.ArrivalTime).Minutes()
-			if delay > 0 {
-				arrStr = fmt.Sprintf("%s (+%.0f)", arrTime, delay)
-			}
-		}
-
-		durationMin := int(trip.Duration.Minutes())
-		hours := durationMin / 60
-		mins := durationMin % 60
-		var durationStr string
-		if hours > 0 {
-			durationStr = fmt.Sprintf("%d tim %d min", hours, mins)
-		} else {
-			durationStr = fmt.Sprintf("%d min", mins)
-		}
-
-		changes := ""
-		if trip.Interchanges == 0 {
-			changes = "direkt"
-		} else if trip.Interchanges == 1 {
-			changes = "1 byte"
-		} else {
-			changes = fmt.Sprintf("%d byten", trip.Interchanges)
-		}
-
-		sb.WriteString(fmt.Sprintf("  %s → %s   (%s, %s)\n\n", depStr, arrStr, durationStr, changes))
-
-		// Legs
-		for _, leg := range trip.Legs {
-			if leg.IsWalk {
-				walkMin := int(leg.ArrivalTime.Sub(leg.DepartureTime).Minutes())
-				if leg.Distance > 0 {
-					sb.WriteString(fmt.Sprintf("  🚶 Gå %d m (%d min)\n", leg.Distance, walkMin))
-				} else {
-					sb.WriteString(fmt.Sprintf("  🚶 Byte (%d min)\n", walkMin))
-				}
-			} else {
-				emoji := GetCategoryEmoji(leg.Category)
-				lineStr := leg.Line
-				if lineStr == "" {
-					lineStr = GetCategoryName(leg.Category)
-				}
-
-				depTime := leg.DepartureTime.Format("15:04")
-				arrTime := leg.ArrivalTime.Format("15:04")
-
-				trackInfo := ""
-				if leg.OriginTrack != "" {
-					trackInfo = fmt.Sprintf(" [spår %s]", leg.OriginTrack)
-				}
-
-				sb.WriteString(fmt.Sprintf("  %s %s %s%s\n", emoji, lineStr, leg.Origin, trackInfo))
-				sb.WriteString(fmt.Sprintf("     %s → %s mot %s\n", depTime, arrTime, leg.Direction))
-
-				if leg.Operator != "" && leg.Operator != leg.Line {
-					sb.WriteString(fmt.Sprintf("     (%s)\n", leg.Operator))
-				}
-			}
-		}
-
-		// Google Maps link
-		if mapsURL := GenerateTripMapsURL(trip); mapsURL != "" {
-			sb.WriteString(fmt.Sprintf("\n  🗺️  %s\n", mapsURL))
-		}
-	}
-
-	sb.WriteString("\n")
-	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-
-	return sb.String()
-}
-
-// GenerateTripMapsURL creates a Google Maps URL for a transit trip
-func GenerateTripMapsURL(trip ParsedTrip) string {
-	if len(trip.Legs) == 0 {
-		return ""
-	}
-
-	// Get origin from first leg and destination from last leg
-	origin := trip.Legs[0].Origin
-	dest := trip.Legs[len(trip.Legs)-1].Destination
-
-	params := url.Values{}
-	params.Set("api", "1")
-	params.Set("origin", origin)
-	params.Set("destination", dest)
-	params.Set("travelmode", "transit")
-
-	// Add waypoints for transfers
-	if len(trip.Legs) > 1 {
-		var waypoints []string
-		for i := 0; i < len(trip.Legs)-1; i++ {
-			waypoints = append(waypoints, trip.Legs[i].Destination)
-		}
-		if len(waypoints) > 0 {
-			params.Set("waypoints", strings.Join(waypoints, "|"))
-		}
-	}
-
-	return "https://www.google.com/maps/dir/?" + params.Encode()
-}
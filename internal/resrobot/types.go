@@ -1,6 +1,12 @@
 package resrobot
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"transport/internal/geo"
+	"transport/internal/planner"
+)
 
 // LocationResponse represents the response from location.name endpoint
 type LocationResponse struct {
@@ -20,13 +26,32 @@ type StopLocationData struct {
 	Lon         float64  `json:"lon"`
 	Lat         float64  `json:"lat"`
 	Weight      int      `json:"weight"`
-	Products    int      `json:"products"`     // Bitmask of available transport types
+	Products    Products `json:"products"`     // Product classes serving the stop
 	TimezoneOffset int   `json:"timezoneOffset"`
+	Dist        int      `json:"dist,omitempty"` // Meters from the coordinate, for NearbyStops
+}
+
+// DisplayName returns the stop name without the municipality suffix
+func (s *StopLocationData) DisplayName() string {
+	return cleanStopName(s.Name)
+}
+
+// Municipality extracts the municipality from names such as
+// "Stockholm Centralstation (Stockholm kn)"
+func (s *StopLocationData) Municipality() string {
+	open := strings.LastIndex(s.Name, "(")
+	if open < 0 || !strings.HasSuffix(s.Name, ")") {
+		return ""
+	}
+	inner := s.Name[open+1 : len(s.Name)-1]
+	return strings.TrimSpace(strings.TrimSuffix(inner, " kn"))
 }
 
 // TripResponse represents the response from trip endpoint
 type TripResponse struct {
 	Trip []Trip `json:"Trip"`
+	ScrB string `json:"scrB,omitempty"` // Scroll context for earlier trips
+	ScrF string `json:"scrF,omitempty"` // Scroll context for later trips
 }
 
 // Trip represents a journey from origin to destination
@@ -49,6 +74,7 @@ type Leg struct {
 	Origin       TripStop    `json:"Origin"`
 	Destination  TripStop    `json:"Destination"`
 	Notes        *Notes      `json:"Notes,omitempty"`
+	Stops        *Stops      `json:"Stops,omitempty"` // With passlist=1
 	Product      *Product    `json:"Product,omitempty"`
 	Name         string      `json:"name"`           // e.g., "Pendeltåg 41"
 	Type         string      `json:"type"`           // e.g., "JNY" (journey), "WALK"
@@ -117,7 +143,12 @@ type DepartureBoardResponse struct {
 	Departure []DepartureBoardItem `json:"Departure"`
 }
 
-// DepartureBoardItem represents a single departure
+// ArrivalBoardResponse represents the response from arrivalBoard endpoint
+type ArrivalBoardResponse struct {
+	Arrival []DepartureBoardItem `json:"Arrival"`
+}
+
+// DepartureBoardItem represents a single departure or arrival
 type DepartureBoardItem struct {
 	Product       Product `json:"Product"`
 	Stops         *Stops  `json:"Stops,omitempty"`
@@ -132,8 +163,10 @@ type DepartureBoardItem struct {
 	RtDate        string  `json:"rtDate,omitempty"`
 	Direction     string  `json:"direction"`
 	DirectionFlag string  `json:"directionFlag"`
+	Origin        string  `json:"origin,omitempty"` // Arrivals only
 	Track         string  `json:"track,omitempty"`
 	RtTrack       string  `json:"rtTrack,omitempty"`
+	Cancelled     bool    `json:"cancelled,omitempty"`
 }
 
 // Stops contains stop sequence
@@ -152,21 +185,30 @@ type StopSequence struct {
 	ArrDate   string `json:"arrDate,omitempty"`
 	DepTime   string `json:"depTime,omitempty"`
 	DepDate   string `json:"depDate,omitempty"`
+	RtArrTime string `json:"rtArrTime,omitempty"`
+	RtArrDate string `json:"rtArrDate,omitempty"`
+	RtDepTime string `json:"rtDepTime,omitempty"`
+	RtDepDate string `json:"rtDepDate,omitempty"`
 }
 
 // ParsedTrip is our internal representation of a trip
 type ParsedTrip struct {
 	Duration     time.Duration
 	Interchanges int
+	Status       TripStatus
 	Legs         []ParsedLeg
 }
 
 // ParsedLeg is our internal representation of a leg
 type ParsedLeg struct {
 	Origin        string
+	OriginID      string
 	OriginTrack   string
+	OriginCoord   geo.Point
 	Destination   string
+	DestID        string
 	DestTrack     string
+	DestCoord     geo.Point
 	DepartureTime time.Time
 	ArrivalTime   time.Time
 	RtDeparture   *time.Time // Real-time departure
@@ -177,6 +219,19 @@ type ParsedLeg struct {
 	Direction     string
 	IsWalk        bool
 	Distance      int        // meters, for walking
+	Stops         []ParsedStop // Intermediate stops, with TripOptions.IntermediateStops
+	Notes         []ParsedNote // Attributes such as required reservation, and disruptions
+}
+
+// ParsedStop is a stop a leg passes between its origin and destination
+type ParsedStop struct {
+	Name        string
+	Lat         float64
+	Lon         float64
+	Arrival     time.Time
+	Departure   time.Time
+	RtArrival   *time.Time // Real-time arrival
+	RtDeparture *time.Time // Real-time departure
 }
 
 // Transport category codes
@@ -194,24 +249,10 @@ const (
 	CatNattbus      = "NAT"  // Nattbuss
 )
 
-// GetCategoryEmoji returns an emoji for the transport category
+// GetCategoryEmoji returns an emoji for the transport category, the icon of
+// the mode its product class shares with SL
 func GetCategoryEmoji(cat string) string {
-	switch cat {
-	case CatPendeltag, CatRegionaltag, CatSJ, CatSnabbtag, CatNorrtag:
-		return "🚆"
-	case CatMetro:
-		return "🚇"
-	case CatBus, CatNattbus:
-		return "🚌"
-	case CatSparvagn:
-		return "🚊"
-	case CatFerry:
-		return "⛴️"
-	case CatFlygbuss:
-		return "🚐"
-	default:
-		return "🚍"
-	}
+	return planner.ModeIcon(CategoryProducts(cat).Mode())
 }
 
 // GetCategoryName returns a readable name for the category
@@ -0,0 +1,28 @@
+package taxi
+
+import "net/http"
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithNominatimURL geocodes with another Nominatim search endpoint, e.g. a
+// fake from transporttest
+func WithNominatimURL(url string) Option {
+	return func(c *Client) {
+		c.nominatimURL = url
+	}
+}
+
+// WithOSRMURL routes with another OSRM driving endpoint
+func WithOSRMURL(url string) Option {
+	return func(c *Client) {
+		c.osrmURL = url
+	}
+}
+
+// WithHTTPClient sends requests with hc instead of the shared client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
@@ -1,20 +1,45 @@
 package taxi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"transport/internal/errs"
+	"transport/internal/httpx"
 )
 
 const (
 	nominatimURL = "https://nominatim.openstreetmap.org/search"
 	osrmURL      = "https://router.project-osrm.org/route/v1/driving"
-	userAgent    = "transport-cli/1.0"
 )
 
+// Client geocodes addresses with Nominatim and routes with OSRM
+type Client struct {
+	httpClient   *http.Client
+	nominatimURL string
+	osrmURL      string
+}
+
+// NewClient creates a client for the public Nominatim and OSRM servers.
+// Requests go through the shared transport, which keeps Nominatim within
+// its one request per second limit.
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient:   httpx.NewClient(10 * time.Second),
+		nominatimURL: nominatimURL,
+		osrmURL:      osrmURL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
 // Location represents a geocoded location
 type Location struct {
 	Name      string
@@ -56,26 +81,38 @@ type TaxiSearch struct {
 }
 
 // Geocode converts an address to coordinates using Nominatim
-func Geocode(address string) (*Location, error) {
+func (c *Client) Geocode(ctx context.Context, address string) (*Location, error) {
+	return c.GeocodeIn(ctx, address, "Stockholm")
+}
+
+// GeocodeIn converts an address in region (a city or county) to
+// coordinates. An empty region searches all of Sweden.
+func (c *Client) GeocodeIn(ctx context.Context, address, region string) (*Location, error) {
+	query := address + ", Sweden"
+	if region != "" {
+		query = address + ", " + region + ", Sweden"
+	}
+
 	params := url.Values{}
-	params.Set("q", address+", Stockholm, Sweden")
+	params.Set("q", query)
 	params.Set("format", "json")
 	params.Set("limit", "1")
 	params.Set("countrycodes", "se")
 
-	req, err := http.NewRequest("GET", nominatimURL+"?"+params.Encode(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.nominatimURL+"?"+params.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", userAgent)
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("geocoding failed: %w", err)
+		return nil, fmt.Errorf("geocoding failed: %w", errs.Unavailable("Nominatim", err))
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, errs.FromResponse("Nominatim", resp)
+	}
+
 	var results []struct {
 		Lat         string `json:"lat"`
 		Lon         string `json:"lon"`
@@ -86,11 +123,11 @@ func Geocode(address string) (*Location, error) {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		return nil, fmt.Errorf("failed to decode geocoding response: %w", err)
+		return nil, errs.Unavailable("Nominatim", fmt.Errorf("failed to decode geocoding response: %w", err))
 	}
 
 	if len(results) == 0 {
-		return nil, fmt.Errorf("location not found: %s", address)
+		return nil, &errs.NotFoundError{What: "location", Query: address}
 	}
 
 	r := results[0]
@@ -117,18 +154,26 @@ func Geocode(address string) (*Location, error) {
 }
 
 // CalculateRoute calculates the route between two locations using OSRM
-func CalculateRoute(from, to *Location) (*Route, error) {
+func (c *Client) CalculateRoute(ctx context.Context, from, to *Location) (*Route, error) {
 	// OSRM uses lon,lat format
 	coords := fmt.Sprintf("%f,%f;%f,%f", from.Lon, from.Lat, to.Lon, to.Lat)
-	reqURL := fmt.Sprintf("%s/%s?overview=false", osrmURL, coords)
+	reqURL := fmt.Sprintf("%s/%s?overview=false", c.osrmURL, coords)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(reqURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("routing failed: %w", err)
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("routing failed: %w", errs.Unavailable("OSRM", err))
 	}
 	defer resp.Body.Close()
 
+	// OSRM answers 400 with a code in the body when there is no route
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return nil, errs.FromResponse("OSRM", resp)
+	}
+
 	var result struct {
 		Code   string `json:"code"`
 		Routes []struct {
@@ -138,11 +183,14 @@ func CalculateRoute(from, to *Location) (*Route, error) {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode routing response: %w", err)
+		return nil, errs.Unavailable("OSRM", fmt.Errorf("failed to decode routing response: %w", err))
 	}
 
-	if result.Code != "Ok" || len(result.Routes) == 0 {
-		return nil, fmt.Errorf("no route found")
+	switch {
+	case result.Code == "NoRoute" || (result.Code == "Ok" && len(result.Routes) == 0):
+		return nil, &errs.NotFoundError{What: "route"}
+	case result.Code != "Ok":
+		return nil, &errs.UpstreamError{Service: "OSRM", StatusCode: resp.StatusCode, Message: result.Code}
 	}
 
 	return &Route{
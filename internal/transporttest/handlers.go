@@ -0,0 +1,216 @@
+package transporttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Bodies served when no fixture was recorded for a query
+const (
+	noLocations      = `{"locations":[]}`
+	noDepartures     = `{"departures":[]}`
+	noStopLocations  = `{"stopLocationOrCoordLocation":[]}`
+	noBoardItems     = `{}`
+	noGeocodeResults = `[]`
+	resRobotAuthErr  = `{"errorCode":"API_AUTH","errorText":"Access denied for given accessId"}`
+)
+
+// serve records the request and answers it from an override or a fixture
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	override := s.overrides[r.URL.Path]
+	s.mu.Unlock()
+
+	if override != nil {
+		override.ServeHTTP(w, r)
+		return
+	}
+
+	p := r.URL.Path
+	q := r.URL.Query()
+	switch {
+	case p == slJourneyPath+"/stop-finder":
+		serveJSON(w, "sl/stop-finder/"+slug(q.Get("name_sf"))+".json", noLocations)
+	case p == slJourneyPath+"/trips":
+		serveJSON(w, "sl/trips.json", "")
+	case p == slTransportPath+"/sites":
+		serveJSON(w, "sl/sites.json", "")
+	case strings.HasPrefix(p, slTransportPath+"/sites/") && strings.HasSuffix(p, "/departures"):
+		siteID := path.Base(path.Dir(p))
+		serveJSON(w, "sl/departures/"+siteID+".json", noDepartures)
+	case strings.HasPrefix(p, resRobotPath+"/"):
+		if q.Get("accessId") != APIKey {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(resRobotAuthErr))
+			return
+		}
+		switch strings.TrimPrefix(p, resRobotPath) {
+		case "/location.name":
+			serveJSON(w, "resrobot/location/"+slug(q.Get("input"))+".json", noStopLocations)
+		case "/trip":
+			serveResRobotTrip(w, q.Get("passlist") == "1")
+		case "/location.nearbystops":
+			serveNearbyStops(w, q)
+		case "/departureBoard":
+			serveResRobotBoard(w, "departures", "Departure", q)
+		case "/arrivalBoard":
+			serveResRobotBoard(w, "arrivals", "Arrival", q)
+		default:
+			http.NotFound(w, r)
+		}
+	case p == nominatimPath:
+		// Geocode adds ", Stockholm, Sweden" to every query
+		place, _, _ := strings.Cut(q.Get("q"), ",")
+		serveJSON(w, "nominatim/"+slug(place)+".json", noGeocodeResults)
+	case strings.HasPrefix(p, osrmPath+"/"):
+		serveJSON(w, "osrm/route.json", "")
+	case p == airportsPath:
+		data, err := fixtures.ReadFile("fixtures/ourairports/airports.csv")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Write(data)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveJSON writes the named fixture, or fallback if there is no such
+// fixture. Without a fallback a missing fixture is a 404.
+func serveJSON(w http.ResponseWriter, name, fallback string) {
+	data, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		if fallback == "" {
+			http.Error(w, "no fixture "+name, http.StatusNotFound)
+			return
+		}
+		data = []byte(fallback)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(data)
+}
+
+// serveResRobotTrip writes the recorded trips. Like ResRobot, it leaves out
+// the stops of each leg unless asked for with passlist=1.
+func serveResRobotTrip(w http.ResponseWriter, passlist bool) {
+	if passlist {
+		serveJSON(w, "resrobot/trip.json", "")
+		return
+	}
+
+	data, err := fixtures.ReadFile("fixtures/resrobot/trip.json")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	trips, _ := doc["Trip"].([]any)
+	for _, trip := range trips {
+		trip, _ := trip.(map[string]any)
+		legList, _ := trip["LegList"].(map[string]any)
+		legs, _ := legList["Leg"].([]any)
+		for _, leg := range legs {
+			if leg, ok := leg.(map[string]any); ok {
+				delete(leg, "Stops")
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// serveNearbyStops writes the recorded stops around Sundsvall Centralstation,
+// wherever the coordinate is, keeping those within the radius r and at
+// most maxNo of them
+func serveNearbyStops(w http.ResponseWriter, q url.Values) {
+	data, err := fixtures.ReadFile("fixtures/resrobot/nearbystops.json")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var doc map[string][]map[string]map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	radius, err := strconv.Atoi(q.Get("r"))
+	if err != nil {
+		radius = 1000
+	}
+	maxNo, _ := strconv.Atoi(q.Get("maxNo"))
+
+	kept := []map[string]map[string]any{}
+	for _, item := range doc["stopLocationOrCoordLocation"] {
+		if dist, _ := item["StopLocation"]["dist"].(float64); int(dist) > radius {
+			continue
+		}
+		if maxNo > 0 && len(kept) == maxNo {
+			break
+		}
+		kept = append(kept, item)
+	}
+	doc["stopLocationOrCoordLocation"] = kept
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// serveResRobotBoard writes the recorded departures or arrivals of the stop
+// in the id parameter, keeping those of the requested products like
+// ResRobot does. Other parameters are ignored.
+func serveResRobotBoard(w http.ResponseWriter, dir, list string, q url.Values) {
+	name := dir + "/" + resRobotExtID(q.Get("id")) + ".json"
+	data, err := fixtures.ReadFile("fixtures/resrobot/" + name)
+	products, _ := strconv.Atoi(q.Get("products"))
+	if err != nil || products == 0 {
+		serveJSON(w, "resrobot/"+name, noBoardItems)
+		return
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	items, _ := doc[list].([]any)
+	kept := []any{}
+	for _, item := range items {
+		item, _ := item.(map[string]any)
+		product, _ := item["Product"].(map[string]any)
+		cls, _ := product["cls"].(string)
+		if bits, _ := strconv.Atoi(cls); bits&products != 0 {
+			kept = append(kept, item)
+		}
+	}
+	doc[list] = kept
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// resRobotExtID returns the external ID in a stop ID such as
+// "A=1@O=Sundsvall Centralstation@...@L=740000009@". External IDs are
+// returned as they are.
+func resRobotExtID(id string) string {
+	for _, part := range strings.Split(id, "@") {
+		if ext, ok := strings.CutPrefix(part, "L="); ok {
+			return ext
+		}
+	}
+	return id
+}
@@ -0,0 +1,194 @@
+// Package transporttest serves recorded responses from the SL, ResRobot,
+// Nominatim, OSRM and OurAirports APIs, so that the clients and the
+// transport command can be exercised without network access.
+//
+// Point a client at the fake with the matching options:
+//
+//	srv := transporttest.Start(t)
+//	client := api.NewClient(srv.SLOptions()...)
+//
+// or run the transport binary with srv.Env() added to its environment.
+//
+// The recorded trips go from Slussen to Odenplan (SL) and from Göteborg to
+// Stockholm (ResRobot), around Now; each planner answers every trip search
+// with its recording. The second ResRobot trip is partially cancelled.
+// ResRobot also finds Uppsala, Slussen and Odenplan, has departure and
+// arrival boards for Sundsvall Centralstation and finds the stops around
+// it for any coordinate. Stop and place lookups answer from a fixture named after
+// the query, e.g. fixtures/sl/stop-finder/odenplan.json, and with an empty
+// result for anything that wasn't recorded.
+package transporttest
+
+import (
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"transport/internal/api"
+	"transport/internal/flight"
+	"transport/internal/match"
+	"transport/internal/resrobot"
+	"transport/internal/taxi"
+	"transport/internal/tz"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// APIKey is the only ResRobot key the fake accepts
+const APIKey = "transporttest"
+
+// Now is when the responses were recorded. Clients configured by the
+// Server read the time from it, so departures and trips stay current.
+var Now = time.Date(2026, 3, 2, 8, 0, 0, 0, tz.Stockholm)
+
+// Path prefixes of the fake APIs on the server
+const (
+	slJourneyPath   = "/sl/journey"
+	slTransportPath = "/sl/transport"
+	resRobotPath    = "/resrobot"
+	nominatimPath   = "/nominatim/search"
+	osrmPath        = "/osrm/route/v1/driving"
+	airportsPath    = "/ourairports/airports.csv"
+)
+
+// Server is a fake of every upstream API, served over HTTP
+type Server struct {
+	*httptest.Server
+
+	cacheDir string // sites cache for commands run with Env
+
+	mu        sync.Mutex
+	requests  []string
+	overrides map[string]http.Handler
+}
+
+// NewServer starts a fake server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{overrides: make(map[string]http.Handler)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	if dir, err := os.MkdirTemp("", "transporttest"); err == nil {
+		s.cacheDir = dir
+	}
+	return s
+}
+
+// Start starts a fake server that is closed when the test finishes
+func Start(tb testing.TB) *Server {
+	tb.Helper()
+	s := NewServer()
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Close shuts the server down and removes its cache directory
+func (s *Server) Close() {
+	s.Server.Close()
+	if s.cacheDir != "" {
+		os.RemoveAll(s.cacheDir)
+	}
+}
+
+// Handle serves requests for path with h instead of the recorded
+// responses, e.g. to make an API fail:
+//
+//	srv.Handle("/sl/journey/trips", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//		w.WriteHeader(http.StatusTooManyRequests)
+//	}))
+func (s *Server) Handle(path string, h http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides[path] = h
+}
+
+// Requests returns the path and query of every request served so far, in
+// order
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// SLJourneyURL is the base URL of the fake SL Journey Planner
+func (s *Server) SLJourneyURL() string { return s.URL + slJourneyPath }
+
+// SLTransportURL is the base URL of the fake SL Transport API
+func (s *Server) SLTransportURL() string { return s.URL + slTransportPath }
+
+// ResRobotURL is the base URL of the fake ResRobot API
+func (s *Server) ResRobotURL() string { return s.URL + resRobotPath }
+
+// NominatimURL is the search endpoint of the fake Nominatim
+func (s *Server) NominatimURL() string { return s.URL + nominatimPath }
+
+// OSRMURL is the driving route endpoint of the fake OSRM
+func (s *Server) OSRMURL() string { return s.URL + osrmPath }
+
+// AirportsURL is the URL of the fake OurAirports CSV
+func (s *Server) AirportsURL() string { return s.URL + airportsPath }
+
+// SLOptions configures an SL client to use the fake, with the clock at Now
+// and the sites cache kept in memory
+func (s *Server) SLOptions() []api.Option {
+	return []api.Option{
+		api.WithBaseURL(s.SLJourneyURL()),
+		api.WithTransportURL(s.SLTransportURL()),
+		api.WithClock(func() time.Time { return Now }),
+		api.WithCacheDir(""),
+	}
+}
+
+// ResRobotOptions configures a ResRobot client to use the fake, with the
+// clock at Now
+func (s *Server) ResRobotOptions() []resrobot.Option {
+	return []resrobot.Option{
+		resrobot.WithBaseURL(s.ResRobotURL()),
+		resrobot.WithAPIKey(APIKey),
+		resrobot.WithClock(func() time.Time { return Now }),
+	}
+}
+
+// TaxiOptions configures a geocoding and routing client to use the fake
+func (s *Server) TaxiOptions() []taxi.Option {
+	return []taxi.Option{
+		taxi.WithNominatimURL(s.NominatimURL()),
+		taxi.WithOSRMURL(s.OSRMURL()),
+	}
+}
+
+// FlightOptions configures an airports client to use the fake
+func (s *Server) FlightOptions() []flight.Option {
+	return []flight.Option{
+		flight.WithAirportsURL(s.AirportsURL()),
+	}
+}
+
+// Env returns the environment variables that make the transport command
+// use the fake, as "KEY=value" pairs for exec.Cmd.Env. The clock is pinned
+// to Now and the sites cache is kept in a directory of its own.
+func (s *Server) Env() []string {
+	env := []string{
+		"TRANSPORT_SL_JOURNEY_URL=" + s.SLJourneyURL(),
+		"TRANSPORT_SL_TRANSPORT_URL=" + s.SLTransportURL(),
+		"TRANSPORT_RESROBOT_URL=" + s.ResRobotURL(),
+		"TRANSPORT_NOMINATIM_URL=" + s.NominatimURL(),
+		"TRANSPORT_OSRM_URL=" + s.OSRMURL(),
+		"TRANSPORT_AIRPORTS_URL=" + s.AirportsURL(),
+		"TRANSPORT_NOW=" + Now.Format(time.RFC3339),
+		"RESROBOT_API_KEY=" + APIKey,
+	}
+	if s.cacheDir != "" {
+		env = append(env, "TRANSPORT_CACHE_DIR="+s.cacheDir)
+	}
+	return env
+}
+
+// slug turns a query into a fixture name, e.g. "T-Centralen" → "t-centralen"
+func slug(query string) string {
+	return strings.ReplaceAll(match.Normalize(query), " ", "-")
+}
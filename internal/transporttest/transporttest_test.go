@@ -0,0 +1,81 @@
+package transporttest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"transport/internal/api"
+	"transport/internal/errs"
+	"transport/internal/resrobot"
+	"transport/internal/transporttest"
+)
+
+func TestSLClient(t *testing.T) {
+	srv := transporttest.Start(t)
+	client := api.NewClient(srv.SLOptions()...)
+	ctx := context.Background()
+
+	journeys, err := client.PlanTripByName(ctx, "Slussen", "Odenplan", client.DefaultTripOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journeys) == 0 || len(journeys[0].Legs) == 0 {
+		t.Fatalf("no journeys from the recording")
+	}
+	if got := journeys[0].Legs[0].Origin.Name; !strings.Contains(got, "Slussen") {
+		t.Errorf("first journey leaves from %q, want Slussen", got)
+	}
+
+	departures, err := client.GetDepartures(ctx, 9192)
+	if err != nil || len(departures) == 0 {
+		t.Errorf("GetDepartures(Slussen) = %d departures, %v", len(departures), err)
+	}
+	if departures, err := client.GetDepartures(ctx, 1); err != nil || len(departures) != 0 {
+		t.Errorf("GetDepartures(unrecorded) = %d departures, %v; want none", len(departures), err)
+	}
+
+	requests := srv.Requests()
+	if !slices.ContainsFunc(requests, func(r string) bool { return strings.HasPrefix(r, "/sl/journey/trips?") }) {
+		t.Errorf("no trip search among the requests %q", requests)
+	}
+}
+
+func TestResRobotClient(t *testing.T) {
+	srv := transporttest.Start(t)
+	ctx := context.Background()
+
+	client := resrobot.NewClient(srv.ResRobotOptions()...)
+	trips, err := client.PlanTripByName(ctx, "Göteborg", "Stockholm", client.DefaultTripOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trips) == 0 || len(trips[0].Legs) == 0 {
+		t.Fatalf("no trips from the recording")
+	}
+	if got := trips[0].Legs[0].Origin; !strings.Contains(got, "Göteborg") {
+		t.Errorf("first trip leaves from %q, want Göteborg", got)
+	}
+
+	// The fake only accepts its own key
+	other := resrobot.NewClient(append(srv.ResRobotOptions(), resrobot.WithAPIKey("other"))...)
+	if _, err := other.PlanTripByName(ctx, "Göteborg", "Stockholm", client.DefaultTripOptions()); err == nil {
+		t.Errorf("trip planned with a key the fake doesn't know")
+	}
+}
+
+func TestHandle(t *testing.T) {
+	srv := transporttest.Start(t)
+	srv.Handle("/sl/journey/trips", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+
+	client := api.NewClient(srv.SLOptions()...)
+	_, err := client.PlanTripByName(context.Background(), "Slussen", "Odenplan", client.DefaultTripOptions())
+	if !errors.Is(err, errs.ErrUpstream) {
+		t.Errorf("PlanTripByName() error = %v, want an upstream error", err)
+	}
+}
@@ -15,9 +15,22 @@ func init() {
 	}
 }
 
+// clock is where Now reads the time from
+var clock = time.Now
+
 // Now returns the current time in Stockholm.
 func Now() time.Time {
-	return time.Now().In(Stockholm)
+	return clock().In(Stockholm)
+}
+
+// SetClock makes Now read the time from now, e.g. a fixed time for
+// reproducible output. A nil now restores the system clock. Not safe to
+// call while other goroutines use Now.
+func SetClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	clock = now
 }
 
 // ParseStockholm parses a time string as Stockholm local time.